@@ -5,15 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/exec"
 	"os/signal"
+	"reflect"
 	"strings"
 	"syscall"
 	"time"
 	_ "time/tzdata"
 
 	_ "github.com/breml/rootcerts"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/qdm12/gluetun/internal/alpine"
 	"github.com/qdm12/gluetun/internal/cli"
 	"github.com/qdm12/gluetun/internal/command"
@@ -351,6 +354,27 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 		}
 	} // TODO move inside firewall?
 
+	switch {
+	case *allSettings.DNS.Standalone:
+		err = allowStandaloneDNS(ctx, firewallConf, defaultRoutes,
+			allSettings.Firewall.OutboundSubnets, allSettings.DNS.ListeningPort())
+		if err != nil {
+			return fmt.Errorf("allowing standalone DNS traffic: %w", err)
+		}
+	case *allSettings.DNS.DoT.Enabled && !*allSettings.DNS.KeepNameserver &&
+		allSettings.DNS.ListensOnNetwork():
+		logger.Warn("⚠️⚠️⚠️  the DNS server listens on " + allSettings.DNS.ListeningAddress +
+			" and is reachable by other hosts, make sure this is intended!")
+		if len(allSettings.DNS.AllowedSubnets) == 0 {
+			logger.Warn("no DNS allowed subnets set, queries from other hosts are refused")
+		}
+		err = allowDNSQueries(ctx, firewallConf, defaultRoutes,
+			allSettings.DNS.ListeningPort())
+		if err != nil {
+			return fmt.Errorf("allowing DNS queries: %w", err)
+		}
+	}
+
 	// Shutdown settings
 	const totalShutdownTimeout = 3 * time.Second
 	const defaultShutdownTimeout = 400 * time.Millisecond
@@ -379,15 +403,17 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 
 	portForwardLogger := logger.New(log.SetComponent("port forwarding"))
 	portForwardLooper := portforward.NewLoop(allSettings.VPN.Provider.PortForwarding,
-		routingConf, httpClient, firewallConf, portForwardLogger, puid, pgid)
+		routingConf, httpClient, firewallConf, cmder, portForwardLogger, puid, pgid)
 	portForwardRunError, err := portForwardLooper.Start(ctx)
 	if err != nil {
 		return fmt.Errorf("starting port forwarding loop: %w", err)
 	}
 
 	dnsLogger := logger.New(log.SetComponent("dns"))
-	dnsLooper, err := dns.NewLoop(allSettings.DNS, httpClient,
-		dnsLogger)
+	killSwitch := *allSettings.Firewall.Enabled && !*allSettings.DNS.Standalone
+	jsonLogs := allSettings.Log.Format == "json"
+	dnsLooper, err := dns.NewLoop(allSettings.DNS, httpClient, cmder, ipv6Supported,
+		killSwitch, dnsLogger, jsonLogs, prometheus.DefaultRegisterer)
 	if err != nil {
 		return fmt.Errorf("creating DNS loop: %w", err)
 	}
@@ -467,7 +493,8 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 		"http server", goroutine.OptionTimeout(defaultShutdownTimeout))
 	httpServer, err := server.New(httpServerCtx, controlServerAddress, controlServerLogging,
 		logger.New(log.SetComponent("http server")),
-		allSettings.ControlServer.AuthFilePath,
+		allSettings.ControlServer.AuthFilePath, *allSettings.ControlServer.APIKey,
+		*allSettings.ControlServer.RateLimit,
 		buildInfo, vpnLooper, portForwardLooper, dnsLooper, updaterLooper, publicIPLooper,
 		storage, ipv6Supported)
 	if err != nil {
@@ -479,7 +506,12 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	controlGroupHandler.Add(httpServerHandler)
 
 	healthLogger := logger.New(log.SetComponent("healthcheck"))
-	healthcheckServer := healthcheck.NewServer(allSettings.Health, healthLogger, vpnLooper)
+	var healthStatusApplier healthcheck.StatusApplier = vpnLooper
+	if *allSettings.DNS.Standalone {
+		healthStatusApplier = dnsLooper
+	}
+	healthcheckServer := healthcheck.NewServer(allSettings.Health, healthLogger,
+		healthStatusApplier, dnsLooper)
 	healthServerHandler, healthServerCtx, healthServerDone := goshutdown.NewGoRoutineHandler(
 		"HTTP health server", goroutine.OptionTimeout(defaultShutdownTimeout))
 	go healthcheckServer.Run(healthServerCtx, healthServerDone)
@@ -491,9 +523,31 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	orderHandler.Append(controlGroupHandler, tickersGroupHandler, healthServerHandler,
 		vpnHandler, otherGroupHandler)
 
-	// Start VPN for the first time in a blocking call
-	// until the VPN is launched
-	_, _ = vpnLooper.ApplyStatus(ctx, constants.Running) // TODO option to disable with variable
+	if *allSettings.DNS.Standalone {
+		// Only start the DNS server, the VPN stays stopped
+		logger.Info("running in standalone DNS mode without VPN")
+		_, _ = dnsLooper.ApplyStatus(ctx, constants.Running)
+	} else {
+		// Start VPN for the first time in a blocking call
+		// until the VPN is launched
+		_, _ = vpnLooper.ApplyStatus(ctx, constants.Running)
+	}
+
+	reloader := &settingsReloader{
+		reader:        reader,
+		logger:        logger,
+		storage:       storage,
+		ipv6Supported: ipv6Supported,
+		dns:           dnsLooper,
+		vpn:           vpnLooper,
+		updater:       updaterLooper,
+		httpProxy:     httpProxyLooper,
+		shadowsocks:   shadowsocksLooper,
+	}
+	reloadSignalCh := make(chan os.Signal, 1)
+	signal.Notify(reloadSignalCh, syscall.SIGHUP)
+	defer signal.Stop(reloadSignalCh)
+	go reloader.run(ctx, reloadSignalCh)
 
 	select {
 	case <-ctx.Done():
@@ -518,6 +572,109 @@ func _main(ctx context.Context, buildInfo models.BuildInformation,
 	return orderHandler.Shutdown(context.Background())
 }
 
+// allowStandaloneDNS allows DNS queries from other hosts on the DNS port
+// and outbound traffic through the default interfaces, so the DNS over TLS
+// server can reach its upstream resolvers and download block lists
+// without the VPN.
+func allowStandaloneDNS(ctx context.Context, firewallConf *firewall.Config,
+	defaultRoutes []routing.DefaultRoute, outboundSubnets []netip.Prefix,
+	dnsPort uint16) (err error) {
+	err = allowDNSQueries(ctx, firewallConf, defaultRoutes, dnsPort)
+	if err != nil {
+		return err
+	}
+
+	subnets := make([]netip.Prefix, len(outboundSubnets), len(outboundSubnets)+2) //nolint:gomnd
+	copy(subnets, outboundSubnets)
+	subnets = append(subnets,
+		netip.PrefixFrom(netip.IPv4Unspecified(), 0),
+		netip.PrefixFrom(netip.IPv6Unspecified(), 0))
+	return firewallConf.SetOutboundSubnets(ctx, subnets)
+}
+
+// allowDNSQueries allows DNS queries from other hosts
+// on the DNS port given through the default interfaces.
+func allowDNSQueries(ctx context.Context, firewallConf *firewall.Config,
+	defaultRoutes []routing.DefaultRoute, dnsPort uint16) (err error) {
+	for _, defaultRoute := range defaultRoutes {
+		err = firewallConf.SetAllowedPort(ctx, dnsPort, defaultRoute.NetInterface)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settingsReloader reloads the settings when receiving a SIGHUP signal.
+type settingsReloader struct {
+	reader        *reader.Reader
+	logger        log.LoggerInterface
+	storage       *storage.Storage
+	ipv6Supported bool
+	dns           *dns.Loop
+	vpn           *vpn.Loop
+	updater       *updater.Loop
+	httpProxy     *httpproxy.Loop
+	shadowsocks   *shadowsocks.Loop
+}
+
+// run reloads the settings for each signal received, one reload at a
+// time, so status changes of the loops never run concurrently with each
+// other. Signals received during a reload trigger a single reload after it.
+func (r *settingsReloader) run(ctx context.Context, signalCh <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signalCh:
+			r.logger.Info("Caught OS signal SIGHUP, reloading settings")
+			err := r.reload(ctx)
+			if err != nil {
+				r.logger.Error("reloading settings: " + err.Error())
+			}
+		}
+	}
+}
+
+// reload reads the settings again, applies them to the loops supporting
+// settings changes at runtime and rebuilds the DNS block lists.
+// Other settings, such as the firewall or the control server settings,
+// only take effect after a restart.
+func (r *settingsReloader) reload(ctx context.Context) (err error) {
+	var allSettings settings.Settings
+	err = allSettings.Read(r.reader, r.logger)
+	if err != nil {
+		return err
+	}
+	allSettings.SetDefaults()
+	err = allSettings.Validate(r.storage, r.ipv6Supported, r.logger)
+	if err != nil {
+		return err
+	}
+
+	dnsUnchanged := reflect.DeepEqual(r.dns.GetSettings(), allSettings.DNS)
+	outcome, err := r.dns.SetSettings(ctx, allSettings.DNS)
+	if err != nil {
+		return fmt.Errorf("setting DNS settings: %w", err)
+	}
+	r.logger.Info("DNS: " + outcome)
+	if dnsUnchanged && r.dns.GetStatus() == constants.Running {
+		// A DNS server restart downloads the block lists already.
+		_, err = r.dns.RebuildBlockLists(ctx)
+		if err != nil {
+			r.logger.Warn(err.Error())
+		}
+	}
+
+	if !*allSettings.DNS.Standalone {
+		r.logger.Info("VPN: " + r.vpn.SetSettings(ctx, allSettings.VPN))
+	}
+	r.logger.Info("updater: " + r.updater.SetSettings(allSettings.Updater))
+	r.logger.Info("HTTP proxy: " + r.httpProxy.SetSettings(ctx, allSettings.HTTPProxy))
+	r.logger.Info("shadowsocks: " + r.shadowsocks.SetSettings(ctx, allSettings.Shadowsocks))
+	return nil
+}
+
 type printVersionElement struct {
 	name       string
 	getVersion func(ctx context.Context) (version string, err error)
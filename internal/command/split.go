@@ -0,0 +1,92 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrCommandEmpty            = errors.New("command is empty")
+	ErrSingleQuoteUnterminated = errors.New("unterminated single-quoted string")
+	ErrDoubleQuoteUnterminated = errors.New("unterminated double-quoted string")
+	ErrEscapeUnterminated      = errors.New("unterminated backslash-escape")
+)
+
+// Split splits a command line into its program and arguments, such
+// that `/bin/sh -c "echo hello"` is split into `/bin/sh`, `-c` and
+// `echo hello`. It supports single quotes, double quotes and backslash
+// escapes the way a POSIX shell does, but does not expand variables,
+// globs or braces. It returns an error if the command line has no word
+// or has an unterminated quote or escape.
+func Split(commandLine string) (words []string, err error) {
+	var word strings.Builder
+	inWord := false
+	const (
+		unquoted = iota
+		singleQuoted
+		doubleQuoted
+	)
+	state := unquoted
+	escaped := false
+
+	for _, r := range commandLine {
+		switch {
+		case escaped:
+			escaped = false
+			if state == doubleQuoted && !strings.ContainsRune(`$`+"`"+`"\`, r) {
+				// Within double quotes, the backslash is only
+				// an escape before $, `, " and \.
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+		case state == singleQuoted:
+			if r == '\'' {
+				state = unquoted
+				continue
+			}
+			word.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case state == doubleQuoted:
+			if r == '"' {
+				state = unquoted
+				continue
+			}
+			word.WriteRune(r)
+		case r == '\'':
+			state = singleQuoted
+			inWord = true
+		case r == '"':
+			state = doubleQuoted
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, fmt.Errorf("%w", ErrEscapeUnterminated)
+	case state == singleQuoted:
+		return nil, fmt.Errorf("%w", ErrSingleQuoteUnterminated)
+	case state == doubleQuoted:
+		return nil, fmt.Errorf("%w", ErrDoubleQuoteUnterminated)
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w", ErrCommandEmpty)
+	}
+	return words, nil
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Split(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		commandLine string
+		words       []string
+		errWrapped  error
+		errMessage  string
+	}{
+		"empty": {
+			errWrapped: ErrCommandEmpty,
+			errMessage: "command is empty",
+		},
+		"whitespaces_only": {
+			commandLine: " \t\n ",
+			errWrapped:  ErrCommandEmpty,
+			errMessage:  "command is empty",
+		},
+		"program_only": {
+			commandLine: "/bin/true",
+			words:       []string{"/bin/true"},
+		},
+		"arguments": {
+			commandLine: "  /bin/echo  hello\tworld ",
+			words:       []string{"/bin/echo", "hello", "world"},
+		},
+		"double_quoted_argument": {
+			commandLine: `/bin/sh -c "echo \"hello\" \$HOME \n"`,
+			words:       []string{"/bin/sh", "-c", `echo "hello" $HOME \n`},
+		},
+		"single_quoted_argument": {
+			commandLine: `/bin/sh -c 'echo "hello" \ok'`,
+			words:       []string{"/bin/sh", "-c", `echo "hello" \ok`},
+		},
+		"escaped_space": {
+			commandLine: `/bin/echo hello\ world`,
+			words:       []string{"/bin/echo", "hello world"},
+		},
+		"empty_quoted_argument": {
+			commandLine: `/bin/echo "" ''`,
+			words:       []string{"/bin/echo", "", ""},
+		},
+		"quotes_within_word": {
+			commandLine: `/bin/echo a"b c"'d e'f`,
+			words:       []string{"/bin/echo", "ab cd ef"},
+		},
+		"unterminated_single_quote": {
+			commandLine: `/bin/echo 'hello`,
+			errWrapped:  ErrSingleQuoteUnterminated,
+			errMessage:  "unterminated single-quoted string",
+		},
+		"unterminated_double_quote": {
+			commandLine: `/bin/echo "hello`,
+			errWrapped:  ErrDoubleQuoteUnterminated,
+			errMessage:  "unterminated double-quoted string",
+		},
+		"unterminated_escape": {
+			commandLine: `/bin/echo hello\`,
+			errWrapped:  ErrEscapeUnterminated,
+			errMessage:  "unterminated backslash-escape",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			words, err := Split(testCase.commandLine)
+
+			assert.Equal(t, testCase.words, words)
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
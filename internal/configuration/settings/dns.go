@@ -1,11 +1,20 @@
 package settings
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/reader"
+	"github.com/qdm12/gosettings/validate"
 	"github.com/qdm12/gotree"
 )
 
@@ -17,6 +26,48 @@ type DNS struct {
 	// DoT server. It cannot be the zero value in the internal
 	// state.
 	ServerAddress netip.Addr
+	// ResolvConfAddress is the nameserver address written to
+	// resolv.conf when the DNS over TLS server is used, for example
+	// the IP address of the container shared with other containers
+	// using its network stack. It must be a local address the DNS
+	// over TLS server listens on. It defaults to `ServerAddress`
+	// and cannot be the zero value in the internal state.
+	ResolvConfAddress netip.Addr
+	// InternalAddress is the nameserver address the Go program
+	// uses, reached on port 53, when the DNS over TLS server is
+	// used, for example for block lists downloads and the control
+	// server. It must be a local address the DNS over TLS server
+	// listens on. It defaults to `ServerAddress`, which is
+	// 127.0.0.1 by default, and cannot be the zero value in the
+	// internal state.
+	InternalAddress netip.Addr
+	// InternalResolver is a fixed DNS server address the Go program
+	// uses instead of the DNS over TLS server or plaintext DNS, for
+	// example for block lists downloads and the control server,
+	// while the system uses the nameserver written to resolv.conf.
+	// It takes precedence over `InternalAddress`, and is ignored if
+	// `KeepNameserver` is true. It defaults to the unset address,
+	// in which case the Go program uses the same DNS server as the
+	// system.
+	InternalResolver netip.AddrPort
+	// PlaintextPort is the port used to reach the plaintext
+	// DNS server, which is either the `ServerAddress` if it is
+	// not 127.0.0.1, or the plaintext IP address of a DNS over
+	// TLS provider when falling back on plaintext DNS. Note the
+	// system resolv.conf cannot specify a port, so only the
+	// Go program uses this port if it is not 53.
+	// It defaults to 53 and cannot be nil or zero in the
+	// internal state.
+	PlaintextPort *uint16
+	// PlaintextAddresses are plaintext DNS server addresses tried
+	// in order when using plaintext DNS, for example resolvers of
+	// the local network, which must then be allowed as outbound
+	// subnets by the firewall. The first address answering a DNS
+	// query on the `PlaintextPort` is used, and if none answers,
+	// the `ServerAddress` if it is not 127.0.0.1, or the plaintext
+	// IP address of a DNS over TLS provider, is used as before.
+	// It defaults to an empty slice.
+	PlaintextAddresses []netip.Addr
 	// KeepNameserver is true if the existing DNS server
 	// found in /etc/resolv.conf should be used
 	// Note setting this to true will likely DNS traffic
@@ -24,16 +75,339 @@ type DNS struct {
 	// the local DNS server of your Docker/Kubernetes
 	// configuration, which is likely not going through the tunnel.
 	// This will also disable the DNS over TLS server and the
-	// `ServerAddress` field will be ignored.
+	// `ServerAddress` field will be ignored, so it cannot be
+	// set to true together with `Standalone`.
 	// It defaults to false and cannot be nil in the
 	// internal state.
 	KeepNameserver *bool
+	// Standalone is true if only the DNS over TLS server should
+	// run, without the VPN, to serve other hosts of the local
+	// network. Block lists are then downloaded and DNS over TLS
+	// upstream resolvers reached through the default interface.
+	// It requires the DNS over TLS server to be enabled and
+	// `KeepNameserver` to be false, since the existing nameserver
+	// would be used instead of the DNS over TLS server, and it
+	// requires `AllowedSubnets` to be set to serve other hosts.
+	// It defaults to false and cannot be nil in the internal state.
+	Standalone *bool
+	// ListeningAddress is the address the DNS over TLS server
+	// listens on, for example 0.0.0.0:53 to serve other hosts,
+	// or :5353 to listen on another port. Its host, if set, must
+	// be unspecified or the `ServerAddress` so the program and
+	// system can use the server. The firewall allows DNS queries
+	// from other hosts on its port only if its host is set and
+	// is not a loopback address, or in standalone mode. Note the
+	// system resolv.conf cannot specify a port, so only the Go
+	// program uses the server if its port is not 53.
+	// It defaults to ":53" and cannot be empty in the internal state.
+	ListeningAddress string
+	// AllowedSubnets are the subnets of the hosts allowed to
+	// query the DNS server, in addition to the loopback addresses
+	// which are always allowed. Queries from other hosts are
+	// refused, so the DNS server is not an open resolver when
+	// its listening address is not a loopback address.
+	AllowedSubnets []netip.Prefix
+	// BootstrapPlaintext is true if plaintext DNS should be used
+	// on start until the DNS over TLS server is ready. If false,
+	// no hostname can be resolved until the DNS over TLS server
+	// is ready, including container names of the Docker network,
+	// and block lists are downloaded resolving hostnames with the
+	// DNS over TLS upstream resolvers. Plaintext DNS is still used
+	// if the DNS over TLS server fails. It defaults to true and
+	// cannot be nil in the internal state.
+	BootstrapPlaintext *bool
+	// Strict is true if plaintext DNS should never be used, so
+	// hostnames fail to resolve while the DNS over TLS server is
+	// not ready or failed, instead of being resolved in plaintext.
+	// The DNS server is then retried with its restart backoff, and
+	// `BootstrapPlaintext` is ignored. It requires the DNS over TLS
+	// server to be enabled and `KeepNameserver` to be false.
+	// It defaults to false and cannot be nil in the internal state.
+	Strict *bool
+	// IPv6 can be "on", "off" or "auto". If "on", IPv6 addresses
+	// of DNS over TLS providers are used to connect to them and
+	// the plaintext DNS fallback, and ::1 is set as a nameserver
+	// alongside 127.0.0.1. If "auto", IPv6 is used only if the
+	// container has IPv6 support. It defaults to "off" and cannot
+	// be the empty string in the internal state.
+	IPv6 string
+	// MaxBackoff is the maximum duration to wait between
+	// two attempts to restart the DNS server after a failure.
+	// It defaults to 1h and cannot be nil in the internal state.
+	MaxBackoff *time.Duration
+	// StableUptime is the minimum duration the DNS server must
+	// keep running before its restart backoff duration is reset,
+	// so a flapping server does not restart in rapid succession.
+	// It defaults to 30s and cannot be nil in the internal state.
+	StableUptime *time.Duration
+	// StartupGrace is the duration after the DNS loop first starts
+	// during which DNS over TLS server setup failures are retried
+	// quietly, without setting the crashed status or falling back
+	// on plaintext DNS, since the VPN tunnel and upstream resolvers
+	// may take a while to be reachable on a cold start. It defaults
+	// to 30s and cannot be nil in the internal state.
+	StartupGrace *time.Duration
+	// StartupTimeout is the maximum duration of each DNS over TLS
+	// server setup, including the block lists download and the
+	// readiness check. Once it elapses, the setup is aborted and
+	// the DNS server is stopped and restarted after the backoff
+	// duration, so a hung setup does not block the DNS loop.
+	// A zero duration disables it. It defaults to 5m and cannot
+	// be nil in the internal state.
+	StartupTimeout *time.Duration
+	// TunnelWaitTimeout is the maximum duration to wait for the
+	// VPN tunnel to be up before first setting up the DNS over TLS
+	// server, so its setup does not fail repeatedly until the VPN
+	// is connected. The DNS over TLS server is set up anyway once
+	// it elapses. It is ignored in standalone mode, and a zero
+	// duration disables waiting. It defaults to 0 and cannot be
+	// nil in the internal state.
+	TunnelWaitTimeout *time.Duration
+	// KeepOnReconnect is true if the DNS over TLS server running
+	// when the VPN reconnects should be checked in the background
+	// to still resolve hostnames through the new VPN tunnel, and
+	// restarted only if it fails to resolve. If it is false, the
+	// DNS over TLS server keeps running without being checked.
+	// It defaults to true and cannot be nil in the internal state.
+	KeepOnReconnect *bool
+	// ReadinessTimeout is the maximum duration to wait for
+	// the DNS server to resolve a hostname after it started,
+	// before considering it failed. It defaults to 10s and
+	// cannot be nil in the internal state.
+	ReadinessTimeout *time.Duration
+	// ReadinessRetryInterval is the duration to wait between
+	// each resolution attempt when checking the DNS server is
+	// ready. It defaults to 300ms and cannot be nil in the
+	// internal state.
+	ReadinessRetryInterval *time.Duration
+	// StopGrace is the maximum duration to wait for queries in
+	// flight to complete when the DNS server is stopped, during
+	// which new queries are refused. It defaults to 1s and cannot
+	// be nil in the internal state. Set it to 0 to stop immediately.
+	StopGrace *time.Duration
+	// UpstreamProxy is the URL of a SOCKS5 proxy the DNS over TLS
+	// upstream connections go through, in the format
+	// socks5://[user:password@]host:port. The plaintext DNS fallback
+	// does not go through it. An empty string disables it. It defaults
+	// to the empty string and cannot be nil in the internal state.
+	UpstreamProxy *string
+	// StatusPath is the path to the file where the last status
+	// applied to the DNS loop through the control server is
+	// persisted, to restore it when the container restarts, so
+	// a stopped or paused DNS server stays stopped or paused.
+	// An empty string disables it. It defaults to the empty
+	// string and cannot be nil in the internal state.
+	StatusPath *string
+	// StatusDomain is a domain name the DNS over TLS server answers
+	// TXT queries for with the DNS and VPN status, for example
+	// `gluetun.status`, so clients without access to the control
+	// server can check the status with DNS queries. An empty string
+	// disables it. It defaults to the empty string and cannot be nil
+	// in the internal state.
+	StatusDomain *string
+	// Records is a list of static DNS records answered
+	// by the DNS over TLS server, for example to resolve
+	// local network hostnames. These are not used when
+	// the plaintext DNS is in use.
+	Records []DNSRecord
+	// ForwardZones is a list of domain suffixes forwarded
+	// to specific upstream resolvers by the DNS over TLS
+	// server, instead of the DNS over TLS providers.
+	ForwardZones []DNSForwardZone
+	// BypassDomains is a list of domain suffixes resolved by
+	// the original nameserver of the container, found in
+	// resolv.conf on start, for example to resolve local
+	// network hostnames with the LAN DNS server.
+	BypassDomains []string
+	// DNSSEC is true if DNSSEC validation should be done by the
+	// upstream DNS over TLS resolvers, with validation failures
+	// logged. If false, validation is disabled by setting the
+	// checking disabled bit on queries sent upstream.
+	// It defaults to true and cannot be nil in the internal state.
+	DNSSEC *bool
+	// EDNSClientSubnet is true if EDNS client subnet options of
+	// queries should be forwarded to the upstream resolvers, for
+	// geographically accurate answers. If false, they are removed
+	// so no information on the clients subnet is sent upstream.
+	// It defaults to false and cannot be nil in the internal state.
+	EDNSClientSubnet *bool
+	// DNS64 contains settings to synthesize AAAA records
+	// from A records for IPv6-only clients.
+	DNS64 DNS64
 	// DOT contains settings to configure the DoT
 	// server.
 	DoT DoT
 }
 
-func (d DNS) validate() (err error) {
+var (
+	ErrDNSMaxBackoffTooShort        = errors.New("maximum backoff duration is too short")
+	ErrDNSStableUptimeNegative      = errors.New("stable uptime duration is negative")
+	ErrDNSStartupGraceNegative      = errors.New("startup grace duration is negative")
+	ErrDNSStartupTimeoutNegative    = errors.New("startup timeout is negative")
+	ErrDNSTunnelWaitNegative        = errors.New("tunnel wait timeout is negative")
+	ErrDNSUpstreamProxyNotValid     = errors.New("upstream proxy URL is not valid")
+	ErrDNSReadinessTimeoutNotValid  = errors.New("readiness timeout is not valid")
+	ErrDNSReadinessIntervalNotValid = errors.New("readiness retry interval is not valid")
+	ErrDNSStopGraceNegative         = errors.New("stop grace duration is negative")
+	ErrDNSIPv6NotValid              = errors.New("IPv6 mode is not valid")
+	ErrDNSServerAddressNotValid     = errors.New("DNS server address is not valid")
+	ErrDNSResolvConfAddressNotValid = errors.New("resolv.conf DNS address is not valid")
+	ErrDNSInternalAddressNotValid   = errors.New("internal DNS address is not valid")
+	ErrDNSListeningAddressNotValid  = errors.New("DNS listening address is not valid")
+	ErrDNSPlaintextPortNotValid     = errors.New("plaintext DNS port is not valid")
+	ErrDNSPlaintextAddressNotValid  = errors.New("plaintext DNS address is not valid")
+	ErrDNSStandaloneKeepNameserver  = errors.New("standalone DNS mode cannot keep the existing nameserver")
+	ErrDNSStandaloneDoTDisabled     = errors.New("standalone DNS mode requires the DNS over TLS server")
+	ErrDNSStandaloneRequireVPN      = errors.New("standalone DNS mode cannot download block lists through the VPN")
+	ErrDNSStandaloneLoopback        = errors.New("standalone DNS mode cannot listen on a loopback address")
+	ErrDNSStandaloneAllowedSubnets  = errors.New("standalone DNS mode requires allowed subnets")
+	ErrDNSBypassDomainNotValid      = errors.New("DNS bypass domain is not valid")
+	ErrDNSStatusDomainNotValid      = errors.New("DNS status domain is not valid")
+	ErrDNSStrictKeepNameserver      = errors.New("strict DNS mode cannot keep the existing nameserver")
+	ErrDNSStrictDoTDisabled         = errors.New("strict DNS mode requires the DNS over TLS server")
+)
+
+// Validate validates the DNS settings and returns an error
+// if one of them is not valid.
+func (d DNS) Validate() (err error) {
+	if !d.ServerAddress.IsValid() {
+		return fmt.Errorf("%w", ErrDNSServerAddressNotValid)
+	}
+
+	if *d.PlaintextPort == 0 {
+		return fmt.Errorf("%w: %d must be between 1 and 65535",
+			ErrDNSPlaintextPortNotValid, *d.PlaintextPort)
+	}
+
+	for _, address := range d.PlaintextAddresses {
+		if !address.IsValid() || address.IsUnspecified() {
+			return fmt.Errorf("%w: %s", ErrDNSPlaintextAddressNotValid, address)
+		}
+	}
+
+	err = d.validateListeningAddress()
+	if err != nil {
+		return err
+	}
+
+	err = d.validateLocalAddress(d.ResolvConfAddress, ErrDNSResolvConfAddressNotValid)
+	if err != nil {
+		return err
+	}
+
+	err = d.validateLocalAddress(d.InternalAddress, ErrDNSInternalAddressNotValid)
+	if err != nil {
+		return err
+	}
+
+	if *d.Standalone {
+		switch {
+		case *d.KeepNameserver:
+			return fmt.Errorf("%w", ErrDNSStandaloneKeepNameserver)
+		case !*d.DoT.Enabled:
+			return fmt.Errorf("%w", ErrDNSStandaloneDoTDisabled)
+		case *d.DoT.Blacklist.RequireVPN:
+			return fmt.Errorf("%w", ErrDNSStandaloneRequireVPN)
+		case d.listensOnLoopback():
+			return fmt.Errorf("%w: %s", ErrDNSStandaloneLoopback, d.ListeningAddress)
+		case len(d.AllowedSubnets) == 0:
+			return fmt.Errorf("%w", ErrDNSStandaloneAllowedSubnets)
+		}
+	}
+
+	if *d.Strict {
+		switch {
+		case *d.KeepNameserver:
+			return fmt.Errorf("%w", ErrDNSStrictKeepNameserver)
+		case !*d.DoT.Enabled:
+			return fmt.Errorf("%w", ErrDNSStrictDoTDisabled)
+		}
+	}
+
+	err = validate.IsOneOf(d.IPv6, "on", "off", "auto")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSIPv6NotValid, err)
+	}
+
+	const minMaxBackoff = 10 * time.Second
+	if *d.MaxBackoff < minMaxBackoff {
+		return fmt.Errorf("%w: %s must be at least %s",
+			ErrDNSMaxBackoffTooShort, *d.MaxBackoff, minMaxBackoff)
+	}
+
+	if *d.StableUptime < 0 {
+		return fmt.Errorf("%w: %s", ErrDNSStableUptimeNegative, *d.StableUptime)
+	}
+
+	if *d.StartupGrace < 0 {
+		return fmt.Errorf("%w: %s", ErrDNSStartupGraceNegative, *d.StartupGrace)
+	}
+
+	if *d.StartupTimeout < 0 {
+		return fmt.Errorf("%w: %s", ErrDNSStartupTimeoutNegative, *d.StartupTimeout)
+	}
+
+	if *d.TunnelWaitTimeout < 0 {
+		return fmt.Errorf("%w: %s", ErrDNSTunnelWaitNegative, *d.TunnelWaitTimeout)
+	}
+
+	if *d.ReadinessTimeout <= 0 {
+		return fmt.Errorf("%w: %s must be positive",
+			ErrDNSReadinessTimeoutNotValid, *d.ReadinessTimeout)
+	}
+
+	if *d.ReadinessRetryInterval <= 0 || *d.ReadinessRetryInterval > *d.ReadinessTimeout {
+		return fmt.Errorf("%w: %s must be positive and not exceed the readiness timeout %s",
+			ErrDNSReadinessIntervalNotValid, *d.ReadinessRetryInterval, *d.ReadinessTimeout)
+	}
+
+	if *d.StopGrace < 0 {
+		return fmt.Errorf("%w: %s", ErrDNSStopGraceNegative, *d.StopGrace)
+	}
+
+	if *d.UpstreamProxy != "" { // optional
+		err = validateUpstreamProxy(*d.UpstreamProxy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *d.StatusPath != "" { // optional
+		_, err := filepath.Abs(*d.StatusPath)
+		if err != nil {
+			return fmt.Errorf("status path is not valid: %w", err)
+		}
+	}
+
+	if *d.StatusDomain != "" && !hostRegex.MatchString(*d.StatusDomain) {
+		return fmt.Errorf("%w: %s", ErrDNSStatusDomainNotValid, *d.StatusDomain)
+	}
+
+	for _, record := range d.Records {
+		err = record.validate()
+		if err != nil {
+			return fmt.Errorf("validating record: %w", err)
+		}
+	}
+
+	for _, zone := range d.ForwardZones {
+		err = zone.validate()
+		if err != nil {
+			return fmt.Errorf("validating forward zone: %w", err)
+		}
+	}
+
+	for _, domain := range d.BypassDomains {
+		if !hostRegex.MatchString(domain) {
+			return fmt.Errorf("%w: %s", ErrDNSBypassDomainNotValid, domain)
+		}
+	}
+
+	err = d.DNS64.validate()
+	if err != nil {
+		return fmt.Errorf("validating DNS64 settings: %w", err)
+	}
+
 	err = d.DoT.validate()
 	if err != nil {
 		return fmt.Errorf("validating DoT settings: %w", err)
@@ -42,27 +416,261 @@ func (d DNS) validate() (err error) {
 	return nil
 }
 
+func (d DNS) validateListeningAddress() (err error) {
+	err = validate.ListeningAddress(d.ListeningAddress, os.Getuid())
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSListeningAddressNotValid, err)
+	}
+
+	host, port, err := net.SplitHostPort(d.ListeningAddress)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSListeningAddressNotValid, err)
+	}
+
+	if port == "0" {
+		// Port 0 picks a random port unknown to the DNS clients.
+		return fmt.Errorf("%w: port cannot be 0", ErrDNSListeningAddressNotValid)
+	}
+
+	if host == "" {
+		return nil
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSListeningAddressNotValid, err)
+	}
+	if !ip.IsUnspecified() && ip != d.ServerAddress {
+		return fmt.Errorf("%w: host %s must be unspecified or the DNS server address %s",
+			ErrDNSListeningAddressNotValid, ip, d.ServerAddress)
+	}
+	return nil
+}
+
+// validateUpstreamProxy checks the upstream proxy URL given
+// is a SOCKS5 URL with a host and a port.
+func validateUpstreamProxy(proxyURL string) (err error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSUpstreamProxyNotValid, err)
+	}
+
+	switch {
+	case parsed.Scheme != "socks5":
+		return fmt.Errorf("%w: scheme %q must be socks5",
+			ErrDNSUpstreamProxyNotValid, parsed.Scheme)
+	case parsed.Hostname() == "" || parsed.Port() == "":
+		return fmt.Errorf("%w: %s: expected format socks5://[user:password@]host:port",
+			ErrDNSUpstreamProxyNotValid, parsed.Redacted())
+	}
+	return nil
+}
+
+// validateLocalAddress checks the address given is the DNS server
+// address, or a local address the DNS over TLS server listens on,
+// and wraps errNotValid otherwise.
+func (d DNS) validateLocalAddress(address netip.Addr, errNotValid error) (err error) {
+	if !address.IsValid() {
+		return fmt.Errorf("%w: %s", errNotValid, address)
+	}
+
+	if address == d.ServerAddress {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(d.ListeningAddress)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSListeningAddressNotValid, err)
+	}
+	if host != "" {
+		listeningIP, err := netip.ParseAddr(host)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDNSListeningAddressNotValid, err)
+		}
+		if !listeningIP.IsUnspecified() && listeningIP != address {
+			return fmt.Errorf("%w: %s is not the listening address host %s",
+				errNotValid, address, listeningIP)
+		}
+	}
+
+	if address.IsLoopback() {
+		return nil
+	}
+	interfaceAddresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("listing interface addresses: %w", err)
+	}
+	for _, interfaceAddress := range interfaceAddresses {
+		ipNet, ok := interfaceAddress.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipNet.IP)
+		if ok && ip.Unmap() == address.Unmap() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not an address of a local network interface",
+		errNotValid, address)
+}
+
+// ListensOnNetwork returns true if the listening address host
+// is set and is not a loopback address, such that the DNS server
+// should be reachable by other hosts. An empty host, as in the
+// default ":53", is kept reachable from the container only.
+func (d DNS) ListensOnNetwork() bool {
+	ip, ok := d.listeningIP()
+	return ok && !ip.IsLoopback()
+}
+
+func (d DNS) listensOnLoopback() bool {
+	ip, ok := d.listeningIP()
+	return ok && ip.IsLoopback()
+}
+
+// listeningIP returns the IP address of the listening address
+// host, and false if the host is empty.
+func (d DNS) listeningIP() (ip netip.Addr, ok bool) {
+	host, _, err := net.SplitHostPort(d.ListeningAddress)
+	if err != nil {
+		// Settings should be validated before calling this function,
+		// so an error happening here is a programming error.
+		panic(err)
+	}
+	if host == "" {
+		return ip, false
+	}
+	ip, err = netip.ParseAddr(host)
+	if err != nil {
+		panic(err)
+	}
+	return ip, true
+}
+
+// ListeningPort returns the port of the listening address.
+func (d DNS) ListeningPort() (port uint16) {
+	_, portString, err := net.SplitHostPort(d.ListeningAddress)
+	if err != nil {
+		panic(err)
+	}
+	portUint64, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		panic(err)
+	}
+	return uint16(portUint64)
+}
+
 func (d *DNS) Copy() (copied DNS) {
 	return DNS{
-		ServerAddress:  d.ServerAddress,
-		KeepNameserver: gosettings.CopyPointer(d.KeepNameserver),
-		DoT:            d.DoT.copy(),
+		ServerAddress:          d.ServerAddress,
+		ResolvConfAddress:      d.ResolvConfAddress,
+		InternalAddress:        d.InternalAddress,
+		InternalResolver:       d.InternalResolver,
+		PlaintextPort:          gosettings.CopyPointer(d.PlaintextPort),
+		PlaintextAddresses:     gosettings.CopySlice(d.PlaintextAddresses),
+		KeepNameserver:         gosettings.CopyPointer(d.KeepNameserver),
+		BootstrapPlaintext:     gosettings.CopyPointer(d.BootstrapPlaintext),
+		Strict:                 gosettings.CopyPointer(d.Strict),
+		Standalone:             gosettings.CopyPointer(d.Standalone),
+		ListeningAddress:       d.ListeningAddress,
+		AllowedSubnets:         gosettings.CopySlice(d.AllowedSubnets),
+		IPv6:                   d.IPv6,
+		MaxBackoff:             gosettings.CopyPointer(d.MaxBackoff),
+		StableUptime:           gosettings.CopyPointer(d.StableUptime),
+		StartupGrace:           gosettings.CopyPointer(d.StartupGrace),
+		StartupTimeout:         gosettings.CopyPointer(d.StartupTimeout),
+		TunnelWaitTimeout:      gosettings.CopyPointer(d.TunnelWaitTimeout),
+		KeepOnReconnect:        gosettings.CopyPointer(d.KeepOnReconnect),
+		ReadinessTimeout:       gosettings.CopyPointer(d.ReadinessTimeout),
+		ReadinessRetryInterval: gosettings.CopyPointer(d.ReadinessRetryInterval),
+		StopGrace:              gosettings.CopyPointer(d.StopGrace),
+		UpstreamProxy:          gosettings.CopyPointer(d.UpstreamProxy),
+		StatusPath:             gosettings.CopyPointer(d.StatusPath),
+		StatusDomain:           gosettings.CopyPointer(d.StatusDomain),
+		Records:                gosettings.CopySlice(d.Records),
+		ForwardZones:           gosettings.CopySlice(d.ForwardZones),
+		BypassDomains:          gosettings.CopySlice(d.BypassDomains),
+		DNSSEC:                 gosettings.CopyPointer(d.DNSSEC),
+		EDNSClientSubnet:       gosettings.CopyPointer(d.EDNSClientSubnet),
+		DNS64:                  d.DNS64.copy(),
+		DoT:                    d.DoT.copy(),
 	}
 }
 
-// overrideWith overrides fields of the receiver
+// OverrideWith overrides fields of the receiver
 // settings object with any field set in the other
 // settings.
-func (d *DNS) overrideWith(other DNS) {
+func (d *DNS) OverrideWith(other DNS) {
 	d.ServerAddress = gosettings.OverrideWithValidator(d.ServerAddress, other.ServerAddress)
+	d.ResolvConfAddress = gosettings.OverrideWithValidator(d.ResolvConfAddress, other.ResolvConfAddress)
+	d.InternalAddress = gosettings.OverrideWithValidator(d.InternalAddress, other.InternalAddress)
+	d.InternalResolver = gosettings.OverrideWithValidator(d.InternalResolver, other.InternalResolver)
+	d.PlaintextPort = gosettings.OverrideWithPointer(d.PlaintextPort, other.PlaintextPort)
+	d.PlaintextAddresses = gosettings.OverrideWithSlice(d.PlaintextAddresses, other.PlaintextAddresses)
 	d.KeepNameserver = gosettings.OverrideWithPointer(d.KeepNameserver, other.KeepNameserver)
+	d.BootstrapPlaintext = gosettings.OverrideWithPointer(d.BootstrapPlaintext, other.BootstrapPlaintext)
+	d.Strict = gosettings.OverrideWithPointer(d.Strict, other.Strict)
+	d.Standalone = gosettings.OverrideWithPointer(d.Standalone, other.Standalone)
+	d.ListeningAddress = gosettings.OverrideWithComparable(d.ListeningAddress, other.ListeningAddress)
+	d.AllowedSubnets = gosettings.OverrideWithSlice(d.AllowedSubnets, other.AllowedSubnets)
+	d.IPv6 = gosettings.OverrideWithComparable(d.IPv6, other.IPv6)
+	d.MaxBackoff = gosettings.OverrideWithPointer(d.MaxBackoff, other.MaxBackoff)
+	d.StableUptime = gosettings.OverrideWithPointer(d.StableUptime, other.StableUptime)
+	d.StartupGrace = gosettings.OverrideWithPointer(d.StartupGrace, other.StartupGrace)
+	d.StartupTimeout = gosettings.OverrideWithPointer(d.StartupTimeout, other.StartupTimeout)
+	d.TunnelWaitTimeout = gosettings.OverrideWithPointer(d.TunnelWaitTimeout, other.TunnelWaitTimeout)
+	d.KeepOnReconnect = gosettings.OverrideWithPointer(d.KeepOnReconnect, other.KeepOnReconnect)
+	d.Records = gosettings.OverrideWithSlice(d.Records, other.Records)
+	d.ForwardZones = gosettings.OverrideWithSlice(d.ForwardZones, other.ForwardZones)
+	d.BypassDomains = gosettings.OverrideWithSlice(d.BypassDomains, other.BypassDomains)
+	d.DNSSEC = gosettings.OverrideWithPointer(d.DNSSEC, other.DNSSEC)
+	d.EDNSClientSubnet = gosettings.OverrideWithPointer(d.EDNSClientSubnet, other.EDNSClientSubnet)
+	d.DNS64.overrideWith(other.DNS64)
+	d.ReadinessTimeout = gosettings.OverrideWithPointer(d.ReadinessTimeout, other.ReadinessTimeout)
+	d.ReadinessRetryInterval = gosettings.OverrideWithPointer(d.ReadinessRetryInterval,
+		other.ReadinessRetryInterval)
+	d.StopGrace = gosettings.OverrideWithPointer(d.StopGrace, other.StopGrace)
+	d.UpstreamProxy = gosettings.OverrideWithPointer(d.UpstreamProxy, other.UpstreamProxy)
+	d.StatusPath = gosettings.OverrideWithPointer(d.StatusPath, other.StatusPath)
+	d.StatusDomain = gosettings.OverrideWithPointer(d.StatusDomain, other.StatusDomain)
 	d.DoT.overrideWith(other.DoT)
 }
 
 func (d *DNS) setDefaults() {
 	localhost := netip.AddrFrom4([4]byte{127, 0, 0, 1})
 	d.ServerAddress = gosettings.DefaultValidator(d.ServerAddress, localhost)
+	d.ResolvConfAddress = gosettings.DefaultValidator(d.ResolvConfAddress, d.ServerAddress)
+	d.InternalAddress = gosettings.DefaultValidator(d.InternalAddress, d.ServerAddress)
+	const defaultPlaintextPort = 53
+	d.PlaintextPort = gosettings.DefaultPointer(d.PlaintextPort, defaultPlaintextPort)
 	d.KeepNameserver = gosettings.DefaultPointer(d.KeepNameserver, false)
+	d.BootstrapPlaintext = gosettings.DefaultPointer(d.BootstrapPlaintext, true)
+	d.Strict = gosettings.DefaultPointer(d.Strict, false)
+	d.Standalone = gosettings.DefaultPointer(d.Standalone, false)
+	d.ListeningAddress = gosettings.DefaultComparable(d.ListeningAddress, ":53")
+	d.IPv6 = gosettings.DefaultComparable(d.IPv6, "off")
+	const defaultMaxBackoff = time.Hour
+	d.MaxBackoff = gosettings.DefaultPointer(d.MaxBackoff, defaultMaxBackoff)
+	const defaultStableUptime = 30 * time.Second
+	d.StableUptime = gosettings.DefaultPointer(d.StableUptime, defaultStableUptime)
+	const defaultStartupGrace = 30 * time.Second
+	d.StartupGrace = gosettings.DefaultPointer(d.StartupGrace, defaultStartupGrace)
+	const defaultStartupTimeout = 5 * time.Minute
+	d.StartupTimeout = gosettings.DefaultPointer(d.StartupTimeout, defaultStartupTimeout)
+	d.TunnelWaitTimeout = gosettings.DefaultPointer(d.TunnelWaitTimeout, 0)
+	d.KeepOnReconnect = gosettings.DefaultPointer(d.KeepOnReconnect, true)
+	const defaultReadinessTimeout = 10 * time.Second
+	d.ReadinessTimeout = gosettings.DefaultPointer(d.ReadinessTimeout, defaultReadinessTimeout)
+	const defaultReadinessRetryInterval = 300 * time.Millisecond
+	d.ReadinessRetryInterval = gosettings.DefaultPointer(d.ReadinessRetryInterval,
+		defaultReadinessRetryInterval)
+	const defaultStopGrace = time.Second
+	d.StopGrace = gosettings.DefaultPointer(d.StopGrace, defaultStopGrace)
+	d.UpstreamProxy = gosettings.DefaultPointer(d.UpstreamProxy, "")
+	d.StatusPath = gosettings.DefaultPointer(d.StatusPath, "")
+	d.StatusDomain = gosettings.DefaultPointer(d.StatusDomain, "")
+	d.DNSSEC = gosettings.DefaultPointer(d.DNSSEC, true)
+	d.EDNSClientSubnet = gosettings.DefaultPointer(d.EDNSClientSubnet, false)
+	d.DNS64.setDefaults()
 	d.DoT.setDefaults()
 }
 
@@ -76,7 +684,83 @@ func (d DNS) toLinesNode() (node *gotree.Node) {
 	if *d.KeepNameserver {
 		return node
 	}
+	node.Appendf("Standalone without VPN: %s", gosettings.BoolToYesNo(d.Standalone))
 	node.Appendf("DNS server address to use: %s", d.ServerAddress)
+	if d.ResolvConfAddress != d.ServerAddress {
+		node.Appendf("resolv.conf DNS address: %s", d.ResolvConfAddress)
+	}
+	if d.InternalAddress != d.ServerAddress {
+		node.Appendf("Internal DNS address: %s", d.InternalAddress)
+	}
+	if d.InternalResolver.IsValid() {
+		node.Appendf("Internal DNS resolver: %s", d.InternalResolver)
+	}
+	node.Appendf("Plaintext DNS port: %d", *d.PlaintextPort)
+	if len(d.PlaintextAddresses) > 0 {
+		plaintextNode := node.Appendf("Plaintext DNS addresses:")
+		for _, address := range d.PlaintextAddresses {
+			plaintextNode.Appendf(address.String())
+		}
+	}
+	node.Appendf("Listening address: %s", d.ListeningAddress)
+	if len(d.AllowedSubnets) > 0 {
+		allowedSubnetsNode := node.Appendf("Allowed subnets:")
+		for _, subnet := range d.AllowedSubnets {
+			allowedSubnetsNode.Appendf(subnet.String())
+		}
+	}
+	node.Appendf("Strict mode without plaintext DNS: %s", gosettings.BoolToYesNo(d.Strict))
+	if !*d.Strict {
+		node.Appendf("Plaintext DNS until ready: %s", gosettings.BoolToYesNo(d.BootstrapPlaintext))
+	}
+	node.Appendf("IPv6: %s", d.IPv6)
+	node.Appendf("Maximum restart backoff: %s", *d.MaxBackoff)
+	node.Appendf("Uptime to reset restart backoff: %s", *d.StableUptime)
+	node.Appendf("Startup grace period: %s", *d.StartupGrace)
+	if *d.StartupTimeout > 0 {
+		node.Appendf("Startup timeout: %s", *d.StartupTimeout)
+	}
+	if *d.TunnelWaitTimeout > 0 && !*d.Standalone {
+		node.Appendf("Wait for the VPN tunnel on start: up to %s", *d.TunnelWaitTimeout)
+	}
+	if !*d.Standalone {
+		node.Appendf("Check on VPN reconnections: %s", gosettings.BoolToYesNo(d.KeepOnReconnect))
+	}
+	node.Appendf("Readiness check: timeout %s, retry every %s",
+		*d.ReadinessTimeout, *d.ReadinessRetryInterval)
+	node.Appendf("Stop grace period: %s", *d.StopGrace)
+	if *d.UpstreamProxy != "" {
+		// Settings are validated before being displayed.
+		proxyURL, _ := url.Parse(*d.UpstreamProxy)
+		node.Appendf("Upstream SOCKS5 proxy: %s", proxyURL.Redacted())
+	}
+	if *d.StatusPath != "" {
+		node.Appendf("Status persistence path: %s", *d.StatusPath)
+	}
+	if *d.StatusDomain != "" {
+		node.Appendf("Status domain: %s", *d.StatusDomain)
+	}
+	if len(d.Records) > 0 {
+		recordsNode := node.Appendf("Static records:")
+		for _, record := range d.Records {
+			recordsNode.Appendf(record.String())
+		}
+	}
+	if len(d.ForwardZones) > 0 {
+		zonesNode := node.Appendf("Forward zones:")
+		for _, zone := range d.ForwardZones {
+			zonesNode.Appendf(zone.String())
+		}
+	}
+	if len(d.BypassDomains) > 0 {
+		bypassNode := node.Appendf("Domains resolved by the original nameserver:")
+		for _, domain := range d.BypassDomains {
+			bypassNode.Appendf(domain)
+		}
+	}
+	node.Appendf("DNSSEC validation: %s", gosettings.BoolToYesNo(d.DNSSEC))
+	node.Appendf("EDNS client subnet: %s", gosettings.BoolToYesNo(d.EDNSClientSubnet))
+	node.AppendNode(d.DNS64.toLinesNode())
 	node.AppendNode(d.DoT.toLinesNode())
 	return node
 }
@@ -87,11 +771,159 @@ func (d *DNS) read(r *reader.Reader) (err error) {
 		return err
 	}
 
+	d.PlaintextPort, err = r.Uint16Ptr("DNS_PLAINTEXT_PORT")
+	if err != nil {
+		return err
+	}
+
+	d.PlaintextAddresses, err = r.CSVNetipAddresses("DNS_PLAINTEXT_ADDRESSES")
+	if err != nil {
+		return err
+	}
+
+	d.ResolvConfAddress, err = r.NetipAddr("DNS_RESOLV_CONF_ADDRESS")
+	if err != nil {
+		return err
+	}
+
+	d.InternalAddress, err = r.NetipAddr("DNS_INTERNAL_ADDRESS")
+	if err != nil {
+		return err
+	}
+
+	d.InternalResolver, err = r.NetipAddrPort("DNS_INTERNAL_RESOLVER")
+	if err != nil {
+		return err
+	}
+
 	d.KeepNameserver, err = r.BoolPtr("DNS_KEEP_NAMESERVER")
 	if err != nil {
 		return err
 	}
 
+	d.BootstrapPlaintext, err = r.BoolPtr("DNS_BOOTSTRAP_PLAINTEXT")
+	if err != nil {
+		return err
+	}
+
+	d.Strict, err = r.BoolPtr("DNS_STRICT")
+	if err != nil {
+		return err
+	}
+
+	d.Standalone, err = r.BoolPtr("DNS_ONLY")
+	if err != nil {
+		return err
+	}
+
+	d.ListeningAddress = r.String("DNS_LISTENING_ADDRESS")
+
+	d.IPv6 = strings.ToLower(r.String("DNS_IPV6", reader.RetroKeys("DOT_IPV6")))
+	switch d.IPv6 {
+	case "yes", "true":
+		d.IPv6 = "on"
+	case "no", "false":
+		d.IPv6 = "off"
+	}
+
+	d.MaxBackoff, err = r.DurationPtr("DNS_MAX_BACKOFF")
+	if err != nil {
+		return err
+	}
+
+	d.StableUptime, err = r.DurationPtr("DNS_STABLE_UPTIME")
+	if err != nil {
+		return err
+	}
+
+	d.StartupGrace, err = r.DurationPtr("DNS_STARTUP_GRACE")
+	if err != nil {
+		return err
+	}
+
+	d.StartupTimeout, err = r.DurationPtr("DNS_STARTUP_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	d.TunnelWaitTimeout, err = r.DurationPtr("DNS_TUNNEL_WAIT_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	d.KeepOnReconnect, err = r.BoolPtr("DNS_KEEP_ON_VPN_RECONNECT")
+	if err != nil {
+		return err
+	}
+
+	d.ReadinessTimeout, err = r.DurationPtr("DNS_READINESS_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	d.ReadinessRetryInterval, err = r.DurationPtr("DNS_READINESS_RETRY_INTERVAL")
+	if err != nil {
+		return err
+	}
+
+	d.StopGrace, err = r.DurationPtr("DNS_STOP_GRACE")
+	if err != nil {
+		return err
+	}
+
+	d.UpstreamProxy = r.Get("DNS_UPSTREAM_PROXY", reader.AcceptEmpty(true))
+
+	d.StatusPath = r.Get("DNS_STATUS_PATH", reader.AcceptEmpty(true))
+
+	d.StatusDomain = r.Get("DNS_STATUS_DOMAIN", reader.AcceptEmpty(true))
+
+	recordStrings := r.CSV("DNS_RECORDS")
+	if len(recordStrings) > 0 {
+		d.Records = make([]DNSRecord, len(recordStrings))
+		for i, recordString := range recordStrings {
+			d.Records[i], err = parseDNSRecord(recordString)
+			if err != nil {
+				return fmt.Errorf("environment variable DNS_RECORDS: %w", err)
+			}
+		}
+	}
+
+	zoneStrings := r.CSV("DNS_FORWARD_ZONES")
+	if len(zoneStrings) > 0 {
+		d.ForwardZones = make([]DNSForwardZone, len(zoneStrings))
+		for i, zoneString := range zoneStrings {
+			d.ForwardZones[i], err = parseDNSForwardZone(zoneString)
+			if err != nil {
+				return fmt.Errorf("environment variable DNS_FORWARD_ZONES: %w", err)
+			}
+		}
+	}
+
+	d.AllowedSubnets, err = r.CSVNetipPrefixes("DNS_ALLOWED_SUBNETS")
+	if err != nil {
+		return err
+	}
+
+	d.BypassDomains = r.CSV("DNS_BYPASS_DOMAINS")
+	for i, domain := range d.BypassDomains {
+		d.BypassDomains[i] = strings.Trim(strings.ToLower(domain), ".")
+	}
+
+	d.DNSSEC, err = r.BoolPtr("DNSSEC")
+	if err != nil {
+		return err
+	}
+
+	d.EDNSClientSubnet, err = r.BoolPtr("DNS_EDNS_CLIENT_SUBNET")
+	if err != nil {
+		return err
+	}
+
+	err = d.DNS64.read(r)
+	if err != nil {
+		return fmt.Errorf("DNS64 settings: %w", err)
+	}
+
 	err = d.DoT.read(r)
 	if err != nil {
 		return fmt.Errorf("DNS over TLS settings: %w", err)
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gosettings/reader"
+	"github.com/qdm12/gotree"
+)
+
+// DNS64 contains settings to synthesize AAAA records from
+// A records for IPv6-only clients behind a NAT64 gateway.
+type DNS64 struct {
+	// Enabled is true if AAAA records should be synthesized
+	// for hostnames having only A records.
+	// It defaults to false and cannot be nil in the internal state.
+	Enabled *bool
+	// Prefix is the NAT64 IPv6 prefix the IPv4 addresses are
+	// embedded in, as defined in RFC 6052. Its length must be
+	// one of 32, 40, 48, 56, 64 or 96.
+	// It defaults to 64:ff9b::/96 and cannot be the zero value
+	// in the internal state.
+	Prefix netip.Prefix
+}
+
+var ErrDNS64PrefixNotValid = errors.New("DNS64 prefix is not valid")
+
+func (d DNS64) validate() (err error) {
+	if !d.Prefix.Addr().Is6() || d.Prefix.Addr().Is4In6() {
+		return fmt.Errorf("%w: %s is not an IPv6 prefix", ErrDNS64PrefixNotValid, d.Prefix)
+	}
+
+	switch d.Prefix.Bits() {
+	case 32, 40, 48, 56, 64, 96: //nolint:gomnd
+	default:
+		return fmt.Errorf("%w: %s must have a length of 32, 40, 48, 56, 64 or 96",
+			ErrDNS64PrefixNotValid, d.Prefix)
+	}
+
+	return nil
+}
+
+func (d *DNS64) copy() (copied DNS64) {
+	return DNS64{
+		Enabled: gosettings.CopyPointer(d.Enabled),
+		Prefix:  d.Prefix,
+	}
+}
+
+func (d *DNS64) overrideWith(other DNS64) {
+	d.Enabled = gosettings.OverrideWithPointer(d.Enabled, other.Enabled)
+	d.Prefix = gosettings.OverrideWithValidator(d.Prefix, other.Prefix)
+}
+
+func (d *DNS64) setDefaults() {
+	d.Enabled = gosettings.DefaultPointer(d.Enabled, false)
+	wellKnownPrefix := netip.MustParsePrefix("64:ff9b::/96")
+	d.Prefix = gosettings.DefaultValidator(d.Prefix, wellKnownPrefix)
+}
+
+func (d DNS64) String() string {
+	return d.toLinesNode().String()
+}
+
+func (d DNS64) toLinesNode() (node *gotree.Node) {
+	node = gotree.New("DNS64 settings:")
+	node.Appendf("Enabled: %s", gosettings.BoolToYesNo(d.Enabled))
+	if !*d.Enabled {
+		return node
+	}
+	node.Appendf("Prefix: %s", d.Prefix)
+	return node
+}
+
+func (d *DNS64) read(r *reader.Reader) (err error) {
+	d.Enabled, err = r.BoolPtr("DNS64")
+	if err != nil {
+		return err
+	}
+
+	d.Prefix, err = r.NetipPrefix("DNS64_PREFIX")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
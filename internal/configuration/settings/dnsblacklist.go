@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/netip"
+	"net/url"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/qdm12/dns/v2/pkg/blockbuilder"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/reader"
+	"github.com/qdm12/gosettings/validate"
 	"github.com/qdm12/gotree"
 )
 
@@ -22,19 +27,161 @@ type DNSBlacklist struct {
 	AddBlockedHosts      []string
 	AddBlockedIPs        []netip.Addr
 	AddBlockedIPPrefixes []netip.Prefix
+	// HostnamesURLs are the URLs of additional hostnames block
+	// lists, with one hostname per line, downloaded together with
+	// the built-in block lists. It defaults to an empty slice.
+	HostnamesURLs []string
+	// IPsURLs are the URLs of additional IP addresses block lists,
+	// with one IP address or IP prefix per line, downloaded together
+	// with the built-in block lists. It defaults to an empty slice.
+	IPsURLs []string
+	// LocalListsPath is the path to a directory containing
+	// block list .txt files, with one hostname, IP address
+	// or IP prefix per line. These are merged with the
+	// downloaded block lists, and are used alone if the
+	// download fails. An empty string disables it.
+	// It defaults to /gluetun/blocklists and cannot be nil
+	// in the internal state.
+	LocalListsPath *string
+	// CachePath is the path to the file where the last downloaded
+	// block lists are cached, to start the DNS server quickly with
+	// them on the next start while the block lists are downloaded
+	// again in the background. An empty string disables it.
+	// It defaults to /gluetun/blocklists-cache.json and cannot be
+	// nil in the internal state.
+	CachePath *string
+	// CacheMaxAge is the maximum age of the cached block lists to
+	// use them on start. Older cached block lists are ignored and
+	// the block lists are downloaded before the DNS server starts.
+	// It defaults to 168h and cannot be nil or zero in the internal state.
+	CacheMaxAge *time.Duration
+	// ExportPath is the path to the file where the blocked hostnames,
+	// IP addresses and IP prefixes used by the DNS server are written,
+	// one per line, for other programs to use them. An empty string
+	// disables it. It defaults to the empty string and cannot be nil
+	// in the internal state.
+	ExportPath *string
+	// RPZPath is the path to the file where the blocked hostnames,
+	// IP addresses and IP prefixes used by the DNS server are written
+	// as a Response Policy Zone, for resolvers such as BIND to use
+	// them. An empty string disables it. It defaults to the empty
+	// string and cannot be nil in the internal state.
+	RPZPath *string
+	// PostUpdateHook is the command to run after each successful
+	// block lists update, with the number of entries of each block
+	// list source set as environment variables. An empty string
+	// disables it. It defaults to the empty string and cannot be
+	// nil in the internal state.
+	PostUpdateHook *string
+	// PostUpdateHookTimeout is the maximum duration of the post
+	// update hook command, after which it is killed. It defaults
+	// to 30s and cannot be nil or zero in the internal state.
+	PostUpdateHookTimeout *time.Duration
+	// MaxConcurrentDownloads is the maximum number of block
+	// lists to download at the same time. It defaults to 4
+	// and cannot be nil or zero in the internal state.
+	MaxConcurrentDownloads *uint
+	// MinEntries is the minimum number of hostnames, IP addresses
+	// and IP prefixes the downloaded block lists must contain
+	// together to be applied. Downloaded block lists with fewer
+	// entries are discarded and the previous block lists are kept,
+	// in case most downloads failed. It defaults to 0, which
+	// disables the check, and cannot be nil in the internal state.
+	MinEntries *uint
+	// DownloadTimeout is the maximum duration of each block list
+	// download, so a hung source does not block the block lists
+	// update. It defaults to 15s and cannot be nil or zero in the
+	// internal state.
+	DownloadTimeout *time.Duration
+	// MaxIdleConnsPerHost is the maximum number of idle connections
+	// kept open to each block list host, to reuse them for the next
+	// downloads. It defaults to 2 and cannot be nil or zero in the
+	// internal state.
+	MaxIdleConnsPerHost *uint
+	// IdleConnTimeout is the duration after which idle connections
+	// to block list hosts are closed. Set it to 0 to keep them open
+	// without limit. It defaults to 90s and cannot be nil in the
+	// internal state.
+	IdleConnTimeout *time.Duration
+	// RequireVPN is true if block lists should only be
+	// downloaded once the VPN tunnel is up, and through
+	// the VPN tunnel interface. It defaults to false and
+	// cannot be nil in the internal state.
+	RequireVPN *bool
+	// SourceAddress is the local IP address block lists are
+	// downloaded from, to egress through a specific interface on
+	// hosts with multiple interfaces. It defaults to the unset
+	// address, in which case the default route picks it.
+	SourceAddress netip.Addr
+	// BlockResponse is the response to blocked queries, which can be
+	// "refused" for a REFUSED response, "nxdomain" for a NXDOMAIN
+	// response, or "null" to answer 0.0.0.0 or :: for A and AAAA
+	// queries. It defaults to "refused" and cannot be the empty
+	// string in the internal state.
+	BlockResponse string
+	// MaxMalformedPercent is the maximum percentage of malformed
+	// lines in a downloaded block list, above which the block list
+	// source is rejected entirely, for example if a block list URL
+	// returns an HTML error page. Malformed lines are dropped from
+	// block lists under the threshold. Set it to 100 to never reject
+	// a block list source. It defaults to 10 and cannot be nil in the
+	// internal state.
+	MaxMalformedPercent *uint
+	// LogMalformed is true if the malformed lines of downloaded block
+	// lists should be logged. It defaults to false and cannot be nil
+	// in the internal state.
+	LogMalformed *bool
 }
 
 func (b *DNSBlacklist) setDefaults() {
 	b.BlockMalicious = gosettings.DefaultPointer(b.BlockMalicious, true)
 	b.BlockAds = gosettings.DefaultPointer(b.BlockAds, false)
 	b.BlockSurveillance = gosettings.DefaultPointer(b.BlockSurveillance, true)
+	const defaultLocalListsPath = "/gluetun/blocklists"
+	b.LocalListsPath = gosettings.DefaultPointer(b.LocalListsPath, defaultLocalListsPath)
+	const defaultCachePath = "/gluetun/blocklists-cache.json"
+	b.CachePath = gosettings.DefaultPointer(b.CachePath, defaultCachePath)
+	const defaultCacheMaxAge = 7 * 24 * time.Hour
+	b.CacheMaxAge = gosettings.DefaultPointer(b.CacheMaxAge, defaultCacheMaxAge)
+	b.ExportPath = gosettings.DefaultPointer(b.ExportPath, "")
+	b.RPZPath = gosettings.DefaultPointer(b.RPZPath, "")
+	b.PostUpdateHook = gosettings.DefaultPointer(b.PostUpdateHook, "")
+	const defaultPostUpdateHookTimeout = 30 * time.Second
+	b.PostUpdateHookTimeout = gosettings.DefaultPointer(b.PostUpdateHookTimeout,
+		defaultPostUpdateHookTimeout)
+	const defaultMaxConcurrentDownloads = 4
+	b.MaxConcurrentDownloads = gosettings.DefaultPointer(b.MaxConcurrentDownloads,
+		defaultMaxConcurrentDownloads)
+	b.MinEntries = gosettings.DefaultPointer(b.MinEntries, 0)
+	const defaultDownloadTimeout = 15 * time.Second
+	b.DownloadTimeout = gosettings.DefaultPointer(b.DownloadTimeout, defaultDownloadTimeout)
+	const defaultMaxIdleConnsPerHost = 2
+	b.MaxIdleConnsPerHost = gosettings.DefaultPointer(b.MaxIdleConnsPerHost,
+		defaultMaxIdleConnsPerHost)
+	const defaultIdleConnTimeout = 90 * time.Second
+	b.IdleConnTimeout = gosettings.DefaultPointer(b.IdleConnTimeout, defaultIdleConnTimeout)
+	b.RequireVPN = gosettings.DefaultPointer(b.RequireVPN, false)
+	b.BlockResponse = gosettings.DefaultComparable(b.BlockResponse, "refused")
+	const defaultMaxMalformedPercent = 10
+	b.MaxMalformedPercent = gosettings.DefaultPointer(b.MaxMalformedPercent,
+		defaultMaxMalformedPercent)
+	b.LogMalformed = gosettings.DefaultPointer(b.LogMalformed, false)
 }
 
 var hostRegex = regexp.MustCompile(`^([a-zA-Z0-9]|[a-zA-Z0-9_][a-zA-Z0-9\-_]{0,61}[a-zA-Z0-9_])(\.([a-zA-Z0-9]|[a-zA-Z0-9_][a-zA-Z0-9\-_]{0,61}[a-zA-Z0-9]))*$`) //nolint:lll
 
 var (
-	ErrAllowedHostNotValid = errors.New("allowed host is not valid")
-	ErrBlockedHostNotValid = errors.New("blocked host is not valid")
+	ErrAllowedHostNotValid          = errors.New("allowed host is not valid")
+	ErrBlockedHostNotValid          = errors.New("blocked host is not valid")
+	ErrBlockListURLNotValid         = errors.New("block list URL is not valid")
+	ErrMaxConcurrentDownloadsIsZero = errors.New("maximum concurrent downloads cannot be zero")
+	ErrDownloadTimeoutNotValid      = errors.New("download timeout is not valid")
+	ErrMaxIdleConnsPerHostIsZero    = errors.New("maximum idle connections per host cannot be zero")
+	ErrIdleConnTimeoutNegative      = errors.New("idle connection timeout is negative")
+	ErrBlockResponseNotValid        = errors.New("block response is not valid")
+	ErrCacheMaxAgeNotValid          = errors.New("block lists cache maximum age is not valid")
+	ErrHookTimeoutNotValid          = errors.New("post update hook timeout is not valid")
+	ErrMaxMalformedPercentTooHigh   = errors.New("maximum malformed lines percentage is too high")
 )
 
 func (b DNSBlacklist) validate() (err error) {
@@ -50,18 +197,126 @@ func (b DNSBlacklist) validate() (err error) {
 		}
 	}
 
+	for _, urls := range [][]string{b.HostnamesURLs, b.IPsURLs} {
+		for _, blockListURL := range urls {
+			err = validateBlockListURL(blockListURL)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = validate.IsOneOf(b.BlockResponse, "refused", "nxdomain", "null")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBlockResponseNotValid, err)
+	}
+
+	if *b.MaxConcurrentDownloads == 0 {
+		return fmt.Errorf("%w", ErrMaxConcurrentDownloadsIsZero)
+	}
+
+	if *b.DownloadTimeout <= 0 {
+		return fmt.Errorf("%w: %s must be positive", ErrDownloadTimeoutNotValid, *b.DownloadTimeout)
+	}
+
+	if *b.MaxIdleConnsPerHost == 0 {
+		return fmt.Errorf("%w", ErrMaxIdleConnsPerHostIsZero)
+	}
+
+	if *b.IdleConnTimeout < 0 {
+		return fmt.Errorf("%w: %s", ErrIdleConnTimeoutNegative, *b.IdleConnTimeout)
+	}
+
+	const maxPercent = 100
+	if *b.MaxMalformedPercent > maxPercent {
+		return fmt.Errorf("%w: %d%% must be at most %d%%",
+			ErrMaxMalformedPercentTooHigh, *b.MaxMalformedPercent, maxPercent)
+	}
+
+	if *b.LocalListsPath != "" { // optional
+		_, err := filepath.Abs(*b.LocalListsPath)
+		if err != nil {
+			return fmt.Errorf("local block lists path is not valid: %w", err)
+		}
+	}
+
+	if *b.CachePath != "" { // optional
+		_, err := filepath.Abs(*b.CachePath)
+		if err != nil {
+			return fmt.Errorf("block lists cache path is not valid: %w", err)
+		}
+
+		if *b.CacheMaxAge <= 0 {
+			return fmt.Errorf("%w: %s must be positive", ErrCacheMaxAgeNotValid, *b.CacheMaxAge)
+		}
+	}
+
+	if *b.ExportPath != "" { // optional
+		_, err := filepath.Abs(*b.ExportPath)
+		if err != nil {
+			return fmt.Errorf("block lists export path is not valid: %w", err)
+		}
+	}
+
+	if *b.RPZPath != "" { // optional
+		_, err := filepath.Abs(*b.RPZPath)
+		if err != nil {
+			return fmt.Errorf("block lists RPZ path is not valid: %w", err)
+		}
+	}
+
+	if *b.PostUpdateHook != "" && *b.PostUpdateHookTimeout <= 0 {
+		return fmt.Errorf("%w: %s must be positive",
+			ErrHookTimeoutNotValid, *b.PostUpdateHookTimeout)
+	}
+
+	return nil
+}
+
+func validateBlockListURL(blockListURL string) (err error) {
+	parsed, err := url.Parse(blockListURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBlockListURLNotValid, err)
+	}
+
+	switch {
+	case parsed.Scheme != "http" && parsed.Scheme != "https":
+		return fmt.Errorf("%w: scheme %q must be http or https",
+			ErrBlockListURLNotValid, parsed.Scheme)
+	case parsed.Hostname() == "":
+		return fmt.Errorf("%w: %s has no host", ErrBlockListURLNotValid, parsed.Redacted())
+	}
 	return nil
 }
 
 func (b DNSBlacklist) copy() (copied DNSBlacklist) {
 	return DNSBlacklist{
-		BlockMalicious:       gosettings.CopyPointer(b.BlockMalicious),
-		BlockAds:             gosettings.CopyPointer(b.BlockAds),
-		BlockSurveillance:    gosettings.CopyPointer(b.BlockSurveillance),
-		AllowedHosts:         gosettings.CopySlice(b.AllowedHosts),
-		AddBlockedHosts:      gosettings.CopySlice(b.AddBlockedHosts),
-		AddBlockedIPs:        gosettings.CopySlice(b.AddBlockedIPs),
-		AddBlockedIPPrefixes: gosettings.CopySlice(b.AddBlockedIPPrefixes),
+		BlockMalicious:         gosettings.CopyPointer(b.BlockMalicious),
+		BlockAds:               gosettings.CopyPointer(b.BlockAds),
+		BlockSurveillance:      gosettings.CopyPointer(b.BlockSurveillance),
+		AllowedHosts:           gosettings.CopySlice(b.AllowedHosts),
+		AddBlockedHosts:        gosettings.CopySlice(b.AddBlockedHosts),
+		AddBlockedIPs:          gosettings.CopySlice(b.AddBlockedIPs),
+		AddBlockedIPPrefixes:   gosettings.CopySlice(b.AddBlockedIPPrefixes),
+		HostnamesURLs:          gosettings.CopySlice(b.HostnamesURLs),
+		IPsURLs:                gosettings.CopySlice(b.IPsURLs),
+		LocalListsPath:         gosettings.CopyPointer(b.LocalListsPath),
+		CachePath:              gosettings.CopyPointer(b.CachePath),
+		CacheMaxAge:            gosettings.CopyPointer(b.CacheMaxAge),
+		ExportPath:             gosettings.CopyPointer(b.ExportPath),
+		RPZPath:                gosettings.CopyPointer(b.RPZPath),
+		PostUpdateHook:         gosettings.CopyPointer(b.PostUpdateHook),
+		PostUpdateHookTimeout:  gosettings.CopyPointer(b.PostUpdateHookTimeout),
+		MaxConcurrentDownloads: gosettings.CopyPointer(b.MaxConcurrentDownloads),
+		MinEntries:             gosettings.CopyPointer(b.MinEntries),
+		DownloadTimeout:        gosettings.CopyPointer(b.DownloadTimeout),
+		MaxIdleConnsPerHost:    gosettings.CopyPointer(b.MaxIdleConnsPerHost),
+		IdleConnTimeout:        gosettings.CopyPointer(b.IdleConnTimeout),
+		RequireVPN:             gosettings.CopyPointer(b.RequireVPN),
+		SourceAddress:          b.SourceAddress,
+		BlockResponse:          b.BlockResponse,
+		MaxMalformedPercent:    gosettings.CopyPointer(b.MaxMalformedPercent),
+		LogMalformed:           gosettings.CopyPointer(b.LogMalformed),
 	}
 }
 
@@ -73,6 +328,29 @@ func (b *DNSBlacklist) overrideWith(other DNSBlacklist) {
 	b.AddBlockedHosts = gosettings.OverrideWithSlice(b.AddBlockedHosts, other.AddBlockedHosts)
 	b.AddBlockedIPs = gosettings.OverrideWithSlice(b.AddBlockedIPs, other.AddBlockedIPs)
 	b.AddBlockedIPPrefixes = gosettings.OverrideWithSlice(b.AddBlockedIPPrefixes, other.AddBlockedIPPrefixes)
+	b.HostnamesURLs = gosettings.OverrideWithSlice(b.HostnamesURLs, other.HostnamesURLs)
+	b.IPsURLs = gosettings.OverrideWithSlice(b.IPsURLs, other.IPsURLs)
+	b.LocalListsPath = gosettings.OverrideWithPointer(b.LocalListsPath, other.LocalListsPath)
+	b.CachePath = gosettings.OverrideWithPointer(b.CachePath, other.CachePath)
+	b.CacheMaxAge = gosettings.OverrideWithPointer(b.CacheMaxAge, other.CacheMaxAge)
+	b.ExportPath = gosettings.OverrideWithPointer(b.ExportPath, other.ExportPath)
+	b.RPZPath = gosettings.OverrideWithPointer(b.RPZPath, other.RPZPath)
+	b.PostUpdateHook = gosettings.OverrideWithPointer(b.PostUpdateHook, other.PostUpdateHook)
+	b.PostUpdateHookTimeout = gosettings.OverrideWithPointer(b.PostUpdateHookTimeout,
+		other.PostUpdateHookTimeout)
+	b.MaxConcurrentDownloads = gosettings.OverrideWithPointer(b.MaxConcurrentDownloads,
+		other.MaxConcurrentDownloads)
+	b.MinEntries = gosettings.OverrideWithPointer(b.MinEntries, other.MinEntries)
+	b.DownloadTimeout = gosettings.OverrideWithPointer(b.DownloadTimeout, other.DownloadTimeout)
+	b.MaxIdleConnsPerHost = gosettings.OverrideWithPointer(b.MaxIdleConnsPerHost,
+		other.MaxIdleConnsPerHost)
+	b.IdleConnTimeout = gosettings.OverrideWithPointer(b.IdleConnTimeout, other.IdleConnTimeout)
+	b.RequireVPN = gosettings.OverrideWithPointer(b.RequireVPN, other.RequireVPN)
+	b.SourceAddress = gosettings.OverrideWithValidator(b.SourceAddress, other.SourceAddress)
+	b.BlockResponse = gosettings.OverrideWithComparable(b.BlockResponse, other.BlockResponse)
+	b.MaxMalformedPercent = gosettings.OverrideWithPointer(b.MaxMalformedPercent,
+		other.MaxMalformedPercent)
+	b.LogMalformed = gosettings.OverrideWithPointer(b.LogMalformed, other.LogMalformed)
 }
 
 func (b DNSBlacklist) ToBlockBuilderSettings(client *http.Client) (
@@ -99,6 +377,66 @@ func (b DNSBlacklist) toLinesNode() (node *gotree.Node) {
 	node.Appendf("Block malicious: %s", gosettings.BoolToYesNo(b.BlockMalicious))
 	node.Appendf("Block ads: %s", gosettings.BoolToYesNo(b.BlockAds))
 	node.Appendf("Block surveillance: %s", gosettings.BoolToYesNo(b.BlockSurveillance))
+	node.Appendf("Block response: %s", b.BlockResponse)
+
+	node.Appendf("Maximum concurrent downloads: %d", *b.MaxConcurrentDownloads)
+	node.Appendf("Download timeout: %s", *b.DownloadTimeout)
+	if *b.MinEntries > 0 {
+		node.Appendf("Minimum downloaded entries: %d", *b.MinEntries)
+	}
+	idleTimeout := "never"
+	if *b.IdleConnTimeout > 0 {
+		idleTimeout = "after " + b.IdleConnTimeout.String()
+	}
+	node.Appendf("Idle connections: %d per host, closed %s",
+		*b.MaxIdleConnsPerHost, idleTimeout)
+	node.Appendf("Download through VPN only: %s", gosettings.BoolToYesNo(b.RequireVPN))
+	maxMalformed := "disabled"
+	const maxPercent = 100
+	if *b.MaxMalformedPercent < maxPercent {
+		maxMalformed = fmt.Sprintf("%d%%", *b.MaxMalformedPercent)
+	}
+	malformedNode := node.Appendf("Malformed lines rejection threshold: %s", maxMalformed)
+	malformedNode.Appendf("Log malformed lines: %s", gosettings.BoolToYesNo(b.LogMalformed))
+	if b.SourceAddress.IsValid() {
+		node.Appendf("Download source address: %s", b.SourceAddress)
+	}
+
+	if *b.LocalListsPath != "" {
+		node.Appendf("Local block lists path: %s", *b.LocalListsPath)
+	}
+
+	if *b.CachePath != "" {
+		cacheNode := node.Appendf("Cache path: %s", *b.CachePath)
+		cacheNode.Appendf("Maximum age: %s", *b.CacheMaxAge)
+	}
+
+	if *b.ExportPath != "" {
+		node.Appendf("Export path: %s", *b.ExportPath)
+	}
+
+	if *b.RPZPath != "" {
+		node.Appendf("RPZ path: %s", *b.RPZPath)
+	}
+
+	if *b.PostUpdateHook != "" {
+		hookNode := node.Appendf("Post update hook: %s", *b.PostUpdateHook)
+		hookNode.Appendf("Timeout: %s", *b.PostUpdateHookTimeout)
+	}
+
+	if len(b.HostnamesURLs) > 0 {
+		hostnamesURLsNode := node.Appendf("Hostnames block lists URLs:")
+		for _, blockListURL := range b.HostnamesURLs {
+			hostnamesURLsNode.Appendf(blockListURL)
+		}
+	}
+
+	if len(b.IPsURLs) > 0 {
+		ipsURLsNode := node.Appendf("IP addresses block lists URLs:")
+		for _, blockListURL := range b.IPsURLs {
+			ipsURLsNode.Appendf(blockListURL)
+		}
+	}
 
 	if len(b.AllowedHosts) > 0 {
 		allowedHostsNode := node.Appendf("Allowed hosts:")
@@ -156,6 +494,76 @@ func (b *DNSBlacklist) read(r *reader.Reader) (err error) {
 
 	b.AllowedHosts = r.CSV("UNBLOCK") // TODO v4 change name
 
+	b.HostnamesURLs = r.CSV("DNS_BLOCKLIST_HOSTNAMES_URLS", reader.ForceLowercase(false))
+	b.IPsURLs = r.CSV("DNS_BLOCKLIST_IPS_URLS", reader.ForceLowercase(false))
+
+	b.LocalListsPath = r.Get("DNS_BLOCKLISTS_PATH", reader.AcceptEmpty(true))
+
+	b.CachePath = r.Get("DNS_BLOCKLISTS_CACHE_PATH", reader.AcceptEmpty(true))
+
+	b.CacheMaxAge, err = r.DurationPtr("DNS_BLOCKLISTS_CACHE_MAX_AGE")
+	if err != nil {
+		return err
+	}
+
+	b.ExportPath = r.Get("DNS_BLOCKLISTS_EXPORT_PATH", reader.AcceptEmpty(true))
+
+	b.RPZPath = r.Get("DNS_BLOCKLISTS_RPZ_PATH", reader.AcceptEmpty(true))
+
+	b.PostUpdateHook = r.Get("DNS_POST_UPDATE_HOOK", reader.ForceLowercase(false))
+
+	b.PostUpdateHookTimeout, err = r.DurationPtr("DNS_POST_UPDATE_HOOK_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	b.MaxConcurrentDownloads, err = r.UintPtr("DNS_BLOCKLISTS_MAX_CONCURRENT_DOWNLOADS")
+	if err != nil {
+		return err
+	}
+
+	b.MinEntries, err = r.UintPtr("DNS_BLOCKLISTS_MIN_ENTRIES")
+	if err != nil {
+		return err
+	}
+
+	b.DownloadTimeout, err = r.DurationPtr("DNS_BLOCKLISTS_DOWNLOAD_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	b.MaxIdleConnsPerHost, err = r.UintPtr("DNS_BLOCKLISTS_MAX_IDLE_CONNS_PER_HOST")
+	if err != nil {
+		return err
+	}
+
+	b.IdleConnTimeout, err = r.DurationPtr("DNS_BLOCKLISTS_IDLE_CONN_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	b.RequireVPN, err = r.BoolPtr("DNS_BLOCKLISTS_REQUIRE_VPN")
+	if err != nil {
+		return err
+	}
+
+	b.SourceAddress, err = r.NetipAddr("DNS_BLOCKLISTS_SOURCE_ADDRESS")
+	if err != nil {
+		return err
+	}
+
+	b.BlockResponse = strings.ToLower(r.String("DNS_BLOCK_RESPONSE"))
+
+	b.MaxMalformedPercent, err = r.UintPtr("DNS_BLOCKLISTS_MAX_MALFORMED_PERCENT")
+	if err != nil {
+		return err
+	}
+
+	b.LogMalformed, err = r.BoolPtr("DNS_BLOCKLISTS_LOG_MALFORMED")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
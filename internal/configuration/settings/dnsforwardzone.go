@@ -0,0 +1,80 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/dns/v2/pkg/provider"
+)
+
+// DNSForwardZone forwards queries for a domain suffix to a
+// specific upstream resolver instead of the DNS over TLS providers.
+type DNSForwardZone struct {
+	// Suffix is the domain suffix to match, for example `corp`
+	// or `in-addr.arpa` for IPv4 reverse lookups.
+	Suffix string `json:"suffix"`
+	// Upstream is either the name of a DNS over TLS provider,
+	// or the IP address of a plaintext DNS server with an
+	// optional port, for example `10.0.0.53` or `10.0.0.53:5353`.
+	Upstream string `json:"upstream"`
+}
+
+var (
+	ErrDNSForwardZoneSuffixNotValid   = errors.New("DNS forward zone suffix is not valid")
+	ErrDNSForwardZoneUpstreamNotValid = errors.New("DNS forward zone upstream is not valid")
+	ErrDNSForwardZoneMalformed        = errors.New("DNS forward zone is malformed")
+)
+
+func (z DNSForwardZone) validate() (err error) {
+	if !hostRegex.MatchString(z.Suffix) {
+		return fmt.Errorf("%w: %s", ErrDNSForwardZoneSuffixNotValid, z.Suffix)
+	}
+
+	_, _, err = z.ParseUpstream()
+	if err != nil {
+		return fmt.Errorf("for suffix %s: %w", z.Suffix, err)
+	}
+
+	return nil
+}
+
+// ParseUpstream returns the DNS over TLS provider if the upstream
+// is a provider name, or otherwise the plaintext DNS server address.
+func (z DNSForwardZone) ParseUpstream() (dotProvider *provider.Provider,
+	plaintextAddress netip.AddrPort, err error) {
+	if addrPort, err := netip.ParseAddrPort(z.Upstream); err == nil {
+		return nil, addrPort, nil
+	}
+
+	if addr, err := netip.ParseAddr(z.Upstream); err == nil {
+		const defaultDNSPort = 53
+		return nil, netip.AddrPortFrom(addr, defaultDNSPort), nil
+	}
+
+	providers := provider.NewProviders()
+	p, err := providers.Get(z.Upstream)
+	if err != nil {
+		return nil, plaintextAddress, fmt.Errorf("%w: %s is neither an IP address "+
+			"nor a DNS over TLS provider", ErrDNSForwardZoneUpstreamNotValid, z.Upstream)
+	}
+	return &p, plaintextAddress, nil
+}
+
+func (z DNSForwardZone) String() string {
+	return z.Suffix + " -> " + z.Upstream
+}
+
+// parseDNSForwardZone parses a forward zone in the format suffix=upstream.
+func parseDNSForwardZone(s string) (zone DNSForwardZone, err error) {
+	suffix, upstream, ok := strings.Cut(s, "=")
+	if !ok || upstream == "" {
+		return zone, fmt.Errorf("%w: %s: expected format suffix=upstream",
+			ErrDNSForwardZoneMalformed, s)
+	}
+
+	zone.Suffix = strings.Trim(strings.ToLower(suffix), ".")
+	zone.Upstream = upstream
+	return zone, nil
+}
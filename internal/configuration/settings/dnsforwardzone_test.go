@@ -0,0 +1,59 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDNSForwardZone(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s          string
+		zone       DNSForwardZone
+		errWrapped error
+		errMessage string
+	}{
+		"missing_separator": {
+			s:          "corp",
+			errWrapped: ErrDNSForwardZoneMalformed,
+			errMessage: "DNS forward zone is malformed: corp: expected format suffix=upstream",
+		},
+		"empty_upstream": {
+			s:          "corp=",
+			errWrapped: ErrDNSForwardZoneMalformed,
+			errMessage: "DNS forward zone is malformed: corp=: expected format suffix=upstream",
+		},
+		"plaintext": {
+			s: ".Corp.=10.0.0.53",
+			zone: DNSForwardZone{
+				Suffix:   "corp",
+				Upstream: "10.0.0.53",
+			},
+		},
+		"provider": {
+			s: "in-addr.arpa=cloudflare",
+			zone: DNSForwardZone{
+				Suffix:   "in-addr.arpa",
+				Upstream: "cloudflare",
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			zone, err := parseDNSForwardZone(testCase.s)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+				return
+			}
+			assert.Equal(t, testCase.zone, zone)
+		})
+	}
+}
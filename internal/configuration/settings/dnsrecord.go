@@ -0,0 +1,55 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// DNSRecord is a static DNS record mapping a hostname
+// to an IP address, answered by the DNS server without
+// querying the upstream resolvers.
+type DNSRecord struct {
+	Hostname string     `json:"hostname"`
+	IP       netip.Addr `json:"ip"`
+}
+
+var (
+	ErrDNSRecordHostnameNotValid = errors.New("DNS record hostname is not valid")
+	ErrDNSRecordIPNotValid       = errors.New("DNS record IP address is not valid")
+	ErrDNSRecordMalformed        = errors.New("DNS record is malformed")
+)
+
+func (r DNSRecord) validate() (err error) {
+	if !hostRegex.MatchString(r.Hostname) {
+		return fmt.Errorf("%w: %s", ErrDNSRecordHostnameNotValid, r.Hostname)
+	}
+
+	if !r.IP.IsValid() {
+		return fmt.Errorf("%w: for hostname %s", ErrDNSRecordIPNotValid, r.Hostname)
+	}
+
+	return nil
+}
+
+func (r DNSRecord) String() string {
+	return r.Hostname + " -> " + r.IP.String()
+}
+
+// parseDNSRecord parses a record in the format hostname=ip.
+func parseDNSRecord(s string) (record DNSRecord, err error) {
+	hostname, ipString, ok := strings.Cut(s, "=")
+	if !ok {
+		return record, fmt.Errorf("%w: %s: expected format hostname=ip",
+			ErrDNSRecordMalformed, s)
+	}
+
+	record.Hostname = hostname
+	record.IP, err = netip.ParseAddr(ipString)
+	if err != nil {
+		return record, fmt.Errorf("%w: %w", ErrDNSRecordIPNotValid, err)
+	}
+
+	return record, nil
+}
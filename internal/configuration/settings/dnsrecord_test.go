@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDNSRecord(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s          string
+		record     DNSRecord
+		errWrapped error
+		errMessage string
+	}{
+		"missing_separator": {
+			s:          "nas.home",
+			errWrapped: ErrDNSRecordMalformed,
+			errMessage: "DNS record is malformed: nas.home: expected format hostname=ip",
+		},
+		"invalid_ip": {
+			s:          "nas.home=x",
+			errWrapped: ErrDNSRecordIPNotValid,
+			errMessage: `DNS record IP address is not valid: ParseAddr("x"): unable to parse IP`,
+		},
+		"ipv4": {
+			s: "nas.home=192.168.1.10",
+			record: DNSRecord{
+				Hostname: "nas.home",
+				IP:       netip.AddrFrom4([4]byte{192, 168, 1, 10}),
+			},
+		},
+		"ipv6": {
+			s: "nas.home=fd00::10",
+			record: DNSRecord{
+				Hostname: "nas.home",
+				IP:       netip.MustParseAddr("fd00::10"),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			record, err := parseDNSRecord(testCase.s)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+				return
+			}
+			assert.Equal(t, testCase.record, record)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/qdm12/dns/v2/pkg/provider"
+	"github.com/qdm12/gluetun/internal/cron"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/reader"
 	"github.com/qdm12/gotree"
@@ -23,20 +24,108 @@ type DoT struct {
 	// It defaults to 24h and cannot be nil in
 	// the internal state.
 	UpdatePeriod *time.Duration
-	// Providers is a list of DNS over TLS providers
+	// UpdateSchedule is a cron expression such as "0 4 * * *"
+	// to update DNS block lists at fixed times, in the local
+	// time of the container. If set, it takes precedence over
+	// UpdatePeriod. It defaults to the empty string to use
+	// UpdatePeriod instead.
+	UpdateSchedule string
+	// UpdateRetries is the number of times to retry a failed
+	// periodic block lists update, with an exponential backoff,
+	// before keeping the previous block lists. It defaults to 3
+	// and cannot be nil in the internal state.
+	UpdateRetries *uint
+	// Providers is a list of DNS over TLS providers.
+	// The provider name `custom` selects the custom
+	// resolver defined in Custom.
 	Providers []string `json:"providers"`
+	// Custom contains settings for a custom DNS over TLS
+	// upstream resolver, used if `custom` is one of the
+	// providers.
+	Custom DoTCustom `json:"custom"`
+	// ServerNames overrides the TLS server name of providers,
+	// for providers or custom resolvers requiring a specific
+	// server name. Providers without server name set use
+	// their default server name.
+	ServerNames []DoTServerName `json:"server_names"`
 	// Caching is true if the DoT server should cache
 	// DNS responses.
 	Caching *bool `json:"caching"`
-	// IPv6 is true if the DoT server should connect over IPv6.
-	IPv6 *bool `json:"ipv6"`
+	// CacheSize is the maximum number of responses to keep
+	// in the cache. It defaults to 100000 and cannot be nil
+	// or zero in the internal state.
+	CacheSize *uint `json:"cache_size"`
+	// CachePrefetch is true if cached responses close to
+	// expiring should be refreshed in the background when
+	// requested, to keep popular records in the cache.
+	// It defaults to false and cannot be nil in the internal state.
+	CachePrefetch *bool `json:"cache_prefetch"`
+	// CacheStatsPeriod is the period to log the ratio of queries
+	// answered from the caches and to set its Prometheus gauge,
+	// to help tuning the cache sizes and TTL bounds. Set it to 0
+	// to disable it. It defaults to 0s and cannot be nil in the
+	// internal state.
+	CacheStatsPeriod *time.Duration `json:"cache_stats_period"`
+	// ServeExpired is true if expired responses should be answered
+	// when the upstream resolvers fail to answer, or take longer
+	// than `ServeExpiredClientTimeout` to answer, as described in
+	// RFC 8767, so hostnames keep resolving during short upstream
+	// outages. It defaults to false and cannot be nil in the
+	// internal state.
+	ServeExpired *bool `json:"serve_expired"`
+	// ServeExpiredTTL is the maximum duration after their expiry
+	// responses can be answered expired. It defaults to 24h and
+	// cannot be nil or zero in the internal state.
+	ServeExpiredTTL *time.Duration `json:"serve_expired_ttl"`
+	// ServeExpiredClientTimeout is the duration to wait for the
+	// upstream resolvers to answer before answering an expired
+	// response, if any. The upstream answer still refreshes the
+	// expired response once received. It defaults to 1.8s and
+	// cannot be nil or zero in the internal state.
+	ServeExpiredClientTimeout *time.Duration `json:"serve_expired_client_timeout"`
+	// NegativeCacheSize is the maximum number of NXDOMAIN and
+	// empty responses to cache, to reduce repeated upstream
+	// lookups of non-existent hostnames. Set it to 0 to disable
+	// negative caching. It defaults to 10000 and cannot be nil
+	// in the internal state.
+	NegativeCacheSize *uint `json:"negative_cache_size"`
+	// NegativeTTLMin is the minimum duration to cache negative
+	// responses for, overriding a lower TTL set by the upstream
+	// server. It defaults to 0s and cannot be nil in the internal state.
+	NegativeTTLMin *time.Duration `json:"negative_ttl_min"`
+	// NegativeTTLMax is the maximum duration to cache negative
+	// responses for, overriding a higher TTL set by the upstream
+	// server. It defaults to 1h and cannot be nil in the internal state.
+	NegativeTTLMax *time.Duration `json:"negative_ttl_max"`
+	// TTLMin is the minimum TTL of the records answered, raising
+	// lower TTLs to reduce upstream lookups of short lived records.
+	// Set it to 0 to disable it. It defaults to 0s and cannot be nil
+	// in the internal state.
+	TTLMin *time.Duration `json:"ttl_min"`
+	// TTLMax is the maximum TTL of the records answered, lowering
+	// higher TTLs so records do not outlive block lists updates.
+	// Set it to 0 to disable it. It defaults to 0s and cannot be nil
+	// in the internal state.
+	TTLMax *time.Duration `json:"ttl_max"`
+	// QueryLog is true if the DoT server should log each DNS
+	// query and its response. It can be changed at runtime
+	// without restarting the DoT server. It defaults to false
+	// and cannot be nil in the internal state.
+	QueryLog *bool `json:"query_log"`
 	// Blacklist contains settings to configure the filter
 	// block lists.
 	Blacklist DNSBlacklist
 }
 
 var (
-	ErrDoTUpdatePeriodTooShort = errors.New("update period is too short")
+	ErrDoTUpdatePeriodTooShort   = errors.New("update period is too short")
+	ErrDoTCacheSizeNotValid      = errors.New("cache size is not valid")
+	ErrDoTUpdateScheduleNotValid = errors.New("update schedule is not valid")
+	ErrDoTNegativeTTLNotValid    = errors.New("negative cache TTL bounds are not valid")
+	ErrDoTTTLNotValid            = errors.New("TTL bounds are not valid")
+	ErrDoTStatsPeriodTooShort    = errors.New("cache statistics period is too short")
+	ErrDoTServeExpiredNotValid   = errors.New("serve expired settings are not valid")
+	ErrDoTProvidersEmpty         = errors.New("no DNS over TLS provider is set")
 )
 
 func (d DoT) validate() (err error) {
@@ -46,14 +135,70 @@ func (d DoT) validate() (err error) {
 			ErrDoTUpdatePeriodTooShort, *d.UpdatePeriod, minUpdatePeriod)
 	}
 
+	if d.UpdateSchedule != "" {
+		_, err = cron.Parse(d.UpdateSchedule)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDoTUpdateScheduleNotValid, err)
+		}
+	}
+
+	const minCacheStatsPeriod = time.Minute
+	if *d.CacheStatsPeriod != 0 && *d.CacheStatsPeriod < minCacheStatsPeriod {
+		return fmt.Errorf("%w: %s must be bigger than %s",
+			ErrDoTStatsPeriodTooShort, *d.CacheStatsPeriod, minCacheStatsPeriod)
+	}
+
+	const maxCacheSize = 10000000
+	if *d.CacheSize == 0 || *d.CacheSize > maxCacheSize {
+		return fmt.Errorf("%w: %d must be between 1 and %d",
+			ErrDoTCacheSizeNotValid, *d.CacheSize, maxCacheSize)
+	}
+
+	if *d.NegativeTTLMin < 0 || *d.NegativeTTLMax < *d.NegativeTTLMin {
+		return fmt.Errorf("%w: minimum %s must be positive and not exceed maximum %s",
+			ErrDoTNegativeTTLNotValid, *d.NegativeTTLMin, *d.NegativeTTLMax)
+	}
+
+	if *d.ServeExpiredTTL <= 0 || *d.ServeExpiredClientTimeout <= 0 {
+		return fmt.Errorf("%w: TTL %s and client timeout %s must be strictly positive",
+			ErrDoTServeExpiredNotValid, *d.ServeExpiredTTL, *d.ServeExpiredClientTimeout)
+	}
+
+	switch {
+	case *d.TTLMin < 0 || *d.TTLMax < 0:
+		return fmt.Errorf("%w: minimum %s and maximum %s must be positive",
+			ErrDoTTTLNotValid, *d.TTLMin, *d.TTLMax)
+	case *d.TTLMax > 0 && *d.TTLMax < *d.TTLMin:
+		return fmt.Errorf("%w: minimum %s must not exceed maximum %s",
+			ErrDoTTTLNotValid, *d.TTLMin, *d.TTLMax)
+	}
+
+	if len(d.Providers) == 0 {
+		return fmt.Errorf("%w", ErrDoTProvidersEmpty)
+	}
+
 	providers := provider.NewProviders()
 	for _, providerName := range d.Providers {
+		if providerName == customProviderName {
+			err = d.Custom.validate()
+			if err != nil {
+				return fmt.Errorf("custom provider: %w", err)
+			}
+			continue
+		}
 		_, err := providers.Get(providerName)
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, serverName := range d.ServerNames {
+		err = serverName.validate(d.Providers)
+		if err != nil {
+			return fmt.Errorf("validating server name: %w", err)
+		}
+	}
+
 	err = d.Blacklist.validate()
 	if err != nil {
 		return err
@@ -64,12 +209,27 @@ func (d DoT) validate() (err error) {
 
 func (d *DoT) copy() (copied DoT) {
 	return DoT{
-		Enabled:      gosettings.CopyPointer(d.Enabled),
-		UpdatePeriod: gosettings.CopyPointer(d.UpdatePeriod),
-		Providers:    gosettings.CopySlice(d.Providers),
-		Caching:      gosettings.CopyPointer(d.Caching),
-		IPv6:         gosettings.CopyPointer(d.IPv6),
-		Blacklist:    d.Blacklist.copy(),
+		Enabled:                   gosettings.CopyPointer(d.Enabled),
+		UpdatePeriod:              gosettings.CopyPointer(d.UpdatePeriod),
+		UpdateSchedule:            d.UpdateSchedule,
+		UpdateRetries:             gosettings.CopyPointer(d.UpdateRetries),
+		Providers:                 gosettings.CopySlice(d.Providers),
+		Custom:                    d.Custom.copy(),
+		ServerNames:               gosettings.CopySlice(d.ServerNames),
+		Caching:                   gosettings.CopyPointer(d.Caching),
+		CacheSize:                 gosettings.CopyPointer(d.CacheSize),
+		CachePrefetch:             gosettings.CopyPointer(d.CachePrefetch),
+		CacheStatsPeriod:          gosettings.CopyPointer(d.CacheStatsPeriod),
+		ServeExpired:              gosettings.CopyPointer(d.ServeExpired),
+		ServeExpiredTTL:           gosettings.CopyPointer(d.ServeExpiredTTL),
+		ServeExpiredClientTimeout: gosettings.CopyPointer(d.ServeExpiredClientTimeout),
+		NegativeCacheSize:         gosettings.CopyPointer(d.NegativeCacheSize),
+		NegativeTTLMin:            gosettings.CopyPointer(d.NegativeTTLMin),
+		NegativeTTLMax:            gosettings.CopyPointer(d.NegativeTTLMax),
+		TTLMin:                    gosettings.CopyPointer(d.TTLMin),
+		TTLMax:                    gosettings.CopyPointer(d.TTLMax),
+		QueryLog:                  gosettings.CopyPointer(d.QueryLog),
+		Blacklist:                 d.Blacklist.copy(),
 	}
 }
 
@@ -79,9 +239,25 @@ func (d *DoT) copy() (copied DoT) {
 func (d *DoT) overrideWith(other DoT) {
 	d.Enabled = gosettings.OverrideWithPointer(d.Enabled, other.Enabled)
 	d.UpdatePeriod = gosettings.OverrideWithPointer(d.UpdatePeriod, other.UpdatePeriod)
+	d.UpdateSchedule = gosettings.OverrideWithComparable(d.UpdateSchedule, other.UpdateSchedule)
+	d.UpdateRetries = gosettings.OverrideWithPointer(d.UpdateRetries, other.UpdateRetries)
 	d.Providers = gosettings.OverrideWithSlice(d.Providers, other.Providers)
+	d.Custom.overrideWith(other.Custom)
+	d.ServerNames = gosettings.OverrideWithSlice(d.ServerNames, other.ServerNames)
 	d.Caching = gosettings.OverrideWithPointer(d.Caching, other.Caching)
-	d.IPv6 = gosettings.OverrideWithPointer(d.IPv6, other.IPv6)
+	d.CacheSize = gosettings.OverrideWithPointer(d.CacheSize, other.CacheSize)
+	d.CachePrefetch = gosettings.OverrideWithPointer(d.CachePrefetch, other.CachePrefetch)
+	d.CacheStatsPeriod = gosettings.OverrideWithPointer(d.CacheStatsPeriod, other.CacheStatsPeriod)
+	d.ServeExpired = gosettings.OverrideWithPointer(d.ServeExpired, other.ServeExpired)
+	d.ServeExpiredTTL = gosettings.OverrideWithPointer(d.ServeExpiredTTL, other.ServeExpiredTTL)
+	d.ServeExpiredClientTimeout = gosettings.OverrideWithPointer(d.ServeExpiredClientTimeout,
+		other.ServeExpiredClientTimeout)
+	d.NegativeCacheSize = gosettings.OverrideWithPointer(d.NegativeCacheSize, other.NegativeCacheSize)
+	d.NegativeTTLMin = gosettings.OverrideWithPointer(d.NegativeTTLMin, other.NegativeTTLMin)
+	d.NegativeTTLMax = gosettings.OverrideWithPointer(d.NegativeTTLMax, other.NegativeTTLMax)
+	d.TTLMin = gosettings.OverrideWithPointer(d.TTLMin, other.TTLMin)
+	d.TTLMax = gosettings.OverrideWithPointer(d.TTLMax, other.TTLMax)
+	d.QueryLog = gosettings.OverrideWithPointer(d.QueryLog, other.QueryLog)
 	d.Blacklist.overrideWith(other.Blacklist)
 }
 
@@ -89,24 +265,53 @@ func (d *DoT) setDefaults() {
 	d.Enabled = gosettings.DefaultPointer(d.Enabled, true)
 	const defaultUpdatePeriod = 24 * time.Hour
 	d.UpdatePeriod = gosettings.DefaultPointer(d.UpdatePeriod, defaultUpdatePeriod)
+	const defaultUpdateRetries = 3
+	d.UpdateRetries = gosettings.DefaultPointer(d.UpdateRetries, defaultUpdateRetries)
 	d.Providers = gosettings.DefaultSlice(d.Providers, []string{
 		provider.Cloudflare().Name,
 	})
+	d.Custom.setDefaults()
 	d.Caching = gosettings.DefaultPointer(d.Caching, true)
-	d.IPv6 = gosettings.DefaultPointer(d.IPv6, false)
+	const defaultCacheSize = 100000
+	d.CacheSize = gosettings.DefaultPointer(d.CacheSize, defaultCacheSize)
+	d.CachePrefetch = gosettings.DefaultPointer(d.CachePrefetch, false)
+	d.CacheStatsPeriod = gosettings.DefaultPointer(d.CacheStatsPeriod, 0)
+	d.ServeExpired = gosettings.DefaultPointer(d.ServeExpired, false)
+	const defaultServeExpiredTTL = 24 * time.Hour
+	d.ServeExpiredTTL = gosettings.DefaultPointer(d.ServeExpiredTTL, defaultServeExpiredTTL)
+	const defaultServeExpiredClientTimeout = 1800 * time.Millisecond
+	d.ServeExpiredClientTimeout = gosettings.DefaultPointer(d.ServeExpiredClientTimeout,
+		defaultServeExpiredClientTimeout)
+	const defaultNegativeCacheSize = 10000
+	d.NegativeCacheSize = gosettings.DefaultPointer(d.NegativeCacheSize, defaultNegativeCacheSize)
+	d.NegativeTTLMin = gosettings.DefaultPointer(d.NegativeTTLMin, 0)
+	d.NegativeTTLMax = gosettings.DefaultPointer(d.NegativeTTLMax, time.Hour)
+	d.TTLMin = gosettings.DefaultPointer(d.TTLMin, 0)
+	d.TTLMax = gosettings.DefaultPointer(d.TTLMax, 0)
+	d.QueryLog = gosettings.DefaultPointer(d.QueryLog, false)
 	d.Blacklist.setDefaults()
 }
 
-func (d DoT) GetFirstPlaintextIPv4() (ipv4 netip.Addr) {
-	providers := provider.NewProviders()
-	provider, err := providers.Get(d.Providers[0])
-	if err != nil {
-		// Settings should be validated before calling this function,
-		// so an error happening here is a programming error.
-		panic(err)
+// GetPlaintextIPs returns the first plaintext IP address
+// of each provider, in the order the providers are configured.
+// If ipv6 is true, the first IPv6 address is used for providers
+// having IPv6 addresses, otherwise the first IPv4 address is used.
+// The custom provider uses the first custom address if it has no
+// address matching the IP version.
+func (d DoT) GetPlaintextIPs(ipv6 bool) (ips []netip.Addr) {
+	providers := d.GetProviders()
+	ips = make([]netip.Addr, len(providers))
+	for i, provider := range providers {
+		switch {
+		case ipv6 && len(provider.DoT.IPv6) > 0:
+			ips[i] = provider.DoT.IPv6[0].Addr()
+		case len(provider.DoT.IPv4) > 0:
+			ips[i] = provider.DoT.IPv4[0].Addr()
+		default:
+			ips[i] = provider.DoT.IPv6[0].Addr()
+		}
 	}
-
-	return provider.DoT.IPv4[0].Addr()
+	return ips
 }
 
 func (d DoT) String() string {
@@ -122,18 +327,65 @@ func (d DoT) toLinesNode() (node *gotree.Node) {
 	}
 
 	update := "disabled" //nolint:goconst
-	if *d.UpdatePeriod > 0 {
+	switch {
+	case d.UpdateSchedule != "":
+		update = "cron schedule " + d.UpdateSchedule
+	case *d.UpdatePeriod > 0:
 		update = "every " + d.UpdatePeriod.String()
 	}
 	node.Appendf("Update period: %s", update)
+	if update != "disabled" {
+		node.Appendf("Update retries: %d", *d.UpdateRetries)
+	}
 
 	upstreamResolvers := node.Appendf("Upstream resolvers:")
 	for _, provider := range d.Providers {
+		if provider == customProviderName {
+			upstreamResolvers.AppendNode(d.Custom.toLinesNode())
+			continue
+		}
 		upstreamResolvers.Appendf(provider)
 	}
 
-	node.Appendf("Caching: %s", gosettings.BoolToYesNo(d.Caching))
-	node.Appendf("IPv6: %s", gosettings.BoolToYesNo(d.IPv6))
+	if len(d.ServerNames) > 0 {
+		serverNamesNode := node.Appendf("TLS server names:")
+		for _, serverName := range d.ServerNames {
+			serverNamesNode.Appendf(serverName.String())
+		}
+	}
+
+	cachingNode := node.Appendf("Caching: %s", gosettings.BoolToYesNo(d.Caching))
+	if *d.Caching {
+		cachingNode.Appendf("Size: %d responses", *d.CacheSize)
+		cachingNode.Appendf("Prefetch: %s", gosettings.BoolToYesNo(d.CachePrefetch))
+		if *d.CacheStatsPeriod > 0 {
+			cachingNode.Appendf("Hit ratio report: every %s", *d.CacheStatsPeriod)
+		}
+		serveExpired := "disabled"
+		if *d.ServeExpired {
+			serveExpired = fmt.Sprintf("up to %s after expiry, client timeout %s",
+				*d.ServeExpiredTTL, *d.ServeExpiredClientTimeout)
+		}
+		cachingNode.Appendf("Serve expired: %s", serveExpired)
+		negativeCache := "disabled"
+		if *d.NegativeCacheSize > 0 {
+			negativeCache = fmt.Sprintf("%d responses, TTL between %s and %s",
+				*d.NegativeCacheSize, *d.NegativeTTLMin, *d.NegativeTTLMax)
+		}
+		cachingNode.Appendf("Negative cache: %s", negativeCache)
+	}
+	ttlClamping := "disabled"
+	switch {
+	case *d.TTLMin > 0 && *d.TTLMax > 0:
+		ttlClamping = fmt.Sprintf("between %s and %s", *d.TTLMin, *d.TTLMax)
+	case *d.TTLMin > 0:
+		ttlClamping = "at least " + d.TTLMin.String()
+	case *d.TTLMax > 0:
+		ttlClamping = "at most " + d.TTLMax.String()
+	}
+	node.Appendf("Records TTL: %s", ttlClamping)
+
+	node.Appendf("Query log: %s", gosettings.BoolToYesNo(d.QueryLog))
 
 	node.AppendNode(d.Blacklist.toLinesNode())
 
@@ -146,19 +398,95 @@ func (d *DoT) read(reader *reader.Reader) (err error) {
 		return err
 	}
 
-	d.UpdatePeriod, err = reader.DurationPtr("DNS_UPDATE_PERIOD")
+	err = d.readUpdatePeriod(reader)
+	if err != nil {
+		return err
+	}
+
+	d.UpdateRetries, err = reader.UintPtr("DNS_UPDATE_RETRIES")
 	if err != nil {
 		return err
 	}
 
 	d.Providers = reader.CSV("DOT_PROVIDERS")
 
+	err = d.Custom.read(reader)
+	if err != nil {
+		return err
+	}
+
+	serverNameStrings := reader.CSV("DOT_SERVER_NAMES")
+	if len(serverNameStrings) > 0 {
+		d.ServerNames = make([]DoTServerName, len(serverNameStrings))
+		for i, serverNameString := range serverNameStrings {
+			d.ServerNames[i], err = parseDoTServerName(serverNameString)
+			if err != nil {
+				return fmt.Errorf("environment variable DOT_SERVER_NAMES: %w", err)
+			}
+		}
+	}
+
 	d.Caching, err = reader.BoolPtr("DOT_CACHING")
 	if err != nil {
 		return err
 	}
 
-	d.IPv6, err = reader.BoolPtr("DOT_IPV6")
+	d.CacheSize, err = reader.UintPtr("DOT_CACHE_SIZE")
+	if err != nil {
+		return err
+	}
+
+	d.CachePrefetch, err = reader.BoolPtr("DOT_CACHE_PREFETCH")
+	if err != nil {
+		return err
+	}
+
+	d.CacheStatsPeriod, err = reader.DurationPtr("DOT_CACHE_STATS_PERIOD")
+	if err != nil {
+		return err
+	}
+
+	d.ServeExpired, err = reader.BoolPtr("DOT_SERVE_EXPIRED")
+	if err != nil {
+		return err
+	}
+
+	d.ServeExpiredTTL, err = reader.DurationPtr("DOT_SERVE_EXPIRED_TTL")
+	if err != nil {
+		return err
+	}
+
+	d.ServeExpiredClientTimeout, err = reader.DurationPtr("DOT_SERVE_EXPIRED_CLIENT_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	d.NegativeCacheSize, err = reader.UintPtr("DOT_NEGATIVE_CACHE_SIZE")
+	if err != nil {
+		return err
+	}
+
+	d.NegativeTTLMin, err = reader.DurationPtr("DOT_NEGATIVE_TTL_MIN")
+	if err != nil {
+		return err
+	}
+
+	d.NegativeTTLMax, err = reader.DurationPtr("DOT_NEGATIVE_TTL_MAX")
+	if err != nil {
+		return err
+	}
+
+	d.TTLMin, err = reader.DurationPtr("DNS_CACHE_MIN_TTL")
+	if err != nil {
+		return err
+	}
+
+	d.TTLMax, err = reader.DurationPtr("DNS_CACHE_MAX_TTL")
+	if err != nil {
+		return err
+	}
+
+	d.QueryLog, err = reader.BoolPtr("DOT_QUERY_LOG")
 	if err != nil {
 		return err
 	}
@@ -170,3 +498,26 @@ func (d *DoT) read(reader *reader.Reader) (err error) {
 
 	return nil
 }
+
+// readUpdatePeriod reads DNS_UPDATE_PERIOD as a duration,
+// or as a cron expression if it is not a duration.
+func (d *DoT) readUpdatePeriod(reader *reader.Reader) (err error) {
+	value := reader.Get("DNS_UPDATE_PERIOD")
+	if value == nil {
+		return nil
+	}
+
+	updatePeriod, err := time.ParseDuration(*value)
+	if err == nil {
+		d.UpdatePeriod = &updatePeriod
+		return nil
+	}
+
+	_, cronErr := cron.Parse(*value)
+	if cronErr != nil {
+		return fmt.Errorf("environment variable DNS_UPDATE_PERIOD: "+
+			"%s is neither a duration: %w, nor a cron expression: %w", *value, err, cronErr)
+	}
+	d.UpdateSchedule = *value
+	return nil
+}
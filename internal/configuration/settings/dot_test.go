@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DoT_validate_serveExpired(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		ttl           time.Duration
+		clientTimeout time.Duration
+		errWrapped    error
+		errMessage    string
+	}{
+		"valid": {
+			ttl:           time.Hour,
+			clientTimeout: time.Second,
+		},
+		"zero_ttl": {
+			clientTimeout: time.Second,
+			errWrapped:    ErrDoTServeExpiredNotValid,
+			errMessage: "serve expired settings are not valid: " +
+				"TTL 0s and client timeout 1s must be strictly positive",
+		},
+		"zero_client_timeout": {
+			ttl:        time.Hour,
+			errWrapped: ErrDoTServeExpiredNotValid,
+			errMessage: "serve expired settings are not valid: " +
+				"TTL 1h0m0s and client timeout 0s must be strictly positive",
+		},
+		"negative_client_timeout": {
+			ttl:           time.Hour,
+			clientTimeout: -time.Second,
+			errWrapped:    ErrDoTServeExpiredNotValid,
+			errMessage: "serve expired settings are not valid: " +
+				"TTL 1h0m0s and client timeout -1s must be strictly positive",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var settings DoT
+			settings.setDefaults()
+			settings.ServeExpiredTTL = ptrTo(testCase.ttl)
+			settings.ServeExpiredClientTimeout = ptrTo(testCase.clientTimeout)
+
+			err := settings.validate()
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
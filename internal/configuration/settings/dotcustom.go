@@ -0,0 +1,139 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/qdm12/dns/v2/pkg/provider"
+	"github.com/qdm12/gosettings"
+	"github.com/qdm12/gosettings/reader"
+	"github.com/qdm12/gotree"
+)
+
+// customProviderName is the reserved DNS over TLS provider name
+// selecting the custom upstream resolver.
+const customProviderName = "custom"
+
+// DoTCustom contains settings to configure a custom DNS over TLS
+// upstream resolver, bypassing the built-in providers. It is used
+// if `custom` is one of the DNS over TLS providers.
+type DoTCustom struct {
+	// Addresses are the IP addresses and ports of the custom
+	// DNS over TLS resolver. The first IP address is also used
+	// for the plaintext DNS fallback. It defaults to the empty
+	// slice and must be set if the custom provider is used.
+	Addresses []netip.AddrPort `json:"addresses"`
+	// ServerName is the TLS server name of the custom resolver,
+	// used to verify its certificate. It defaults to the empty
+	// string and must be set if the custom provider is used.
+	ServerName *string `json:"server_name"`
+}
+
+var (
+	ErrDoTCustomAddressesNotSet  = errors.New("custom DNS over TLS addresses are not set")
+	ErrDoTCustomAddressNotValid  = errors.New("custom DNS over TLS address is not valid")
+	ErrDoTCustomServerNameNotSet = errors.New("custom DNS over TLS server name is not set")
+)
+
+func (d DoTCustom) validate() (err error) {
+	if len(d.Addresses) == 0 {
+		return fmt.Errorf("%w", ErrDoTCustomAddressesNotSet)
+	}
+
+	for _, address := range d.Addresses {
+		if !address.IsValid() || address.Port() == 0 {
+			return fmt.Errorf("%w: %s", ErrDoTCustomAddressNotValid, address)
+		}
+	}
+
+	switch {
+	case *d.ServerName == "":
+		return fmt.Errorf("%w", ErrDoTCustomServerNameNotSet)
+	case !hostRegex.MatchString(*d.ServerName):
+		return fmt.Errorf("%w: %s", ErrDoTServerNameNotValid, *d.ServerName)
+	}
+
+	return nil
+}
+
+func (d *DoTCustom) copy() (copied DoTCustom) {
+	return DoTCustom{
+		Addresses:  gosettings.CopySlice(d.Addresses),
+		ServerName: gosettings.CopyPointer(d.ServerName),
+	}
+}
+
+func (d *DoTCustom) overrideWith(other DoTCustom) {
+	d.Addresses = gosettings.OverrideWithSlice(d.Addresses, other.Addresses)
+	d.ServerName = gosettings.OverrideWithPointer(d.ServerName, other.ServerName)
+}
+
+func (d *DoTCustom) setDefaults() {
+	d.ServerName = gosettings.DefaultPointer(d.ServerName, "")
+}
+
+// toProvider returns the custom resolver as a DNS over TLS provider,
+// with its addresses split by IP version.
+func (d DoTCustom) toProvider() (customProvider provider.Provider) {
+	customProvider.Name = customProviderName
+	customProvider.DoT.Name = *d.ServerName
+	for _, address := range d.Addresses {
+		if address.Addr().Is4() || address.Addr().Is4In6() {
+			customProvider.DoT.IPv4 = append(customProvider.DoT.IPv4, address)
+			continue
+		}
+		customProvider.DoT.IPv6 = append(customProvider.DoT.IPv6, address)
+	}
+	return customProvider
+}
+
+func (d DoTCustom) String() string {
+	return d.toLinesNode().String()
+}
+
+func (d DoTCustom) toLinesNode() (node *gotree.Node) {
+	node = gotree.New("Custom resolver:")
+	addresses := make([]string, len(d.Addresses))
+	for i, address := range d.Addresses {
+		addresses[i] = address.String()
+	}
+	node.Appendf("Addresses: %s", strings.Join(addresses, ", "))
+	node.Appendf("TLS server name: %s", *d.ServerName)
+	return node
+}
+
+func (d *DoTCustom) read(reader *reader.Reader) (err error) {
+	addressStrings := reader.CSV("DOT_CUSTOM_ADDRESSES")
+	if len(addressStrings) > 0 {
+		d.Addresses = make([]netip.AddrPort, len(addressStrings))
+		for i, addressString := range addressStrings {
+			d.Addresses[i], err = parseDoTCustomAddress(addressString)
+			if err != nil {
+				return fmt.Errorf("environment variable DOT_CUSTOM_ADDRESSES: %w", err)
+			}
+		}
+	}
+
+	d.ServerName = reader.Get("DOT_CUSTOM_SERVER_NAME")
+
+	return nil
+}
+
+// parseDoTCustomAddress parses an address in the format ip:port,
+// or ip in which case the DNS over TLS port 853 is used.
+func parseDoTCustomAddress(s string) (address netip.AddrPort, err error) {
+	address, err = netip.ParseAddrPort(s)
+	if err == nil {
+		return address, nil
+	}
+
+	ip, ipErr := netip.ParseAddr(s)
+	if ipErr != nil {
+		return address, fmt.Errorf("%w: %s: expected format ip or ip:port",
+			ErrDoTCustomAddressNotValid, s)
+	}
+	const defaultDoTPort = 853
+	return netip.AddrPortFrom(ip, defaultDoTPort), nil
+}
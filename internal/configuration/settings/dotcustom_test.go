@@ -0,0 +1,54 @@
+package settings
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDoTCustomAddress(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s          string
+		address    netip.AddrPort
+		errWrapped error
+		errMessage string
+	}{
+		"malformed": {
+			s:          "dns.example.com",
+			errWrapped: ErrDoTCustomAddressNotValid,
+			errMessage: "custom DNS over TLS address is not valid: " +
+				"dns.example.com: expected format ip or ip:port",
+		},
+		"ipv4_without_port": {
+			s:       "1.2.3.4",
+			address: netip.MustParseAddrPort("1.2.3.4:853"),
+		},
+		"ipv4_with_port": {
+			s:       "1.2.3.4:8853",
+			address: netip.MustParseAddrPort("1.2.3.4:8853"),
+		},
+		"ipv6_with_port": {
+			s:       "[::1]:853",
+			address: netip.MustParseAddrPort("[::1]:853"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			address, err := parseDoTCustomAddress(testCase.s)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+				return
+			}
+			assert.Equal(t, testCase.address, address)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qdm12/dns/v2/pkg/provider"
+)
+
+// DoTServerName overrides the TLS server name used to
+// verify the certificate of a DNS over TLS provider.
+type DoTServerName struct {
+	// Provider is the name of the DNS over TLS provider,
+	// for example `cloudflare`.
+	Provider string `json:"provider"`
+	// Name is the TLS server name to use for the provider,
+	// for example `one.one.one.one`.
+	Name string `json:"name"`
+}
+
+var (
+	ErrDoTServerNameProviderNotSet = errors.New("DNS over TLS server name provider is not set")
+	ErrDoTServerNameNotValid       = errors.New("DNS over TLS server name is not valid")
+	ErrDoTServerNameMalformed      = errors.New("DNS over TLS server name is malformed")
+)
+
+func (s DoTServerName) validate(providerNames []string) (err error) {
+	providerSet := false
+	for _, providerName := range providerNames {
+		if providerName == s.Provider {
+			providerSet = true
+			break
+		}
+	}
+	if !providerSet {
+		return fmt.Errorf("%w: %s is not one of the providers %s",
+			ErrDoTServerNameProviderNotSet, s.Provider, strings.Join(providerNames, ", "))
+	}
+
+	if !hostRegex.MatchString(s.Name) {
+		return fmt.Errorf("%w: %s", ErrDoTServerNameNotValid, s.Name)
+	}
+
+	return nil
+}
+
+func (s DoTServerName) String() string {
+	return s.Provider + " -> " + s.Name
+}
+
+// parseDoTServerName parses a server name in the format provider=name.
+func parseDoTServerName(s string) (serverName DoTServerName, err error) {
+	providerName, name, ok := strings.Cut(s, "=")
+	if !ok || providerName == "" || name == "" {
+		return serverName, fmt.Errorf("%w: %s: expected format provider=name",
+			ErrDoTServerNameMalformed, s)
+	}
+
+	serverName.Provider = strings.ToLower(providerName)
+	serverName.Name = strings.ToLower(name)
+	return serverName, nil
+}
+
+// GetProviders returns the DNS over TLS providers, in the order
+// they are configured, with their TLS server name overridden by
+// the server names set.
+func (d DoT) GetProviders() (providers []provider.Provider) {
+	providersData := provider.NewProviders()
+	providers = make([]provider.Provider, len(d.Providers))
+	for i, providerName := range d.Providers {
+		if providerName == customProviderName {
+			providers[i] = d.Custom.toProvider()
+		} else {
+			var err error
+			providers[i], err = providersData.Get(providerName)
+			if err != nil {
+				// Settings should be validated before calling this function,
+				// so an error happening here is a programming error.
+				panic(err)
+			}
+		}
+		for _, serverName := range d.ServerNames {
+			if serverName.Provider == providerName {
+				providers[i].DoT.Name = serverName.Name
+			}
+		}
+	}
+	return providers
+}
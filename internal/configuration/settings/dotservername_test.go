@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseDoTServerName(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s          string
+		serverName DoTServerName
+		errWrapped error
+		errMessage string
+	}{
+		"missing_separator": {
+			s:          "cloudflare",
+			errWrapped: ErrDoTServerNameMalformed,
+			errMessage: "DNS over TLS server name is malformed: cloudflare: expected format provider=name",
+		},
+		"empty_name": {
+			s:          "cloudflare=",
+			errWrapped: ErrDoTServerNameMalformed,
+			errMessage: "DNS over TLS server name is malformed: cloudflare=: expected format provider=name",
+		},
+		"valid": {
+			s: "Cloudflare=One.One.One.One",
+			serverName: DoTServerName{
+				Provider: "cloudflare",
+				Name:     "one.one.one.one",
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			serverName, err := parseDoTServerName(testCase.s)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+				return
+			}
+			assert.Equal(t, testCase.serverName, serverName)
+		})
+	}
+}
@@ -32,6 +32,12 @@ type Health struct {
 	// It defaults to 5 seconds and cannot be zero in
 	// the internal state.
 	SuccessWait time.Duration
+	// DNSOverTLS is true if the healthcheck should also fail
+	// when the DNS over TLS server is not running or when DNS
+	// queries fall back on plaintext DNS. Failures of this
+	// check do not restart the VPN.
+	// It defaults to false and cannot be nil in the internal state.
+	DNSOverTLS *bool
 	// VPN has health settings specific to the VPN loop.
 	VPN HealthyWait
 }
@@ -57,6 +63,7 @@ func (h *Health) copy() (copied Health) {
 		ReadTimeout:       h.ReadTimeout,
 		TargetAddress:     h.TargetAddress,
 		SuccessWait:       h.SuccessWait,
+		DNSOverTLS:        gosettings.CopyPointer(h.DNSOverTLS),
 		VPN:               h.VPN.copy(),
 	}
 }
@@ -70,6 +77,7 @@ func (h *Health) OverrideWith(other Health) {
 	h.ReadTimeout = gosettings.OverrideWithComparable(h.ReadTimeout, other.ReadTimeout)
 	h.TargetAddress = gosettings.OverrideWithComparable(h.TargetAddress, other.TargetAddress)
 	h.SuccessWait = gosettings.OverrideWithComparable(h.SuccessWait, other.SuccessWait)
+	h.DNSOverTLS = gosettings.OverrideWithPointer(h.DNSOverTLS, other.DNSOverTLS)
 	h.VPN.overrideWith(other.VPN)
 }
 
@@ -82,6 +90,7 @@ func (h *Health) SetDefaults() {
 	h.TargetAddress = gosettings.DefaultComparable(h.TargetAddress, "cloudflare.com:443")
 	const defaultSuccessWait = 5 * time.Second
 	h.SuccessWait = gosettings.DefaultComparable(h.SuccessWait, defaultSuccessWait)
+	h.DNSOverTLS = gosettings.DefaultPointer(h.DNSOverTLS, false)
 	h.VPN.setDefaults()
 }
 
@@ -96,6 +105,7 @@ func (h Health) toLinesNode() (node *gotree.Node) {
 	node.Appendf("Duration to wait after success: %s", h.SuccessWait)
 	node.Appendf("Read header timeout: %s", h.ReadHeaderTimeout)
 	node.Appendf("Read timeout: %s", h.ReadTimeout)
+	node.Appendf("Check DNS over TLS: %s", gosettings.BoolToYesNo(h.DNSOverTLS))
 	node.AppendNode(h.VPN.toLinesNode("VPN"))
 	return node
 }
@@ -110,6 +120,11 @@ func (h *Health) Read(r *reader.Reader) (err error) {
 		return err
 	}
 
+	h.DNSOverTLS, err = r.BoolPtr("HEALTH_DNS_OVER_TLS")
+	if err != nil {
+		return err
+	}
+
 	err = h.VPN.read(r)
 	if err != nil {
 		return fmt.Errorf("VPN health settings: %w", err)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/reader"
+	"github.com/qdm12/gosettings/validate"
 	"github.com/qdm12/gotree"
 	"github.com/qdm12/log"
 )
@@ -14,6 +15,12 @@ type Log struct {
 	// Level is the log level of the logger.
 	// It cannot be empty in the internal state.
 	Level string
+	// Format is the format of log records emitted for events,
+	// which can be `text` or `json`. Records of the `json` format
+	// are a JSON object, with fields such as the event type, to
+	// be parsed by log processing tools.
+	// It cannot be empty in the internal state.
+	Format string
 }
 
 func (l Log) validate() (err error) {
@@ -21,12 +28,18 @@ func (l Log) validate() (err error) {
 	if err != nil {
 		return fmt.Errorf("level: %w", err)
 	}
+
+	err = validate.IsOneOf(l.Format, "text", "json")
+	if err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
 	return nil
 }
 
 func (l *Log) copy() (copied Log) {
 	return Log{
-		Level: l.Level,
+		Level:  l.Level,
+		Format: l.Format,
 	}
 }
 
@@ -35,10 +48,12 @@ func (l *Log) copy() (copied Log) {
 // settings.
 func (l *Log) overrideWith(other Log) {
 	l.Level = gosettings.OverrideWithComparable(l.Level, other.Level)
+	l.Format = gosettings.OverrideWithComparable(l.Format, other.Format)
 }
 
 func (l *Log) setDefaults() {
 	l.Level = gosettings.DefaultComparable(l.Level, log.LevelInfo.String())
+	l.Format = gosettings.DefaultComparable(l.Format, "text")
 }
 
 func (l Log) String() string {
@@ -48,10 +63,12 @@ func (l Log) String() string {
 func (l Log) toLinesNode() (node *gotree.Node) {
 	node = gotree.New("Log settings:")
 	node.Appendf("Log level: %s", l.Level)
+	node.Appendf("Log format: %s", l.Format)
 	return node
 }
 
 func (l *Log) read(r *reader.Reader) (err error) {
 	l.Level = r.String("LOG_LEVEL")
+	l.Format = r.String("LOG_FORMAT")
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/qdm12/gluetun/internal/command"
 	"github.com/qdm12/gluetun/internal/constants/providers"
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/reader"
@@ -33,6 +34,12 @@ type PortForwarding struct {
 	// forwarded port. The redirection is disabled if it is set to 0, which
 	// is its default as well.
 	ListeningPort *uint16 `json:"listening_port"`
+	// ChangeCommand is the command to run when the forwarded ports
+	// change, with the new ports appended as arguments. Its arguments
+	// can be quoted or escaped as in a shell, without expansions.
+	// It defaults to the empty string, meaning no command is run.
+	// It cannot be nil for the internal state.
+	ChangeCommand *string `json:"change_command"`
 	// Username is only used for Private Internet Access port forwarding.
 	Username string `json:"username"`
 	// Password is only used for Private Internet Access port forwarding.
@@ -67,6 +74,13 @@ func (p PortForwarding) Validate(vpnProvider string) (err error) {
 		}
 	}
 
+	if *p.ChangeCommand != "" {
+		_, err = command.Split(*p.ChangeCommand)
+		if err != nil {
+			return fmt.Errorf("change command is not valid: %w", err)
+		}
+	}
+
 	if providerSelected == providers.PrivateInternetAccess {
 		switch {
 		case p.Username == "":
@@ -85,6 +99,7 @@ func (p *PortForwarding) Copy() (copied PortForwarding) {
 		Provider:      gosettings.CopyPointer(p.Provider),
 		Filepath:      gosettings.CopyPointer(p.Filepath),
 		ListeningPort: gosettings.CopyPointer(p.ListeningPort),
+		ChangeCommand: gosettings.CopyPointer(p.ChangeCommand),
 		Username:      p.Username,
 		Password:      p.Password,
 	}
@@ -95,6 +110,7 @@ func (p *PortForwarding) OverrideWith(other PortForwarding) {
 	p.Provider = gosettings.OverrideWithPointer(p.Provider, other.Provider)
 	p.Filepath = gosettings.OverrideWithPointer(p.Filepath, other.Filepath)
 	p.ListeningPort = gosettings.OverrideWithPointer(p.ListeningPort, other.ListeningPort)
+	p.ChangeCommand = gosettings.OverrideWithPointer(p.ChangeCommand, other.ChangeCommand)
 	p.Username = gosettings.OverrideWithComparable(p.Username, other.Username)
 	p.Password = gosettings.OverrideWithComparable(p.Password, other.Password)
 }
@@ -104,6 +120,7 @@ func (p *PortForwarding) setDefaults() {
 	p.Provider = gosettings.DefaultPointer(p.Provider, "")
 	p.Filepath = gosettings.DefaultPointer(p.Filepath, "/tmp/gluetun/forwarded_port")
 	p.ListeningPort = gosettings.DefaultPointer(p.ListeningPort, 0)
+	p.ChangeCommand = gosettings.DefaultPointer(p.ChangeCommand, "")
 }
 
 func (p PortForwarding) String() string {
@@ -135,6 +152,10 @@ func (p PortForwarding) toLinesNode() (node *gotree.Node) {
 	}
 	node.Appendf("Forwarded port file path: %s", filepath)
 
+	if *p.ChangeCommand != "" {
+		node.Appendf("Forwarded port change command: %s", *p.ChangeCommand)
+	}
+
 	if p.Username != "" {
 		credentialsNode := node.Appendf("Credentials:")
 		credentialsNode.Appendf("Username: %s", p.Username)
@@ -168,6 +189,9 @@ func (p *PortForwarding) read(r *reader.Reader) (err error) {
 		return err
 	}
 
+	p.ChangeCommand = r.Get("VPN_PORT_FORWARDING_CHANGE_COMMAND",
+		reader.ForceLowercase(false))
+
 	usernameKeys := []string{"VPN_PORT_FORWARDING_USERNAME", "OPENVPN_USER", "USER"}
 	for _, key := range usernameKeys {
 		p.Username = r.String(key, reader.ForceLowercase(false))
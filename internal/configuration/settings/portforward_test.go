@@ -3,6 +3,8 @@ package settings
 import (
 	"testing"
 
+	"github.com/qdm12/gluetun/internal/command"
+	"github.com/qdm12/gluetun/internal/constants/providers"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,3 +19,49 @@ func Test_PortForwarding_String(t *testing.T) {
 
 	assert.Empty(t, s)
 }
+
+func Test_PortForwarding_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		changeCommand string
+		errWrapped    error
+		errMessage    string
+	}{
+		"no_change_command": {},
+		"change_command": {
+			changeCommand: `/bin/sh -c "echo forwarded ports $@" sh`,
+		},
+		"blank_change_command": {
+			changeCommand: " \t ",
+			errWrapped:    command.ErrCommandEmpty,
+			errMessage:    "change command is not valid: command is empty",
+		},
+		"unterminated_quote_change_command": {
+			changeCommand: `/bin/sh -c "echo`,
+			errWrapped:    command.ErrDoubleQuoteUnterminated,
+			errMessage:    "change command is not valid: unterminated double-quoted string",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			settings := PortForwarding{
+				Enabled:       boolPtr(true),
+				Provider:      ptrTo(""),
+				Filepath:      ptrTo(""),
+				ChangeCommand: ptrTo(testCase.changeCommand),
+			}
+
+			err := settings.Validate(providers.Protonvpn)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
@@ -24,6 +24,17 @@ type ControlServer struct {
 	// It cannot be empty in the internal state and defaults to
 	// /gluetun/auth/config.toml.
 	AuthFilePath string
+	// APIKey is an API key required for all the routes of the
+	// control server, in addition to the roles defined in the
+	// authentication file. It defaults to the empty string,
+	// meaning no API key is required.
+	// It cannot be nil in the internal state.
+	APIKey *string
+	// RateLimit is the maximum number of requests per minute
+	// allowed for each client IP address and route. It can be
+	// set to 0 to disable rate limiting, and defaults to 120.
+	// It cannot be nil in the internal state.
+	RateLimit *uint
 }
 
 func (c ControlServer) validate() (err error) {
@@ -52,6 +63,8 @@ func (c *ControlServer) copy() (copied ControlServer) {
 		Address:      gosettings.CopyPointer(c.Address),
 		Log:          gosettings.CopyPointer(c.Log),
 		AuthFilePath: c.AuthFilePath,
+		APIKey:       gosettings.CopyPointer(c.APIKey),
+		RateLimit:    gosettings.CopyPointer(c.RateLimit),
 	}
 }
 
@@ -62,12 +75,17 @@ func (c *ControlServer) overrideWith(other ControlServer) {
 	c.Address = gosettings.OverrideWithPointer(c.Address, other.Address)
 	c.Log = gosettings.OverrideWithPointer(c.Log, other.Log)
 	c.AuthFilePath = gosettings.OverrideWithComparable(c.AuthFilePath, other.AuthFilePath)
+	c.APIKey = gosettings.OverrideWithPointer(c.APIKey, other.APIKey)
+	c.RateLimit = gosettings.OverrideWithPointer(c.RateLimit, other.RateLimit)
 }
 
 func (c *ControlServer) setDefaults() {
 	c.Address = gosettings.DefaultPointer(c.Address, ":8000")
 	c.Log = gosettings.DefaultPointer(c.Log, true)
 	c.AuthFilePath = gosettings.DefaultComparable(c.AuthFilePath, "/gluetun/auth/config.toml")
+	c.APIKey = gosettings.DefaultPointer(c.APIKey, "")
+	const defaultRateLimit = 120
+	c.RateLimit = gosettings.DefaultPointer(c.RateLimit, defaultRateLimit)
 }
 
 func (c ControlServer) String() string {
@@ -79,6 +97,14 @@ func (c ControlServer) toLinesNode() (node *gotree.Node) {
 	node.Appendf("Listening address: %s", *c.Address)
 	node.Appendf("Logging: %s", gosettings.BoolToYesNo(c.Log))
 	node.Appendf("Authentication file path: %s", c.AuthFilePath)
+	if *c.APIKey != "" {
+		node.Appendf("API key: %s", gosettings.ObfuscateKey(*c.APIKey))
+	}
+	rateLimit := "disabled"
+	if *c.RateLimit > 0 {
+		rateLimit = fmt.Sprintf("%d requests per minute", *c.RateLimit)
+	}
+	node.Appendf("Rate limit: %s", rateLimit)
 	return node
 }
 
@@ -92,5 +118,12 @@ func (c *ControlServer) read(r *reader.Reader) (err error) {
 
 	c.AuthFilePath = r.String("HTTP_CONTROL_SERVER_AUTH_CONFIG_FILEPATH")
 
+	c.APIKey = r.Get("HTTP_CONTROL_SERVER_API_KEY", reader.ForceLowercase(false))
+
+	c.RateLimit, err = r.UintPtr("HTTP_CONTROL_SERVER_RATE_LIMIT")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -41,7 +41,7 @@ func (s *Settings) Validate(filterChoicesGetter FilterChoicesGetter, ipv6Support
 	warner Warner) (err error) {
 	nameToValidation := map[string]func() error{
 		"control server":  s.ControlServer.validate,
-		"dns":             s.DNS.validate,
+		"dns":             s.DNS.Validate,
 		"firewall":        s.Firewall.validate,
 		"health":          s.Health.Validate,
 		"http proxy":      s.HTTPProxy.validate,
@@ -54,6 +54,9 @@ func (s *Settings) Validate(filterChoicesGetter FilterChoicesGetter, ipv6Support
 		"version":         s.Version.validate,
 		// Pprof validation done in pprof constructor
 		"VPN": func() error {
+			if *s.DNS.Standalone {
+				return nil
+			}
 			return s.VPN.Validate(filterChoicesGetter, ipv6Supported, warner)
 		},
 	}
@@ -91,7 +94,7 @@ func (s *Settings) OverrideWith(other Settings,
 	filterChoicesGetter FilterChoicesGetter, ipv6Supported bool, warner Warner) (err error) {
 	patchedSettings := s.copy()
 	patchedSettings.ControlServer.overrideWith(other.ControlServer)
-	patchedSettings.DNS.overrideWith(other.DNS)
+	patchedSettings.DNS.OverrideWith(other.DNS)
 	patchedSettings.Firewall.overrideWith(other.Firewall)
 	patchedSettings.Health.OverrideWith(other.Health)
 	patchedSettings.HTTPProxy.overrideWith(other.HTTPProxy)
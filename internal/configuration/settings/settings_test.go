@@ -39,28 +39,63 @@ func Test_Settings_String(t *testing.T) {
 |       └── Verbosity level: 1
 ├── DNS settings:
 |   ├── Keep existing nameserver(s): no
+|   ├── Standalone without VPN: no
 |   ├── DNS server address to use: 127.0.0.1
+|   ├── Plaintext DNS port: 53
+|   ├── Listening address: :53
+|   ├── Strict mode without plaintext DNS: no
+|   ├── Plaintext DNS until ready: yes
+|   ├── IPv6: off
+|   ├── Maximum restart backoff: 1h0m0s
+|   ├── Uptime to reset restart backoff: 30s
+|   ├── Startup grace period: 30s
+|   ├── Startup timeout: 5m0s
+|   ├── Check on VPN reconnections: yes
+|   ├── Readiness check: timeout 10s, retry every 300ms
+|   ├── Stop grace period: 1s
+|   ├── DNSSEC validation: yes
+|   ├── EDNS client subnet: no
+|   ├── DNS64 settings:
+|   |   └── Enabled: no
 |   └── DNS over TLS settings:
 |       ├── Enabled: yes
 |       ├── Update period: every 24h0m0s
+|       ├── Update retries: 3
 |       ├── Upstream resolvers:
 |       |   └── Cloudflare
 |       ├── Caching: yes
-|       ├── IPv6: no
+|       |   ├── Size: 100000 responses
+|       |   ├── Prefetch: no
+|       |   ├── Serve expired: disabled
+|       |   └── Negative cache: 10000 responses, TTL between 0s and 1h0m0s
+|       ├── Records TTL: disabled
+|       ├── Query log: no
 |       └── DNS filtering settings:
 |           ├── Block malicious: yes
 |           ├── Block ads: no
-|           └── Block surveillance: yes
+|           ├── Block surveillance: yes
+|           ├── Block response: refused
+|           ├── Maximum concurrent downloads: 4
+|           ├── Download timeout: 15s
+|           ├── Idle connections: 2 per host, closed after 1m30s
+|           ├── Download through VPN only: no
+|           ├── Malformed lines rejection threshold: 10%
+|           |   └── Log malformed lines: no
+|           ├── Local block lists path: /gluetun/blocklists
+|           └── Cache path: /gluetun/blocklists-cache.json
+|               └── Maximum age: 168h0m0s
 ├── Firewall settings:
 |   └── Enabled: yes
 ├── Log settings:
-|   └── Log level: INFO
+|   ├── Log level: INFO
+|   └── Log format: text
 ├── Health settings:
 |   ├── Server listening address: 127.0.0.1:9999
 |   ├── Target address: cloudflare.com:443
 |   ├── Duration to wait after success: 5s
 |   ├── Read header timeout: 100ms
 |   ├── Read timeout: 500ms
+|   ├── Check DNS over TLS: no
 |   └── VPN wait durations:
 |       ├── Initial duration: 6s
 |       └── Additional duration: 5s
@@ -71,7 +106,8 @@ func Test_Settings_String(t *testing.T) {
 ├── Control server settings:
 |   ├── Listening address: :8000
 |   ├── Logging: yes
-|   └── Authentication file path: /gluetun/auth/config.toml
+|   ├── Authentication file path: /gluetun/auth/config.toml
+|   └── Rate limit: 120 requests per minute
 ├── Storage settings:
 |   └── Filepath: /gluetun/servers.json
 ├── OS Alpine settings:
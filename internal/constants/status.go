@@ -11,4 +11,7 @@ const (
 	Stopped   models.LoopStatus = "stopped"
 	Crashed   models.LoopStatus = "crashed"
 	Completed models.LoopStatus = "completed"
+	// Paused is only used by the DNS loop, to keep the DNS server
+	// running without its periodic block lists updates.
+	Paused models.LoopStatus = "paused"
 )
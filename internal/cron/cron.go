@@ -0,0 +1,185 @@
+// Package cron parses standard 5 fields cron expressions
+// and computes their next occurrence.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression, where each field
+// is a bit set of the values matching the expression.
+type Schedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// anyDay is true if either the day of month or day of week
+	// field is '*', in which case a day matches if both fields
+	// match, instead of either of them matching.
+	anyDay bool
+}
+
+var (
+	ErrFieldsCount = errors.New("expression must have 5 fields")
+	ErrFieldValue  = errors.New("field value is not valid")
+	ErrFieldRange  = errors.New("field value is out of range")
+	ErrFieldStep   = errors.New("field step is not valid")
+)
+
+var macros = map[string]string{ //nolint:gochecknoglobals
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression with the fields minute, hour,
+// day of month, month and day of week, such as "0 4 * * *".
+// Each field can be '*', a value, a range such as 1-5, a step such
+// as */15 or 1-30/2, or a comma separated list of these. The day of
+// week is 0 to 7, where both 0 and 7 are Sunday. The macros @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly are
+// also supported.
+func Parse(expression string) (schedule Schedule, err error) {
+	expression = strings.TrimSpace(expression)
+	if macroExpression, ok := macros[expression]; ok {
+		expression = macroExpression
+	}
+
+	fields := strings.Fields(expression)
+	const fieldsCount = 5
+	if len(fields) != fieldsCount {
+		return schedule, fmt.Errorf("%w: %q has %d fields",
+			ErrFieldsCount, expression, len(fields))
+	}
+
+	fieldSettings := []struct {
+		name     string
+		min, max uint
+		bits     *uint64
+	}{
+		{name: "minute", min: 0, max: 59, bits: &schedule.minutes},
+		{name: "hour", min: 0, max: 23, bits: &schedule.hours},
+		{name: "day of month", min: 1, max: 31, bits: &schedule.daysOfMonth},
+		{name: "month", min: 1, max: 12, bits: &schedule.months},
+		{name: "day of week", min: 0, max: 7, bits: &schedule.daysOfWeek},
+	}
+	for i, settings := range fieldSettings {
+		*settings.bits, err = parseField(fields[i], settings.min, settings.max)
+		if err != nil {
+			return schedule, fmt.Errorf("parsing %s field: %w", settings.name, err)
+		}
+	}
+
+	const sunday, otherSunday = 0, 7
+	if schedule.daysOfWeek&(1<<otherSunday) != 0 {
+		schedule.daysOfWeek |= 1 << sunday
+	}
+	schedule.anyDay = fields[2] == "*" || fields[4] == "*"
+
+	return schedule, nil
+}
+
+func parseField(field string, min, max uint) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := parsePart(part, min, max)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+func parsePart(part string, min, max uint) (bits uint64, err error) {
+	rangeString, stepString, hasStep := strings.Cut(part, "/")
+	step := uint(1)
+	if hasStep {
+		step, err = parseValue(stepString)
+		if err != nil || step == 0 {
+			return 0, fmt.Errorf("%w: %q", ErrFieldStep, part)
+		}
+	}
+
+	start, end := min, max
+	switch {
+	case rangeString == "*":
+	case strings.Contains(rangeString, "-"):
+		startString, endString, _ := strings.Cut(rangeString, "-")
+		start, err = parseValue(startString)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrFieldValue, part)
+		}
+		end, err = parseValue(endString)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrFieldValue, part)
+		}
+	default:
+		start, err = parseValue(rangeString)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrFieldValue, part)
+		}
+		end = start
+		if hasStep {
+			end = max
+		}
+	}
+
+	if start < min || end > max || start > end {
+		return 0, fmt.Errorf("%w: %q must be between %d and %d",
+			ErrFieldRange, part, min, max)
+	}
+
+	for value := start; value <= end; value += step {
+		bits |= 1 << value
+	}
+	return bits, nil
+}
+
+func parseValue(s string) (value uint, err error) {
+	value64, err := strconv.ParseUint(s, 10, 8) //nolint:gomnd
+	return uint(value64), err
+}
+
+// Next returns the first time strictly after the time given
+// matching the schedule, in the location of the time given.
+// It returns the zero time if no time matches within 5 years,
+// which can happen for schedules such as "0 0 31 2 *".
+func (s Schedule) Next(t time.Time) (next time.Time) {
+	next = t.Truncate(time.Minute).Add(time.Minute)
+	const maxYears = 5
+	limit := next.AddDate(maxYears, 0, 0)
+
+	for next.Before(limit) {
+		switch {
+		case s.months&(1<<uint(next.Month())) == 0:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !s.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case s.hours&(1<<uint(next.Hour())) == 0:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case s.minutes&(1<<uint(next.Minute())) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,132 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		expression string
+		errWrapped error
+		errMessage string
+	}{
+		"daily_at_4am": {
+			expression: "0 4 * * *",
+		},
+		"lists_ranges_and_steps": {
+			expression: "*/15 1-5,22 1,15 */2 1-5/2",
+		},
+		"macro": {
+			expression: "@weekly",
+		},
+		"too_few_fields": {
+			expression: "0 4 * *",
+			errWrapped: ErrFieldsCount,
+			errMessage: `expression must have 5 fields: "0 4 * *" has 4 fields`,
+		},
+		"invalid_value": {
+			expression: "x 4 * * *",
+			errWrapped: ErrFieldValue,
+			errMessage: `parsing minute field: field value is not valid: "x"`,
+		},
+		"out_of_range": {
+			expression: "0 24 * * *",
+			errWrapped: ErrFieldRange,
+			errMessage: `parsing hour field: field value is out of range: "24" must be between 0 and 23`,
+		},
+		"reversed_range": {
+			expression: "0 0 5-1 * *",
+			errWrapped: ErrFieldRange,
+			errMessage: `parsing day of month field: field value is out of range: "5-1" must be between 1 and 31`,
+		},
+		"zero_step": {
+			expression: "*/0 * * * *",
+			errWrapped: ErrFieldStep,
+			errMessage: `parsing minute field: field step is not valid: "*/0"`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(testCase.expression)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+		})
+	}
+}
+
+func Test_Schedule_Next(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		expression string
+		time       time.Time
+		next       time.Time
+	}{
+		"later_today": {
+			expression: "0 4 * * *",
+			time:       time.Date(2024, 3, 10, 1, 30, 15, 0, time.UTC),
+			next:       time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC),
+		},
+		"tomorrow": {
+			expression: "0 4 * * *",
+			time:       time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC),
+			next:       time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC),
+		},
+		"every_15_minutes": {
+			expression: "*/15 * * * *",
+			time:       time.Date(2024, 3, 10, 23, 50, 0, 0, time.UTC),
+			next:       time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		},
+		"next_year": {
+			expression: "@yearly",
+			time:       time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+			next:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"sunday_as_7": {
+			expression: "30 2 * * 7",
+			time:       time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			next:       time.Date(2024, 3, 17, 2, 30, 0, 0, time.UTC),
+		},
+		"day_of_month_or_day_of_week": {
+			expression: "0 0 20 * 1",
+			time:       time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+			next:       time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC),
+		},
+		"leap_day": {
+			expression: "0 0 29 2 *",
+			time:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			next:       time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		"never": {
+			expression: "0 0 31 2 *",
+			time:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			schedule, err := Parse(testCase.expression)
+			require.NoError(t, err)
+
+			next := schedule.Next(testCase.time)
+
+			assert.Equal(t, testCase.next, next)
+		})
+	}
+}
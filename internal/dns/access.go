@@ -0,0 +1,56 @@
+package dns
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/miekg/dns"
+)
+
+// accessMiddleware refuses queries from hosts outside the allowed
+// subnets, so the DNS server is not an open resolver. Queries from
+// loopback addresses are always allowed.
+type accessMiddleware struct {
+	allowedSubnets []netip.Prefix
+}
+
+func (m *accessMiddleware) String() string {
+	return "access control"
+}
+
+func (m *accessMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if !m.isAllowed(w.RemoteAddr()) {
+			_ = w.WriteMsg(new(dns.Msg).SetRcode(request, dns.RcodeRefused))
+			return
+		}
+		next.ServeDNS(w, request)
+	})
+}
+
+func (m *accessMiddleware) Stop() (err error) {
+	return nil
+}
+
+func (m *accessMiddleware) isAllowed(remoteAddress net.Addr) bool {
+	var ip netip.Addr
+	switch address := remoteAddress.(type) {
+	case *net.UDPAddr:
+		ip = address.AddrPort().Addr()
+	case *net.TCPAddr:
+		ip = address.AddrPort().Addr()
+	default:
+		return false
+	}
+	ip = ip.Unmap()
+
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, subnet := range m.allowedSubnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package dns
+
+import "sync"
+
+// backgroundQueries tracks the queries a middleware runs in the
+// background, so stopping the middleware with the DNS server waits
+// for them to finish and no query is started in the background once
+// stopped. Its zero value is ready to use.
+type backgroundQueries struct {
+	mutex   sync.Mutex
+	stopped bool
+	running sync.WaitGroup
+}
+
+// start returns true if a query can run in the background, in which
+// case done must be called once the query finishes. It returns false
+// once stopped.
+func (b *backgroundQueries) start() (ok bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.stopped {
+		return false
+	}
+	b.running.Add(1)
+	return true
+}
+
+func (b *backgroundQueries) done() {
+	b.running.Done()
+}
+
+// stop prevents queries from starting in the background,
+// and waits for the queries running to finish.
+func (b *backgroundQueries) stop() {
+	b.mutex.Lock()
+	b.stopped = true
+	b.mutex.Unlock()
+	b.running.Wait()
+}
@@ -0,0 +1,190 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gosettings"
+)
+
+// GetBlockedHostnames returns the custom blocked hostnames from the
+// settings and the blocked hostnames from the last block lists download.
+func (l *Loop) GetBlockedHostnames() (custom, downloaded []string) {
+	settings := l.GetSettings()
+	custom = make([]string, len(settings.DoT.Blacklist.AddBlockedHosts))
+	copy(custom, settings.DoT.Blacklist.AddBlockedHosts)
+
+	l.blockListsMu.RLock()
+	defer l.blockListsMu.RUnlock()
+	downloaded = make([]string, len(l.downloaded.BlockedHostnames))
+	copy(downloaded, l.downloaded.BlockedHostnames)
+	return custom, downloaded
+}
+
+// AddBlockedHostnames adds the given hostnames to the custom blocked
+// hostnames and updates the filter without downloading the block lists
+// again. Hostnames can be given as FQDNs with a trailing dot.
+func (l *Loop) AddBlockedHostnames(hostnames []string) (err error) {
+	l.statusManager.Lock()
+	defer l.statusManager.Unlock()
+
+	settings := l.GetSettings()
+	settings = settings.Copy()
+	existing := make(map[string]struct{}, len(settings.DoT.Blacklist.AddBlockedHosts))
+	for _, hostname := range settings.DoT.Blacklist.AddBlockedHosts {
+		existing[hostname] = struct{}{}
+	}
+	for _, hostname := range hostnames {
+		hostname = strings.TrimSuffix(hostname, ".")
+		if _, ok := existing[hostname]; ok {
+			continue
+		}
+		existing[hostname] = struct{}{}
+		settings.DoT.Blacklist.AddBlockedHosts = append(
+			settings.DoT.Blacklist.AddBlockedHosts, hostname)
+	}
+
+	err = settings.Validate()
+	if err != nil {
+		return fmt.Errorf("validating settings: %w", err)
+	}
+
+	err = l.updateFilter(settings)
+	if err != nil {
+		return err
+	}
+
+	l.state.SetSettingsLive(settings)
+	return nil
+}
+
+// GetBlockListSources returns the number of entries each block list
+// source contributed at the last block lists update, together with
+// the custom blocked hostnames and IPs from the settings.
+func (l *Loop) GetBlockListSources() (sources models.DNSBlockListSources) {
+	l.blockListsMu.RLock()
+	sources.Sources = make([]models.DNSBlockListSource, 0, len(l.sources)+1)
+	listed := make(map[string]struct{}, len(l.sources))
+	for _, source := range l.sources {
+		listed[source.name] = struct{}{}
+		sources.Sources = append(sources.Sources, models.DNSBlockListSource{
+			Name:       source.name,
+			Hostnames:  len(source.result.BlockedHostnames),
+			IPs:        len(source.result.BlockedIPs),
+			IPPrefixes: len(source.result.BlockedIPPrefixes),
+		})
+		setSourceError(&sources.Sources[len(sources.Sources)-1], l.sourceErrors)
+	}
+	// Sources failing at the last update may be missing from the
+	// sources in use if the update failed, so they are listed as
+	// contributing no entries.
+	failedNames := make([]string, 0, len(l.sourceErrors))
+	for name := range l.sourceErrors {
+		if _, ok := listed[name]; !ok {
+			failedNames = append(failedNames, name)
+		}
+	}
+	sort.Strings(failedNames)
+	for _, name := range failedNames {
+		sources.Sources = append(sources.Sources, models.DNSBlockListSource{Name: name})
+		setSourceError(&sources.Sources[len(sources.Sources)-1], l.sourceErrors)
+	}
+	l.blockListsMu.RUnlock()
+
+	blacklist := l.GetSettings().DoT.Blacklist
+	customHostnames := filterAllowedHosts(blacklist.AddBlockedHosts, blacklist.AllowedHosts)
+	sources.Sources = append(sources.Sources, models.DNSBlockListSource{
+		Name:       "custom",
+		Hostnames:  len(customHostnames),
+		IPs:        len(blacklist.AddBlockedIPs),
+		IPPrefixes: len(blacklist.AddBlockedIPPrefixes),
+	})
+
+	l.detailsMu.RLock()
+	sources.LastUpdate = l.lastUpdate
+	l.detailsMu.RUnlock()
+	return sources
+}
+
+// setSourceError sets the error of the source given
+// from the source errors given, if it has one.
+func setSourceError(source *models.DNSBlockListSource,
+	sourceErrors map[string]sourceError) {
+	sourceErr, ok := sourceErrors[source.Name]
+	if !ok {
+		return
+	}
+	source.LastError = sourceErr.message
+	since := sourceErr.since
+	source.FailingSince = &since
+}
+
+var ErrDNSServerNotRunning = errors.New("DNS server is not running")
+
+// RebuildBlockLists downloads the block lists and updates the filter of
+// the running DNS server now, without waiting for the next periodic update.
+// It returns the number of entries of each block list source once done.
+func (l *Loop) RebuildBlockLists(ctx context.Context) (
+	sources models.DNSBlockListSources, err error) {
+	status := l.GetStatus()
+	if status != constants.Running {
+		return sources, fmt.Errorf("%w: status is %s", ErrDNSServerNotRunning, status)
+	}
+
+	l.logger.Info("rebuilding block lists")
+	err = l.updateFiles(ctx)
+	if err != nil {
+		return sources, fmt.Errorf("rebuilding block lists: %w", err)
+	}
+
+	return l.GetBlockListSources(), nil
+}
+
+// SetBlockListCategories enables or disables the built-in block list
+// categories given, leaving the other categories unchanged, and rebuilds
+// the block lists of the running DNS server. The categories are kept
+// if the rebuild fails, so they are used at the next block lists update.
+// It returns the number of entries of each block list source once done.
+func (l *Loop) SetBlockListCategories(ctx context.Context,
+	categories models.DNSBlockListCategories) (
+	sources models.DNSBlockListSources, err error) {
+	status := l.GetStatus()
+	if status != constants.Running {
+		return sources, fmt.Errorf("%w: status is %s", ErrDNSServerNotRunning, status)
+	}
+
+	l.statusManager.Lock()
+	settings := l.GetSettings()
+	settings = settings.Copy()
+	blacklist := &settings.DoT.Blacklist
+	blacklist.BlockMalicious = gosettings.OverrideWithPointer(
+		blacklist.BlockMalicious, categories.Malicious)
+	blacklist.BlockAds = gosettings.OverrideWithPointer(
+		blacklist.BlockAds, categories.Ads)
+	blacklist.BlockSurveillance = gosettings.OverrideWithPointer(
+		blacklist.BlockSurveillance, categories.Surveillance)
+	err = settings.Validate()
+	if err != nil {
+		l.statusManager.Unlock()
+		return sources, fmt.Errorf("validating settings: %w", err)
+	}
+	l.state.SetSettingsLive(settings)
+	l.statusManager.Unlock()
+
+	l.logger.Info(fmt.Sprintf("rebuilding block lists with categories: "+
+		"malicious %s, ads %s, surveillance %s",
+		gosettings.BoolToYesNo(blacklist.BlockMalicious),
+		gosettings.BoolToYesNo(blacklist.BlockAds),
+		gosettings.BoolToYesNo(blacklist.BlockSurveillance)))
+	err = l.updateFiles(ctx)
+	if err != nil {
+		return sources, fmt.Errorf("rebuilding block lists: %w", err)
+	}
+
+	return l.GetBlockListSources(), nil
+}
@@ -0,0 +1,165 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/qdm12/dns/v2/pkg/blockbuilder"
+)
+
+// blockListsCache is the JSON file format of the block lists
+// cached on disk, to start quickly with the previous block lists.
+type blockListsCache struct {
+	Time    time.Time               `json:"time"`
+	Sources []blockListsCacheSource `json:"sources"`
+}
+
+type blockListsCacheSource struct {
+	Name       string         `json:"name"`
+	Hostnames  []string       `json:"hostnames"`
+	IPs        []netip.Addr   `json:"ips"`
+	IPPrefixes []netip.Prefix `json:"ip_prefixes"`
+	// Validators are the validators of the source block lists,
+	// to request them conditionally on the next update.
+	Validators blockListValidators `json:"validators"`
+}
+
+var errBlockListsCacheTooOld = errors.New("block lists cache is too old")
+
+// writeBlockListsCache writes the downloaded block lists sources to the
+// file path given, atomically so it is never partially written.
+func writeBlockListsCache(path string, sources []blockListSource,
+	cacheTime time.Time) (err error) {
+	cache := blockListsCache{
+		Time:    cacheTime,
+		Sources: make([]blockListsCacheSource, len(sources)),
+	}
+	for i, source := range sources {
+		cache.Sources[i] = blockListsCacheSource{
+			Name:       source.name,
+			Hostnames:  source.result.BlockedHostnames,
+			IPs:        source.result.BlockedIPs,
+			IPPrefixes: source.result.BlockedIPPrefixes,
+			Validators: source.validators,
+		}
+	}
+
+	err = writeFileAtomically(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(cache)
+	})
+	if err != nil {
+		return fmt.Errorf("writing block lists cache: %w", err)
+	}
+	return nil
+}
+
+// readBlockListsCache reads the block lists sources cached at the file path
+// given, and returns an error if the cache is older than the maximum age given.
+func readBlockListsCache(path string, maxAge time.Duration, now time.Time) (
+	sources []blockListSource, cacheTime time.Time, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, cacheTime, fmt.Errorf("opening block lists cache: %w", err)
+	}
+
+	var cache blockListsCache
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&cache)
+	if err != nil {
+		_ = file.Close()
+		return nil, cacheTime, fmt.Errorf("decoding block lists cache: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return nil, cacheTime, fmt.Errorf("closing block lists cache: %w", err)
+	}
+
+	age := now.Sub(cache.Time)
+	if age > maxAge {
+		return nil, cacheTime, fmt.Errorf("%w: %s is older than %s",
+			errBlockListsCacheTooOld, age.Round(time.Second), maxAge)
+	}
+
+	sources = make([]blockListSource, len(cache.Sources))
+	for i, source := range cache.Sources {
+		sources[i] = blockListSource{
+			name: source.Name,
+			result: blockbuilder.Result{
+				BlockedHostnames:  source.Hostnames,
+				BlockedIPs:        source.IPs,
+				BlockedIPPrefixes: source.IPPrefixes,
+			},
+			validators: source.Validators,
+		}
+	}
+	return sources, cache.Time, nil
+}
+
+// loadCachedBlockLists loads the block lists cached on disk if no block
+// lists update happened yet, and returns true if the cache is loaded.
+// Errors are logged since the caller falls back on downloading the
+// block lists.
+func (l *Loop) loadCachedBlockLists() (loaded bool) {
+	l.detailsMu.RLock()
+	updatedBefore := !l.lastUpdate.IsZero()
+	l.detailsMu.RUnlock()
+	settings := l.GetSettings()
+	blacklist := settings.DoT.Blacklist
+	if updatedBefore || *blacklist.CachePath == "" {
+		return false
+	}
+
+	sources, cacheTime, err := readBlockListsCache(*blacklist.CachePath,
+		*blacklist.CacheMaxAge, l.timeNow())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return false
+	case err != nil:
+		l.logger.Warn(err.Error())
+		return false
+	}
+
+	local, err := l.readLocalSource(blacklist)
+	if err != nil {
+		l.logger.Warn(err.Error())
+		return false
+	}
+	if !isEmptyResult(local) {
+		sources = append(sources, blockListSource{name: "local", result: local})
+	}
+
+	err = l.setBlockLists(settings, sources, cacheTime)
+	if err != nil {
+		l.logger.Warn(err.Error())
+		return false
+	}
+
+	l.logger.Info("using block lists cached at " + cacheTime.Format(time.RFC3339) +
+		" until they are updated")
+	return true
+}
+
+// updateFilesInBackground updates the block lists in the background
+// after the cached block lists are loaded, keeping the cached block
+// lists if the update fails.
+func (l *Loop) updateFilesInBackground(ctx context.Context) {
+	err := l.updateFiles(ctx)
+	switch {
+	case err == nil:
+		l.logger.Info("block lists updated")
+	case ctx.Err() != nil:
+	case errors.Is(err, ErrTunnelNotUp):
+		l.logger.Warn(err.Error())
+		l.logger.Info("block lists update skipped, keeping cached block lists")
+	default:
+		l.logger.Warn(err.Error())
+		l.logger.Warn("keeping cached block lists due to failed files update")
+	}
+}
@@ -0,0 +1,200 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/dns/v2/pkg/middlewares/cache"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// serverCaches are the caches of a DNS over TLS server, set
+// when building its settings, to flush them on demand. A cache
+// is nil if it is disabled.
+type serverCaches struct {
+	responses *flushCache
+	negative  *negativeCacheMiddleware
+	stale     *staleMiddleware
+}
+
+var (
+	ErrCacheDisabled       = errors.New("DNS cache is disabled")
+	ErrFlushDomainNotValid = errors.New("domain to flush is not valid")
+)
+
+// FlushCache flushes the responses cached by the DNS over TLS server
+// for the domain given and its subdomains, or all the cached responses
+// if the domain is empty, without restarting the server. It returns
+// the number of cached responses flushed.
+func (l *Loop) FlushCache(domain string) (flush models.DNSCacheFlush, err error) {
+	status := l.GetStatus()
+	if status != constants.Running && status != constants.Paused {
+		return flush, fmt.Errorf("%w: status is %s", ErrDNSServerNotRunning, status)
+	}
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain != "" {
+		if _, ok := dns.IsDomainName(domain); !ok {
+			return flush, fmt.Errorf("%w: %s", ErrFlushDomainNotValid, domain)
+		}
+	}
+
+	l.detailsMu.RLock()
+	caches := l.caches
+	l.detailsMu.RUnlock()
+	if caches == nil || caches.responses == nil {
+		return flush, fmt.Errorf("%w", ErrCacheDisabled)
+	}
+
+	flush.Domain = domain
+	flush.Flushed = caches.responses.flush(domain)
+	if caches.negative != nil {
+		flush.Flushed += caches.negative.flush(domain)
+	}
+	if caches.stale != nil {
+		// Responses kept to serve them expired are not counted,
+		// since most of them are also in the responses cache.
+		caches.stale.flush(domain)
+	}
+
+	scope := "all domains"
+	if domain != "" {
+		scope = domain + " and its subdomains"
+	}
+	l.logger.Info("flushed " + strconv.Itoa(flush.Flushed) +
+		" cached DNS responses for " + scope)
+	return flush, nil
+}
+
+// setCaches records the caches of the DNS over TLS server in use,
+// which can be nil if the DNS over TLS server stopped.
+func (l *Loop) setCaches(caches *serverCaches) {
+	l.detailsMu.Lock()
+	defer l.detailsMu.Unlock()
+	l.caches = caches
+}
+
+// flushCache wraps a cache so its cached responses can be flushed,
+// either all of them by replacing the cache with a new empty cache,
+// or the ones of a domain, which are then answered as cache misses
+// until they are cached again.
+type flushCache struct {
+	newCache   func() cache.Cache
+	maxEntries int
+	timeNow    func() time.Time
+
+	mutex sync.RWMutex
+	cache cache.Cache
+	// expiries maps the keys of the cached responses
+	// to their expiry, to find the responses to flush.
+	expiries map[string]time.Time
+	// flushed contains the keys of the responses flushed
+	// and not cached again since.
+	flushed map[string]struct{}
+}
+
+func newFlushCache(initial cache.Cache, maxEntries int,
+	newCache func() cache.Cache) *flushCache {
+	return &flushCache{
+		newCache:   newCache,
+		maxEntries: maxEntries,
+		timeNow:    time.Now,
+		cache:      initial,
+		expiries:   make(map[string]time.Time),
+		flushed:    make(map[string]struct{}),
+	}
+}
+
+func (c *flushCache) Get(request *dns.Msg) (response *dns.Msg) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if len(request.Question) > 0 {
+		if _, flushed := c.flushed[prefetchKey(request.Question[0])]; flushed {
+			return nil
+		}
+	}
+	return c.cache.Get(request)
+}
+
+func (c *flushCache) Add(request, response *dns.Msg) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cache.Add(request, response)
+	if len(request.Question) == 0 || response == nil || len(response.Answer) == 0 {
+		return
+	}
+
+	key := prefetchKey(request.Question[0])
+	delete(c.flushed, key)
+	now := c.timeNow()
+	if len(c.expiries) >= c.maxEntries {
+		for key, expiry := range c.expiries {
+			if !now.Before(expiry) {
+				delete(c.expiries, key)
+			}
+		}
+		if len(c.expiries) >= c.maxEntries {
+			return
+		}
+	}
+	c.expiries[key] = now.Add(minAnswerTTL(response))
+}
+
+// flush flushes the cached responses for the domain given and its
+// subdomains, or all the cached responses if the domain is empty.
+// It returns the number of responses flushed not expired yet.
+func (c *flushCache) flush(domain string) (count int) {
+	now := c.timeNow()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if domain == "" {
+		for _, expiry := range c.expiries {
+			if now.Before(expiry) {
+				count++
+			}
+		}
+		c.cache = c.newCache()
+		clear(c.expiries)
+		clear(c.flushed)
+		return count
+	}
+
+	for key, expiry := range c.expiries {
+		if !keyInDomain(key, domain) {
+			continue
+		}
+		delete(c.expiries, key)
+		if now.Before(expiry) {
+			c.flushed[key] = struct{}{}
+			count++
+		}
+	}
+	return count
+}
+
+// keyInDomain returns true if the name of the cache key given
+// is the domain given or one of its subdomains.
+func keyInDomain(key, domain string) bool {
+	name, _, _ := strings.Cut(key, "|")
+	return dns.IsSubDomain(dns.Fqdn(domain), name)
+}
+
+// minAnswerTTL returns the minimum TTL of the answer records of the
+// response given, which must have at least one answer record.
+func minAnswerTTL(response *dns.Msg) (ttl time.Duration) {
+	minTTL := response.Answer[0].Header().Ttl
+	for _, rr := range response.Answer[1:] {
+		if rr.Header().Ttl < minTTL {
+			minTTL = rr.Header().Ttl
+		}
+	}
+	return time.Duration(minTTL) * time.Second
+}
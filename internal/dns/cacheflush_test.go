@@ -0,0 +1,186 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/dns/v2/pkg/middlewares/cache"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/loopstate"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFlushCache returns a flush cache with responses cached for
+// example.com, www.example.com, other.com and the already expired
+// expired.example.com, and the number of caches it created since.
+// The map cache wrapped does not expire responses, so the expired
+// response is still answered unless the whole cache is flushed.
+func newTestFlushCache(t *testing.T) (flushCache *flushCache, cachesCreated *int) {
+	t.Helper()
+
+	cachesCreated = new(int)
+	flushCache = newFlushCache(newMapCache(), 10, func() cache.Cache { //nolint:gomnd
+		*cachesCreated++
+		return newMapCache()
+	})
+	now := time.Unix(0, 0)
+	flushCache.timeNow = func() time.Time { return now }
+
+	ttls := map[string]uint32{
+		"example.com.":         60,
+		"www.example.com.":     60,
+		"other.com.":           60,
+		"expired.example.com.": 1,
+	}
+	for name, ttl := range ttls {
+		request := new(dns.Msg).SetQuestion(name, dns.TypeA)
+		flushCache.Add(request, newAnswer(request, net.IPv4(1, 1, 1, 1), ttl))
+	}
+	now = now.Add(time.Second)
+	return flushCache, cachesCreated
+}
+
+func Test_flushCache_flush(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		domain        string
+		count         int
+		cachesCreated int
+		notCached     []string
+		cached        []string
+	}{
+		"domain_and_subdomains": {
+			domain:    "example.com",
+			count:     2,
+			notCached: []string{"example.com.", "www.example.com."},
+			cached:    []string{"other.com."},
+		},
+		"subdomain_only": {
+			domain:    "www.example.com",
+			count:     1,
+			notCached: []string{"www.example.com."},
+			cached:    []string{"example.com.", "expired.example.com.", "other.com."},
+		},
+		"domain_not_cached": {
+			domain: "unknown.com",
+			cached: []string{"example.com.", "www.example.com.",
+				"expired.example.com.", "other.com."},
+		},
+		"all": {
+			count:         3,
+			cachesCreated: 1,
+			notCached: []string{"example.com.", "www.example.com.",
+				"expired.example.com.", "other.com."},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			flushCache, cachesCreated := newTestFlushCache(t)
+
+			count := flushCache.flush(testCase.domain)
+
+			assert.Equal(t, testCase.count, count)
+			assert.Equal(t, testCase.cachesCreated, *cachesCreated)
+			for _, name := range testCase.notCached {
+				request := new(dns.Msg).SetQuestion(name, dns.TypeA)
+				assert.Nil(t, flushCache.Get(request), name)
+			}
+			for _, name := range testCase.cached {
+				request := new(dns.Msg).SetQuestion(name, dns.TypeA)
+				assert.NotNil(t, flushCache.Get(request), name)
+			}
+		})
+	}
+}
+
+func Test_flushCache_addAfterFlush(t *testing.T) {
+	t.Parallel()
+
+	flushCache, _ := newTestFlushCache(t)
+	request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+
+	count := flushCache.flush("example.com")
+	require.Equal(t, 2, count)
+	require.Nil(t, flushCache.Get(request))
+
+	// A response cached again after the flush is answered again.
+	flushCache.Add(request, newAnswer(request, net.IPv4(2, 2, 2, 2), 60)) //nolint:gomnd
+	response := flushCache.Get(request)
+	require.NotNil(t, response)
+	answer := response.Answer[0].(*dns.A) //nolint:forcetypeassert
+	assert.True(t, net.IPv4(2, 2, 2, 2).Equal(answer.A))
+}
+
+func Test_Loop_FlushCache(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		status     models.LoopStatus
+		cacheless  bool
+		domain     string
+		flush      models.DNSCacheFlush
+		errWrapped error
+		errMessage string
+	}{
+		"not_running": {
+			status:     constants.Stopped,
+			errWrapped: ErrDNSServerNotRunning,
+			errMessage: "DNS server is not running: status is stopped",
+		},
+		"domain_not_valid": {
+			status:     constants.Running,
+			domain:     "example..com",
+			errWrapped: ErrFlushDomainNotValid,
+			errMessage: "domain to flush is not valid: example..com",
+		},
+		"cache_disabled": {
+			status:     constants.Running,
+			cacheless:  true,
+			errWrapped: ErrCacheDisabled,
+			errMessage: "DNS cache is disabled",
+		},
+		"domain": {
+			status: constants.Running,
+			domain: "Example.COM.",
+			flush:  models.DNSCacheFlush{Domain: "example.com", Flushed: 2},
+		},
+		"all_paused": {
+			status: constants.Paused,
+			flush:  models.DNSCacheFlush{Flushed: 3},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loop := &Loop{
+				statusManager: loopstate.New(testCase.status, make(chan struct{}),
+					make(chan models.LoopStatus), make(chan struct{}), make(chan struct{})),
+				logger: noopLogger{},
+			}
+			if !testCase.cacheless {
+				responses, _ := newTestFlushCache(t)
+				loop.setCaches(&serverCaches{responses: responses})
+			}
+
+			flush, err := loop.FlushCache(testCase.domain)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+			assert.Equal(t, testCase.flush, flush)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package dns
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// cacheCounts are the numbers of queries which reached the
+// DNS over TLS server caches and which went past them.
+type cacheCounts struct {
+	queries uint64
+	misses  uint64
+}
+
+// cacheStatsReporter keeps the state of the cache hit ratio
+// reports between ticks of the cache statistics timer.
+type cacheStatsReporter struct {
+	previous cacheCounts
+	// disabledLogged is true if the report was skipped since
+	// caching is disabled, so it is only logged once.
+	disabledLogged bool
+}
+
+// reportCacheStats logs the ratio of queries answered from cache since
+// the previous report, and sets the cache hit ratio gauge. If caching
+// is disabled, it logs it once and does nothing until it is enabled.
+func (l *Loop) reportCacheStats(reporter *cacheStatsReporter, settings settings.DNS) {
+	period := *settings.DoT.CacheStatsPeriod
+	if !*settings.DoT.Caching {
+		if !reporter.disabledLogged {
+			l.logger.Info("not reporting the cache hit ratio since caching is disabled")
+			reporter.disabledLogged = true
+		}
+		return
+	}
+	reporter.disabledLogged = false
+
+	current := l.cacheTracker.counts()
+	queries := current.queries - reporter.previous.queries
+	misses := min(current.misses-reporter.previous.misses, queries)
+	reporter.previous = current
+	if queries == 0 {
+		l.logger.Debug("no DNS query reached the cache in the last " + period.String())
+		return
+	}
+
+	hits := queries - misses
+	ratio := float64(hits) / float64(queries)
+	l.metrics.setCacheHitRatio(ratio)
+	l.logger.Info(fmt.Sprintf("cache hit ratio over the last %s: %.1f%% (%d of %d queries)",
+		period, 100*ratio, hits, queries)) //nolint:gomnd
+}
+
+// resetCacheStatsTimer resets the stopped or expired timer given to fire
+// after the cache statistics period, and returns true if the cache hit
+// ratio reports are disabled so the timer is left stopped.
+func resetCacheStatsTimer(timer *time.Timer, settings settings.DNS) (timerIsStopped bool) {
+	period := *settings.DoT.CacheStatsPeriod
+	if period == 0 {
+		return true
+	}
+	timer.Reset(period)
+	return false
+}
@@ -0,0 +1,122 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/dns/v2/pkg/dot"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// CheckBlocked checks if the hostname given is blocked by the
+// DNS filter, and which block list sources block it. The hostname
+// is blocked if it or one of its parent domains is blocked, or if
+// one of its IP addresses resolved using the DNS over TLS upstream
+// resolvers is blocked.
+func (l *Loop) CheckBlocked(ctx context.Context, hostname string) (
+	check models.DNSBlockCheck) {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	check.Hostname = hostname
+	check.Matches = []models.DNSBlockMatch{}
+
+	sources := l.getSourcesWithCustom()
+
+	// Check the hostname and its parent domains, the same
+	// way the filter does.
+	labels := dns.SplitDomainName(hostname)
+	parents := make(map[string]struct{}, len(labels))
+	for i := range labels {
+		parents[strings.Join(labels[i:], ".")] = struct{}{}
+	}
+	for _, source := range sources {
+		for _, blockedHostname := range source.result.BlockedHostnames {
+			if _, ok := parents[blockedHostname]; ok {
+				check.Matches = append(check.Matches, models.DNSBlockMatch{
+					Source: source.name,
+					Entry:  blockedHostname,
+				})
+			}
+		}
+	}
+
+	ips, err := l.resolveUnfiltered(ctx, hostname)
+	if err != nil {
+		check.ResolveError = err.Error()
+	}
+	for _, ip := range ips {
+		ip = ip.Unmap()
+		for _, source := range sources {
+			for _, blockedIP := range source.result.BlockedIPs {
+				if blockedIP == ip {
+					check.Matches = append(check.Matches, models.DNSBlockMatch{
+						Source: source.name,
+						Entry:  blockedIP.String(),
+					})
+				}
+			}
+			for _, blockedPrefix := range source.result.BlockedIPPrefixes {
+				if blockedPrefix.Contains(ip) {
+					check.Matches = append(check.Matches, models.DNSBlockMatch{
+						Source: source.name,
+						Entry:  blockedPrefix.String(),
+					})
+				}
+			}
+		}
+	}
+
+	check.Blocked = len(check.Matches) > 0
+	return check
+}
+
+// getSourcesWithCustom returns the block list sources from the last
+// update, together with the custom blocked hostnames and IPs source.
+func (l *Loop) getSourcesWithCustom() (sources []blockListSource) {
+	l.blockListsMu.RLock()
+	sources = make([]blockListSource, len(l.sources), len(l.sources)+1)
+	copy(sources, l.sources)
+	l.blockListsMu.RUnlock()
+
+	blacklist := l.GetSettings().DoT.Blacklist
+	custom := blockListSource{name: "custom"}
+	custom.result.BlockedHostnames = filterAllowedHosts(blacklist.AddBlockedHosts,
+		blacklist.AllowedHosts)
+	custom.result.BlockedIPs = blacklist.AddBlockedIPs
+	custom.result.BlockedIPPrefixes = blacklist.AddBlockedIPPrefixes
+	return append(sources, custom)
+}
+
+// resolveUnfiltered resolves the hostname using the DNS over TLS
+// upstream resolvers directly, bypassing the filter.
+func (l *Loop) resolveUnfiltered(ctx context.Context, hostname string) (
+	ips []netip.Addr, err error) {
+	if l.killSwitch && !l.isTunnelUp() {
+		return nil, ErrTunnelNotUp
+	}
+
+	resolver, err := l.newUpstreamResolver(l.GetSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	return resolver.LookupNetIP(ctx, "ip", hostname)
+}
+
+// newUpstreamResolver returns a resolver using the DNS over TLS
+// upstream resolvers directly, without the DNS server middlewares.
+func (l *Loop) newUpstreamResolver(settings settings.DNS) (
+	resolver *net.Resolver, err error) {
+	ipVersion := "ipv4"
+	if l.useIPv6(settings) {
+		ipVersion = "ipv6"
+	}
+	return dot.NewResolver(dot.ResolverSettings{
+		UpstreamResolvers: settings.DoT.GetProviders(),
+		IPVersion:         ipVersion,
+		Warner:            l.logger,
+	})
+}
@@ -0,0 +1,122 @@
+package dns
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// blockListValidators are the HTTP validators of the block lists
+// of a source, used to skip the source update if none of its block
+// lists changed since they were downloaded.
+type blockListValidators struct {
+	// Allowed identifies the allowed hosts the source was built
+	// with, and its URLs for the `urls` source, since changing
+	// them changes the source entries even if its block lists
+	// did not change.
+	Allowed string `json:"allowed"`
+	// URLs maps each block list URL to its validators.
+	URLs map[string]listValidator `json:"urls"`
+}
+
+type listValidator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// allowedKey returns the key identifying the allowed hosts given.
+func allowedKey(hosts []string) string {
+	return strings.Join(hosts, ",")
+}
+
+// newConditionalClient returns a copy of the HTTP client given which
+// sends conditional requests using the validators given, and records
+// the validators of the responses. Not modified responses are answered
+// with an empty block list, so the caller must discard the entries
+// built if any block list was not modified. Validators can be nil to
+// send unconditional requests and only record validators.
+func newConditionalClient(client *http.Client, validators map[string]listValidator) (
+	conditionalClient *http.Client, transport *conditionalTransport) {
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	transport = &conditionalTransport{
+		previous:   validators,
+		validators: make(map[string]listValidator),
+		next:       next,
+	}
+	copied := *client
+	copied.Transport = transport
+	return &copied, transport
+}
+
+type conditionalTransport struct {
+	previous map[string]listValidator
+	next     http.RoundTripper
+
+	mutex       sync.Mutex
+	validators  map[string]listValidator
+	notModified int
+	modified    int
+}
+
+func (t *conditionalTransport) RoundTrip(request *http.Request) (
+	response *http.Response, err error) {
+	url := request.URL.String()
+	previous, conditional := t.previous[url]
+	if conditional {
+		request = request.Clone(request.Context())
+		if previous.ETag != "" {
+			request.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			request.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case response.StatusCode == http.StatusNotModified && conditional:
+		_ = response.Body.Close()
+		t.record(url, previous, false)
+		response.StatusCode = http.StatusOK
+		response.Status = "200 OK"
+		response.Body = http.NoBody
+		response.ContentLength = 0
+	case response.StatusCode == http.StatusOK:
+		validator := listValidator{
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+		}
+		t.record(url, validator, true)
+	}
+	return response, nil
+}
+
+func (t *conditionalTransport) record(url string, validator listValidator,
+	modified bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if modified {
+		t.modified++
+	} else {
+		t.notModified++
+	}
+	if validator != (listValidator{}) {
+		t.validators[url] = validator
+	}
+}
+
+// counts returns the number of block lists not modified
+// and modified, and the validators recorded.
+func (t *conditionalTransport) counts() (notModified, modified int,
+	validators map[string]listValidator) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.notModified, t.modified, t.validators
+}
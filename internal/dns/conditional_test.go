@@ -0,0 +1,88 @@
+package dns
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_conditionalTransport(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"v1"`
+	const content = "example.com\n"
+
+	testCases := map[string]struct {
+		validators  map[string]listValidator
+		content     string
+		notModified int
+		modified    int
+		recorded    listValidator
+	}{
+		"unconditional": {
+			content:  content,
+			modified: 1,
+			recorded: listValidator{ETag: etag},
+		},
+		"not_modified": {
+			validators: map[string]listValidator{
+				"/list": {ETag: etag},
+			},
+			notModified: 1,
+			recorded:    listValidator{ETag: etag},
+		},
+		"modified": {
+			validators: map[string]listValidator{
+				"/list": {ETag: `"v0"`},
+			},
+			content:  content,
+			modified: 1,
+			recorded: listValidator{ETag: etag},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("ETag", etag)
+					if r.Header.Get("If-None-Match") == etag {
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					_, _ = w.Write([]byte(content))
+				}))
+			t.Cleanup(server.Close)
+
+			url := server.URL + "/list"
+			var validators map[string]listValidator
+			if testCase.validators != nil {
+				validators = map[string]listValidator{
+					url: testCase.validators["/list"],
+				}
+			}
+			client, transport := newConditionalClient(server.Client(), validators)
+
+			response, err := client.Get(url) //nolint:noctx
+			require.NoError(t, err)
+			body, err := io.ReadAll(response.Body)
+			require.NoError(t, err)
+			err = response.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, testCase.content, string(body))
+			notModified, modified, recorded := transport.counts()
+			assert.Equal(t, testCase.notModified, notModified)
+			assert.Equal(t, testCase.modified, modified)
+			assert.Equal(t, map[string]listValidator{url: testCase.recorded}, recorded)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package dns
+
+import (
+	"net/netip"
+
+	"github.com/miekg/dns"
+)
+
+// dns64Middleware synthesizes AAAA records from A records for
+// hostnames without AAAA records, as described in RFC 6147, by
+// embedding their IPv4 addresses in the NAT64 prefix.
+type dns64Middleware struct {
+	prefix netip.Prefix
+}
+
+func (m *dns64Middleware) String() string {
+	return "DNS64"
+}
+
+func (m *dns64Middleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if len(request.Question) == 0 || request.Question[0].Qtype != dns.TypeAAAA ||
+			dnssecOK(request) && request.CheckingDisabled {
+			// A validating client would reject synthesized records.
+			next.ServeDNS(w, request)
+			return
+		}
+
+		recorder := &responseRecorder{}
+		next.ServeDNS(recorder, request)
+		response := recorder.response
+		if response == nil || response.Rcode != dns.RcodeSuccess || hasAAAA(response) {
+			if response != nil {
+				_ = w.WriteMsg(response)
+			}
+			return
+		}
+
+		aRequest := request.Copy()
+		aRequest.Question[0].Qtype = dns.TypeA
+		recorder = &responseRecorder{}
+		next.ServeDNS(recorder, aRequest)
+		synthesized := m.synthesize(recorder.response)
+		if len(synthesized) == 0 {
+			_ = w.WriteMsg(response)
+			return
+		}
+
+		response.Answer = synthesized
+		response.Ns = nil
+		_ = w.WriteMsg(response)
+	})
+}
+
+func (m *dns64Middleware) Stop() (err error) {
+	return nil
+}
+
+// synthesize returns the answer records of the A response given, with
+// each A record replaced by an AAAA record embedding its IPv4 address in
+// the NAT64 prefix. It returns no record if no A record can be used.
+func (m *dns64Middleware) synthesize(aResponse *dns.Msg) (answer []dns.RR) {
+	if aResponse == nil || aResponse.Rcode != dns.RcodeSuccess {
+		return nil
+	}
+
+	// The well-known prefix must not be used to reach
+	// non-global IPv4 addresses, as specified in RFC 6052.
+	wellKnownPrefix := m.prefix == netip.MustParsePrefix("64:ff9b::/96")
+
+	answer = make([]dns.RR, 0, len(aResponse.Answer))
+	synthesizedCount := 0
+	for _, rr := range aResponse.Answer {
+		a, ok := rr.(*dns.A)
+		if !ok {
+			if rr.Header().Rrtype == dns.TypeCNAME {
+				answer = append(answer, dns.Copy(rr))
+			}
+			continue
+		}
+
+		ipv4, ok := netip.AddrFromSlice(a.A.To4())
+		if !ok || wellKnownPrefix && !isGlobalIPv4(ipv4) {
+			continue
+		}
+		header := a.Hdr
+		header.Rrtype = dns.TypeAAAA
+		answer = append(answer, &dns.AAAA{
+			Hdr:  header,
+			AAAA: embedIPv4(m.prefix, ipv4).AsSlice(),
+		})
+		synthesizedCount++
+	}
+
+	if synthesizedCount == 0 {
+		return nil
+	}
+	return answer
+}
+
+// embedIPv4 embeds the IPv4 address in the IPv6 prefix given, skipping
+// the reserved bits 64 to 71 of the IPv6 address, as defined in RFC 6052.
+func embedIPv4(prefix netip.Prefix, ipv4 netip.Addr) (ipv6 netip.Addr) {
+	bytes := prefix.Addr().As16()
+	const reservedByteIndex = 8
+	index := prefix.Bits() / 8 //nolint:gomnd
+	for _, b := range ipv4.As4() {
+		if index == reservedByteIndex {
+			index++
+		}
+		bytes[index] = b
+		index++
+	}
+	return netip.AddrFrom16(bytes)
+}
+
+func isGlobalIPv4(ip netip.Addr) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+func hasAAAA(response *dns.Msg) bool {
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype == dns.TypeAAAA {
+			return true
+		}
+	}
+	return false
+}
+
+func dnssecOK(request *dns.Msg) bool {
+	opt := request.IsEdns0()
+	return opt != nil && opt.Do()
+}
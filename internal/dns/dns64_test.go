@@ -0,0 +1,146 @@
+package dns
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_dns64Middleware(t *testing.T) {
+	t.Parallel()
+
+	const name = "example.com."
+	header := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: 60}
+	}
+	newA := func(ip string) dns.RR {
+		return &dns.A{Hdr: header(dns.TypeA), A: net.ParseIP(ip)}
+	}
+	newAAAA := func(ip string) dns.RR {
+		return &dns.AAAA{Hdr: header(dns.TypeAAAA), AAAA: net.ParseIP(ip)}
+	}
+	cname := &dns.CNAME{Hdr: header(dns.TypeCNAME), Target: "target.example.com."}
+	wellKnownPrefix := netip.MustParsePrefix("64:ff9b::/96")
+
+	testCases := map[string]struct {
+		prefix    netip.Prefix
+		qtype     uint16
+		dnssecOK  bool
+		rcode     int
+		a         []dns.RR
+		aaaa      []dns.RR
+		nextCalls int
+		answer    []string
+	}{
+		"a_query": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeA,
+			a:         []dns.RR{newA("8.8.8.8")},
+			nextCalls: 1,
+			answer:    []string{"8.8.8.8"},
+		},
+		"native_aaaa": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			a:         []dns.RR{newA("8.8.8.8")},
+			aaaa:      []dns.RR{newAAAA("2001:4860:4860::8888")},
+			nextCalls: 1,
+			answer:    []string{"2001:4860:4860::8888"},
+		},
+		"nxdomain": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			rcode:     dns.RcodeNameError,
+			nextCalls: 1,
+		},
+		"validating_client": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			dnssecOK:  true,
+			a:         []dns.RR{newA("8.8.8.8")},
+			nextCalls: 1,
+		},
+		"synthesized": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			a:         []dns.RR{newA("8.8.8.8")},
+			nextCalls: 2,
+			answer:    []string{"64:ff9b::808:808"},
+		},
+		"synthesized_with_cname": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			a:         []dns.RR{cname, newA("8.8.8.8")},
+			nextCalls: 2,
+			answer:    []string{"target.example.com.", "64:ff9b::808:808"},
+		},
+		"well_known_prefix_private_ipv4": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			a:         []dns.RR{newA("10.0.0.1")},
+			nextCalls: 2,
+		},
+		"network_specific_prefix_private_ipv4": {
+			prefix:    netip.MustParsePrefix("2001:db8:100::/40"),
+			qtype:     dns.TypeAAAA,
+			a:         []dns.RR{newA("10.0.0.1")},
+			nextCalls: 2,
+			answer:    []string{"2001:db8:10a:0:1::"},
+		},
+		"no_a_record": {
+			prefix:    wellKnownPrefix,
+			qtype:     dns.TypeAAAA,
+			nextCalls: 2,
+		},
+	}
+
+	for testName, testCase := range testCases {
+		testCase := testCase
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			middleware := &dns64Middleware{prefix: testCase.prefix}
+			nextCalls := 0
+			next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				nextCalls++
+				response := new(dns.Msg).SetRcode(request, testCase.rcode)
+				switch request.Question[0].Qtype {
+				case dns.TypeA:
+					response.Answer = testCase.a
+				case dns.TypeAAAA:
+					response.Answer = testCase.aaaa
+				}
+				_ = w.WriteMsg(response)
+			})
+
+			request := new(dns.Msg).SetQuestion(name, testCase.qtype)
+			if testCase.dnssecOK {
+				request.SetEdns0(1232, true) //nolint:gomnd
+				request.CheckingDisabled = true
+			}
+			recorder := &responseRecorder{}
+			middleware.Wrap(next).ServeDNS(recorder, request)
+
+			assert.Equal(t, testCase.nextCalls, nextCalls)
+			response := recorder.response
+			require.NotNil(t, response)
+			assert.Equal(t, testCase.rcode, response.Rcode)
+			var answer []string
+			for _, rr := range response.Answer {
+				switch rr := rr.(type) {
+				case *dns.A:
+					answer = append(answer, rr.A.String())
+				case *dns.AAAA:
+					answer = append(answer, rr.AAAA.String())
+				case *dns.CNAME:
+					answer = append(answer, rr.Target)
+				}
+			}
+			assert.Equal(t, testCase.answer, answer)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package dns
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// dnssecMiddleware sets the checking disabled bit on requests if
+// DNSSEC validation is disabled. If validation is enabled, it logs
+// DNSSEC validation failures reported by the upstream resolvers
+// as extended DNS errors (RFC 8914).
+type dnssecMiddleware struct {
+	enabled bool
+	logger  Logger
+}
+
+func (m *dnssecMiddleware) String() string {
+	return "DNSSEC"
+}
+
+func (m *dnssecMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	if !m.enabled {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+			request.CheckingDisabled = true
+			next.ServeDNS(w, request)
+		})
+	}
+
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		// Extended DNS errors are only sent back by the upstream
+		// resolver if the request has an EDNS0 OPT record.
+		clientHasEDNS := request.IsEdns0() != nil
+		if !clientHasEDNS {
+			const udpSize = 1232
+			request.SetEdns0(udpSize, false)
+		}
+		next.ServeDNS(&dnssecResponseWriter{
+			ResponseWriter: w,
+			request:        request,
+			stripEDNS:      !clientHasEDNS,
+			logger:         m.logger,
+		}, request)
+	})
+}
+
+func (m *dnssecMiddleware) Stop() (err error) {
+	return nil
+}
+
+type dnssecResponseWriter struct {
+	dns.ResponseWriter
+	request   *dns.Msg
+	stripEDNS bool
+	logger    Logger
+}
+
+func (w *dnssecResponseWriter) WriteMsg(response *dns.Msg) error {
+	opt := response.IsEdns0()
+	if opt != nil {
+		for _, option := range opt.Option {
+			ede, ok := option.(*dns.EDNS0_EDE)
+			if !ok || !isDNSSECErrorCode(ede.InfoCode) {
+				continue
+			}
+			name := "unknown"
+			if len(w.request.Question) > 0 {
+				name = w.request.Question[0].Name
+			}
+			w.logger.Warn(fmt.Sprintf("DNSSEC validation failed for %s: %s",
+				name, ede.String()))
+		}
+	}
+
+	if w.stripEDNS && opt != nil {
+		extra := make([]dns.RR, 0, len(response.Extra)-1)
+		for _, rr := range response.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				extra = append(extra, rr)
+			}
+		}
+		response.Extra = extra
+	}
+
+	return w.ResponseWriter.WriteMsg(response)
+}
+
+func isDNSSECErrorCode(infoCode uint16) bool {
+	switch infoCode {
+	case dns.ExtendedErrorCodeUnsupportedDNSKEYAlgorithm,
+		dns.ExtendedErrorCodeUnsupportedDSDigestType,
+		dns.ExtendedErrorCodeDNSSECIndeterminate,
+		dns.ExtendedErrorCodeDNSBogus,
+		dns.ExtendedErrorCodeSignatureExpired,
+		dns.ExtendedErrorCodeSignatureNotYetValid,
+		dns.ExtendedErrorCodeDNSKEYMissing,
+		dns.ExtendedErrorCodeRRSIGsMissing,
+		dns.ExtendedErrorCodeNoZoneKeyBitSet,
+		dns.ExtendedErrorCodeNSECMissing:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// warnLogger is a logger recording the warnings logged.
+type warnLogger struct {
+	noopLogger
+	warnings []string
+}
+
+func (l *warnLogger) Warn(s string) { l.warnings = append(l.warnings, s) }
+
+func Test_dnssecMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// newResponse returns an answer to the request, with an
+	// OPT record with the extended DNS error code given if
+	// it is not zero.
+	newResponse := func(request *dns.Msg, edeCode uint16) *dns.Msg {
+		response := newAnswer(request, net.IPv4(1, 1, 1, 1), 60) //nolint:gomnd
+		if edeCode != 0 {
+			response.SetEdns0(1232, false) //nolint:gomnd
+			opt := response.IsEdns0()
+			opt.Option = append(opt.Option, &dns.EDNS0_EDE{
+				InfoCode:  edeCode,
+				ExtraText: "upstream",
+			})
+		}
+		return response
+	}
+
+	testCases := map[string]struct {
+		enabled          bool
+		clientEDNS       bool
+		edeCode          uint16
+		checkingDisabled bool
+		upstreamEDNS     bool
+		responseEDNS     bool
+		warnings         []string
+	}{
+		"disabled": {
+			edeCode:          dns.ExtendedErrorCodeDNSBogus,
+			checkingDisabled: true,
+			responseEDNS:     true,
+		},
+		"enabled_validated": {
+			enabled:      true,
+			upstreamEDNS: true,
+		},
+		"enabled_bogus_client_without_edns": {
+			enabled:      true,
+			edeCode:      dns.ExtendedErrorCodeDNSBogus,
+			upstreamEDNS: true,
+			warnings: []string{"DNSSEC validation failed for example.com.: " +
+				"6 (DNSSEC Bogus): (upstream)"},
+		},
+		"enabled_bogus_client_with_edns": {
+			enabled:      true,
+			clientEDNS:   true,
+			edeCode:      dns.ExtendedErrorCodeSignatureExpired,
+			upstreamEDNS: true,
+			responseEDNS: true,
+			warnings: []string{"DNSSEC validation failed for example.com.: " +
+				"7 (Signature Expired): (upstream)"},
+		},
+		"enabled_other_extended_error": {
+			enabled:      true,
+			clientEDNS:   true,
+			edeCode:      dns.ExtendedErrorCodeNetworkError,
+			upstreamEDNS: true,
+			responseEDNS: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			logger := &warnLogger{}
+			middleware := &dnssecMiddleware{enabled: testCase.enabled, logger: logger}
+			var upstreamRequest *dns.Msg
+			next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				upstreamRequest = request.Copy()
+				_ = w.WriteMsg(newResponse(request, testCase.edeCode))
+			})
+
+			request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			if testCase.clientEDNS {
+				request.SetEdns0(4096, false) //nolint:gomnd
+			}
+			recorder := &responseRecorder{}
+			middleware.Wrap(next).ServeDNS(recorder, request)
+
+			require.NotNil(t, upstreamRequest)
+			assert.Equal(t, testCase.checkingDisabled, upstreamRequest.CheckingDisabled)
+			assert.Equal(t, testCase.upstreamEDNS, upstreamRequest.IsEdns0() != nil)
+			require.NotNil(t, recorder.response)
+			assert.Len(t, recorder.response.Answer, 1)
+			assert.Equal(t, testCase.responseEDNS, recorder.response.IsEdns0() != nil)
+			assert.Equal(t, testCase.warnings, logger.warnings)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// drainMiddleware tracks queries in flight and refuses new
+// queries once draining started, so the server can be stopped
+// after in flight queries are answered.
+type drainMiddleware struct {
+	mutex    sync.Mutex
+	draining bool
+	inFlight sync.WaitGroup
+}
+
+func (m *drainMiddleware) String() string {
+	return "drain"
+}
+
+func (m *drainMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		m.mutex.Lock()
+		if m.draining {
+			m.mutex.Unlock()
+			_ = w.WriteMsg(new(dns.Msg).SetRcode(request, dns.RcodeRefused))
+			return
+		}
+		m.inFlight.Add(1)
+		m.mutex.Unlock()
+		defer m.inFlight.Done()
+
+		next.ServeDNS(w, request)
+	})
+}
+
+func (m *drainMiddleware) Stop() (err error) {
+	return nil
+}
+
+// drain refuses new queries and waits for queries in flight
+// to complete, for at most the grace duration given.
+// It returns true if all queries in flight completed.
+func (m *drainMiddleware) drain(grace time.Duration) (drained bool) {
+	m.mutex.Lock()
+	m.draining = true
+	m.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.inFlight.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	select {
+	case <-done:
+		if !timer.Stop() {
+			<-timer.C
+		}
+		return true
+	case <-timer.C:
+		return false
+	}
+}
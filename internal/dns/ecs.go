@@ -0,0 +1,34 @@
+package dns
+
+import (
+	"github.com/miekg/dns"
+)
+
+// ecsMiddleware removes EDNS client subnet options (RFC 7871) from
+// requests, so no information on the client subnet is sent to the
+// upstream resolvers.
+type ecsMiddleware struct{}
+
+func (m *ecsMiddleware) String() string {
+	return "EDNS client subnet removal"
+}
+
+func (m *ecsMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		opt := request.IsEdns0()
+		if opt != nil {
+			options := opt.Option[:0]
+			for _, option := range opt.Option {
+				if option.Option() != dns.EDNS0SUBNET {
+					options = append(options, option)
+				}
+			}
+			opt.Option = options
+		}
+		next.ServeDNS(w, request)
+	})
+}
+
+func (m *ecsMiddleware) Stop() (err error) {
+	return nil
+}
@@ -0,0 +1,88 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ecsMiddleware(t *testing.T) {
+	t.Parallel()
+
+	subnet := func() dns.EDNS0 {
+		return &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        1,
+			SourceNetmask: 24, //nolint:gomnd
+			Address:       net.IPv4(203, 0, 113, 0),
+		}
+	}
+	cookie := func() dns.EDNS0 {
+		return &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0123456789abcdef"}
+	}
+
+	testCases := map[string]struct {
+		edns    bool
+		options []dns.EDNS0
+		codes   []uint16
+	}{
+		"no_edns": {},
+		"no_option": {
+			edns: true,
+		},
+		"other_option_only": {
+			edns:    true,
+			options: []dns.EDNS0{cookie()},
+			codes:   []uint16{dns.EDNS0COOKIE},
+		},
+		"subnet_only": {
+			edns:    true,
+			options: []dns.EDNS0{subnet()},
+		},
+		"subnet_and_other_option": {
+			edns:    true,
+			options: []dns.EDNS0{subnet(), cookie()},
+			codes:   []uint16{dns.EDNS0COOKIE},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			middleware := &ecsMiddleware{}
+			var upstreamRequest *dns.Msg
+			next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				upstreamRequest = request.Copy()
+				_ = w.WriteMsg(new(dns.Msg).SetReply(request))
+			})
+
+			request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			if testCase.edns {
+				request.SetEdns0(1232, false) //nolint:gomnd
+				opt := request.IsEdns0()
+				opt.Option = testCase.options
+			}
+			recorder := &responseRecorder{}
+			middleware.Wrap(next).ServeDNS(recorder, request)
+
+			require.NotNil(t, recorder.response)
+			require.NotNil(t, upstreamRequest)
+			opt := upstreamRequest.IsEdns0()
+			if !testCase.edns {
+				assert.Nil(t, opt)
+				return
+			}
+			require.NotNil(t, opt)
+			var codes []uint16
+			for _, option := range opt.Option {
+				codes = append(codes, option.Option())
+			}
+			assert.Equal(t, testCase.codes, codes)
+		})
+	}
+}
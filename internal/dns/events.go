@@ -0,0 +1,44 @@
+package dns
+
+import (
+	"sync"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// events records the last DNS event, such as the DNS
+// degrading to plaintext, reported in the DNS status.
+type events struct {
+	mutex sync.Mutex
+	last  *models.DNSEvent
+}
+
+func (l *Loop) publish(eventType models.DNSEventType) {
+	event := models.DNSEvent{Type: eventType, Time: l.timeNow()}
+	switch eventType {
+	case models.DNSEventDegraded:
+		l.logEvent(l.logger.Warn, eventRecord{
+			Event:   string(eventType),
+			Message: "⚠️ DNS degraded to plaintext, queries are no longer encrypted",
+		})
+	case models.DNSEventRestored:
+		l.logEvent(l.logger.Info, eventRecord{
+			Event:   string(eventType),
+			Message: "DNS restored to encrypted DNS over TLS",
+		})
+	}
+
+	l.events.mutex.Lock()
+	defer l.events.mutex.Unlock()
+	l.events.last = &event
+}
+
+func (l *Loop) getLastEvent() (event *models.DNSEvent) {
+	l.events.mutex.Lock()
+	defer l.events.mutex.Unlock()
+	if l.events.last == nil {
+		return nil
+	}
+	eventCopy := *l.events.last
+	return &eventCopy
+}
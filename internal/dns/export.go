@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+)
+
+// exportBlockLists writes the blocked hostnames, IP addresses and
+// IP prefixes given to the file path given, one per line.
+func exportBlockLists(path string, hostnames []string,
+	ips []netip.Addr, ipPrefixes []netip.Prefix) (err error) {
+	return writeFileAtomically(path, func(w io.Writer) (err error) {
+		buffered := bufio.NewWriter(w)
+		for _, hostname := range hostnames {
+			_, _ = buffered.WriteString(hostname + "\n")
+		}
+		for _, ip := range ips {
+			_, _ = buffered.WriteString(ip.String() + "\n")
+		}
+		for _, ipPrefix := range ipPrefixes {
+			_, _ = buffered.WriteString(ipPrefix.String() + "\n")
+		}
+		return buffered.Flush()
+	})
+}
+
+// writeFileAtomically writes to the file path given through a temporary
+// file renamed once written, so the file is never partially written, even
+// if the program is killed while writing. Temporary files left over by a
+// previous interrupted write are removed.
+func writeFileAtomically(path string, write func(w io.Writer) error) (err error) {
+	const dirPerms os.FileMode = 0o700
+	err = os.MkdirAll(filepath.Dir(path), dirPerms)
+	if err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	tempPattern := filepath.Base(path) + ".*.tmp"
+	leftOvers, err := filepath.Glob(filepath.Join(filepath.Dir(path), tempPattern))
+	if err != nil {
+		return fmt.Errorf("finding left over temporary files: %w", err)
+	}
+	for _, leftOver := range leftOvers {
+		_ = os.Remove(leftOver)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), tempPattern)
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer func() {
+		// the temporary file no longer exists if it was renamed
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	err = write(tempFile)
+	if err != nil {
+		_ = tempFile.Close()
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+
+	// Flush the file to disk before renaming it, so the renamed
+	// file is not empty if the system crashes right after.
+	err = tempFile.Sync()
+	if err != nil {
+		_ = tempFile.Close()
+		return fmt.Errorf("syncing temporary file: %w", err)
+	}
+
+	err = tempFile.Close()
+	if err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	err = os.Rename(tempFile.Name(), path)
+	if err != nil {
+		return fmt.Errorf("moving temporary file: %w", err)
+	}
+	return nil
+}
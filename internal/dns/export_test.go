@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_writeFileAtomically(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		leftOver   bool
+		write      func(w io.Writer) error
+		content    string
+		errWrapped error
+		errMessage string
+	}{
+		"success": {
+			write: func(w io.Writer) error {
+				_, err := w.Write([]byte("new"))
+				return err
+			},
+			content: "new",
+		},
+		"partial_write": {
+			write: func(w io.Writer) error {
+				_, _ = w.Write([]byte("ne"))
+				return errTest
+			},
+			content:    "previous",
+			errWrapped: errTest,
+			errMessage: "writing temporary file: test error",
+		},
+		"left_over_temporary_file": {
+			leftOver: true,
+			write: func(w io.Writer) error {
+				_, err := w.Write([]byte("new"))
+				return err
+			},
+			content: "new",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dirPath := t.TempDir()
+			path := filepath.Join(dirPath, "file.txt")
+			const filePerms os.FileMode = 0o600
+			err := os.WriteFile(path, []byte("previous"), filePerms)
+			require.NoError(t, err)
+			if testCase.leftOver {
+				err = os.WriteFile(path+".123.tmp", []byte("partial"), filePerms)
+				require.NoError(t, err)
+			}
+
+			err = writeFileAtomically(path, testCase.write)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.content, string(content))
+			entries, err := os.ReadDir(dirPath)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/dns/v2/pkg/middlewares/filter/mapfilter"
+)
+
+// filterMiddleware blocks requests for blocked hostnames and
+// responses containing blocked IP addresses, answering them with
+// the block response configured.
+type filterMiddleware struct {
+	filter *mapfilter.Filter
+	// blockResponse is "refused", "nxdomain" or "null".
+	blockResponse string
+}
+
+func (m *filterMiddleware) String() string {
+	return "filter"
+}
+
+func (m *filterMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if m.filter.FilterRequest(request) {
+			_ = w.WriteMsg(m.makeBlockedResponse(request))
+			return
+		}
+
+		recorder := &responseRecorder{}
+		next.ServeDNS(recorder, request)
+		response := recorder.response
+		if response == nil {
+			return
+		}
+
+		if m.filter.FilterResponse(response) {
+			response = m.makeBlockedResponse(request)
+		}
+		_ = w.WriteMsg(response)
+	})
+}
+
+func (m *filterMiddleware) Stop() (err error) {
+	return nil
+}
+
+func (m *filterMiddleware) makeBlockedResponse(request *dns.Msg) (response *dns.Msg) {
+	switch m.blockResponse {
+	case "nxdomain":
+		return new(dns.Msg).SetRcode(request, dns.RcodeNameError)
+	case "null":
+		return makeNullResponse(request)
+	default:
+		return new(dns.Msg).SetRcode(request, dns.RcodeRefused)
+	}
+}
+
+// makeNullResponse answers A and AAAA questions with the unspecified
+// address 0.0.0.0 or :: respectively, and other questions with an
+// empty answer.
+func makeNullResponse(request *dns.Msg) (response *dns.Msg) {
+	response = new(dns.Msg).SetReply(request)
+	const ttl = 60
+	for _, question := range request.Question {
+		header := dns.RR_Header{
+			Name:   question.Name,
+			Rrtype: question.Qtype,
+			Class:  question.Qclass,
+			Ttl:    ttl,
+		}
+		switch question.Qtype {
+		case dns.TypeA:
+			response.Answer = append(response.Answer,
+				&dns.A{Hdr: header, A: net.IPv4zero})
+		case dns.TypeAAAA:
+			response.Answer = append(response.Answer,
+				&dns.AAAA{Hdr: header, AAAA: net.IPv6zero})
+		}
+	}
+	return response
+}
@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// forwardMiddleware forwards queries matching a domain suffix
+// to the upstream resolver configured for this suffix, and passes
+// other queries to the next handler.
+type forwardMiddleware struct {
+	zones  []forwardZone
+	logger Logger
+}
+
+type forwardZone struct {
+	// suffix is the lowercase fully qualified domain suffix.
+	suffix    string
+	client    *dns.Client
+	addresses []string
+}
+
+func newForwardMiddleware(zoneSettings []settings.DNSForwardZone,
+	ipv6 bool, logger Logger) (middleware *forwardMiddleware, err error) {
+	const timeout = 5 * time.Second
+	zones := make([]forwardZone, len(zoneSettings))
+	for i, zoneSetting := range zoneSettings {
+		dotProvider, plaintextAddress, err := zoneSetting.ParseUpstream()
+		if err != nil {
+			return nil, err
+		}
+
+		zone := forwardZone{
+			suffix: dns.Fqdn(strings.ToLower(zoneSetting.Suffix)),
+		}
+		if dotProvider == nil {
+			zone.client = &dns.Client{Net: "udp", Timeout: timeout}
+			zone.addresses = []string{plaintextAddress.String()}
+		} else {
+			zone.client = &dns.Client{
+				Net:     "tcp-tls",
+				Timeout: timeout,
+				TLSConfig: &tls.Config{
+					MinVersion: tls.VersionTLS12,
+					ServerName: dotProvider.DoT.Name,
+				},
+			}
+			addrPorts := dotProvider.DoT.IPv4
+			if ipv6 && len(dotProvider.DoT.IPv6) > 0 {
+				addrPorts = dotProvider.DoT.IPv6
+			}
+			zone.addresses = addrPortsToStrings(addrPorts)
+		}
+		zones[i] = zone
+	}
+
+	// Sort zones by decreasing suffix length so the
+	// most specific suffix matches first.
+	sort.SliceStable(zones, func(i, j int) bool {
+		return len(zones[i].suffix) > len(zones[j].suffix)
+	})
+
+	return &forwardMiddleware{
+		zones:  zones,
+		logger: logger,
+	}, nil
+}
+
+func addrPortsToStrings(addrPorts []netip.AddrPort) (addresses []string) {
+	addresses = make([]string, len(addrPorts))
+	for i, addrPort := range addrPorts {
+		addresses[i] = addrPort.String()
+	}
+	return addresses
+}
+
+func (m *forwardMiddleware) String() string {
+	return "forward zones"
+}
+
+func (m *forwardMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		zone, ok := m.match(request)
+		if !ok {
+			next.ServeDNS(w, request)
+			return
+		}
+
+		response, err := zone.exchange(request)
+		if err != nil {
+			m.logger.Warn(fmt.Sprintf("forwarding query for zone %s: %s", zone.suffix, err))
+			_ = w.WriteMsg(new(dns.Msg).SetRcode(request, dns.RcodeServerFailure))
+			return
+		}
+
+		// Keep the upstream rcode and flags, since SetReply
+		// would reset the rcode of negative responses.
+		response.Id = request.Id
+		_ = w.WriteMsg(response)
+	})
+}
+
+func (m *forwardMiddleware) Stop() (err error) {
+	return nil
+}
+
+func (m *forwardMiddleware) match(request *dns.Msg) (zone forwardZone, ok bool) {
+	if len(request.Question) == 0 {
+		return zone, false
+	}
+
+	name := strings.ToLower(dns.Fqdn(request.Question[0].Name))
+	for _, zone := range m.zones {
+		if name == zone.suffix || strings.HasSuffix(name, "."+zone.suffix) {
+			return zone, true
+		}
+	}
+	return zone, false
+}
+
+// exchange sends the request to each address of the zone
+// until one of them answers.
+func (z forwardZone) exchange(request *dns.Msg) (response *dns.Msg, err error) {
+	for _, address := range z.addresses {
+		response, _, err = z.client.Exchange(request, address)
+		if err == nil {
+			return response, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_forwardMiddleware(t *testing.T) {
+	t.Parallel()
+
+	forwardedIP := net.IPv4(3, 3, 3, 3)
+	port := startUDPServer(t, dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if dns.IsSubDomain("missing.lan.", request.Question[0].Name) {
+			_ = w.WriteMsg(new(dns.Msg).SetRcode(request, dns.RcodeNameError))
+			return
+		}
+		_ = w.WriteMsg(newAnswer(request, forwardedIP, 60)) //nolint:gomnd
+	}))
+	upstream := "127.0.0.1:" + strconv.Itoa(int(port))
+	// Nothing listens on port 1, so queries to it fail.
+	const failingUpstream = "127.0.0.1:1"
+	zoneSettings := []settings.DNSForwardZone{
+		{Suffix: "internal.lan", Upstream: upstream},
+		{Suffix: "broken.lan", Upstream: failingUpstream},
+		{Suffix: "sub.broken.lan", Upstream: upstream},
+		{Suffix: "missing.lan", Upstream: upstream},
+	}
+
+	testCases := map[string]struct {
+		name      string
+		forwarded bool
+		rcode     int
+		warned    bool
+	}{
+		"zone_suffix": {
+			name:      "internal.lan.",
+			forwarded: true,
+		},
+		"zone_subdomain": {
+			name:      "host.internal.lan.",
+			forwarded: true,
+		},
+		"zone_subdomain_uppercase": {
+			name:      "HOST.Internal.LAN.",
+			forwarded: true,
+		},
+		"most_specific_zone": {
+			name:      "host.sub.broken.lan.",
+			forwarded: true,
+		},
+		"upstream_nxdomain": {
+			name:  "host.missing.lan.",
+			rcode: dns.RcodeNameError,
+		},
+		"upstream_failure": {
+			name:   "host.broken.lan.",
+			rcode:  dns.RcodeServerFailure,
+			warned: true,
+		},
+		"not_label_boundary": {
+			name: "notinternal.lan.",
+		},
+		"no_zone": {
+			name: "example.com.",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			logger := &warnLogger{}
+			const ipv6 = false
+			middleware, err := newForwardMiddleware(zoneSettings, ipv6, logger)
+			require.NoError(t, err)
+			nextCalled := false
+			next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				nextCalled = true
+				_ = w.WriteMsg(new(dns.Msg).SetRcode(request, dns.RcodeNameError))
+			})
+
+			request := new(dns.Msg).SetQuestion(testCase.name, dns.TypeA)
+			recorder := &responseRecorder{}
+			middleware.Wrap(next).ServeDNS(recorder, request)
+
+			response := recorder.response
+			require.NotNil(t, response)
+			assert.Equal(t, request.Id, response.Id)
+			switch {
+			case testCase.forwarded:
+				assert.False(t, nextCalled)
+				require.Len(t, response.Answer, 1)
+				answer := response.Answer[0].(*dns.A) //nolint:forcetypeassert
+				assert.True(t, forwardedIP.Equal(answer.A))
+			case testCase.rcode != dns.RcodeSuccess:
+				assert.False(t, nextCalled)
+				assert.Equal(t, testCase.rcode, response.Rcode)
+			default:
+				assert.True(t, nextCalled)
+				assert.Equal(t, dns.RcodeNameError, response.Rcode)
+			}
+			assert.Equal(t, testCase.warned, len(logger.warnings) == 1)
+		})
+	}
+}
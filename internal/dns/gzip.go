@@ -0,0 +1,72 @@
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// newGzipClient returns a copy of the HTTP client given which
+// decompresses gzip compressed block lists, detected from their
+// gzip magic bytes. The HTTP transport only decompresses responses
+// with a gzip Content-Encoding header for requests it added the
+// Accept-Encoding header to, so block lists served as .gz files
+// would otherwise be parsed compressed.
+func newGzipClient(client *http.Client) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	gzipClient := *client
+	gzipClient.Transport = &gzipTransport{
+		next: transport,
+	}
+	return &gzipClient
+}
+
+type gzipTransport struct {
+	next http.RoundTripper
+}
+
+var gzipMagic = []byte{0x1f, 0x8b} //nolint:gochecknoglobals
+
+func (t *gzipTransport) RoundTrip(request *http.Request) (
+	response *http.Response, err error) {
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(response.Body)
+	magic, err := reader.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(magic, gzipMagic) {
+		// empty, too short or not gzip compressed
+		response.Body = &gzipBody{Reader: reader, body: response.Body}
+		return response, nil
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("decompressing %s: %w", request.URL, err)
+	}
+	response.Body = &gzipBody{Reader: gzipReader, body: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return response, nil
+}
+
+// gzipBody reads from its reader and closes the original body.
+type gzipBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
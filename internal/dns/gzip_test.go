@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_gzipTransport(t *testing.T) {
+	t.Parallel()
+
+	plain, err := os.ReadFile(filepath.Join("testdata", "blocklist.txt"))
+	require.NoError(t, err)
+	compressed, err := os.ReadFile(filepath.Join("testdata", "blocklist.txt.gz"))
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		body            []byte
+		contentEncoding string
+		content         []byte
+	}{
+		"plain": {
+			body:    plain,
+			content: plain,
+		},
+		"gzip_file": {
+			body:    compressed,
+			content: plain,
+		},
+		"gzip_content_encoding": {
+			body:            compressed,
+			contentEncoding: "gzip",
+			content:         plain,
+		},
+		"empty": {
+			body:    []byte{},
+			content: []byte{},
+		},
+		"single_byte": {
+			body:    []byte{0x1f},
+			content: []byte{0x1f},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, _ *http.Request) {
+					if testCase.contentEncoding != "" {
+						w.Header().Set("Content-Encoding", testCase.contentEncoding)
+					}
+					_, _ = w.Write(testCase.body)
+				}))
+			t.Cleanup(server.Close)
+
+			client := newGzipClient(server.Client())
+
+			response, err := client.Get(server.URL) //nolint:noctx
+			require.NoError(t, err)
+			content, err := io.ReadAll(response.Body)
+			require.NoError(t, err)
+			err = response.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.content, content)
+		})
+	}
+}
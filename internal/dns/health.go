@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/gluetun/internal/constants"
+)
+
+var (
+	errDoTNotRunning     = errors.New("DNS over TLS server is not running")
+	errPlaintextFallback = errors.New("DNS queries fall back on plaintext DNS")
+	errDoTQueryFailed    = errors.New("DNS over TLS server query failed")
+	errResolvConfNotSet  = errors.New("resolv.conf nameserver is not set")
+)
+
+// CheckDoT checks DNS queries go through the DNS over TLS server, and not
+// just that hostnames resolve, which would also succeed on the plaintext
+// DNS fallback. It returns nil if DNS over TLS is disabled, or if the
+// container nameservers are kept, since plaintext DNS is then expected.
+// It returns an error if the nameserver could not be written to
+// resolv.conf, since the system then does not use the DNS server.
+func (l *Loop) CheckDoT(ctx context.Context) (err error) {
+	settings := l.GetSettings()
+	if *settings.KeepNameserver {
+		return nil
+	}
+
+	l.detailsMu.RLock()
+	resolvConfErr := l.resolvConfErr
+	l.detailsMu.RUnlock()
+	if resolvConfErr != "" {
+		return fmt.Errorf("%w: %s", errResolvConfNotSet, resolvConfErr)
+	}
+
+	if !*settings.DoT.Enabled {
+		return nil
+	}
+
+	status := l.GetStatus()
+	if status != constants.Running && status != constants.Paused {
+		return fmt.Errorf("%w: status is %s", errDoTNotRunning, status)
+	}
+
+	l.detailsMu.RLock()
+	fallback := l.fallback
+	l.detailsMu.RUnlock()
+	if fallback {
+		return errPlaintextFallback
+	}
+
+	// Query the root name servers which are never filtered,
+	// directly to the DNS over TLS server.
+	request := new(dns.Msg).SetQuestion(".", dns.TypeNS)
+	client := &dns.Client{}
+	address := netip.AddrPortFrom(settings.ServerAddress, settings.ListeningPort()).String()
+	response, _, err := client.ExchangeContext(ctx, request, address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errDoTQueryFailed, err)
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("%w: response code is %s", errDoTQueryFailed,
+			dns.RcodeToString[response.Rcode])
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+type CmdRunner interface {
+	Run(cmd *exec.Cmd) (output string, err error)
+}
+
+// runPostUpdateHook runs the post update hook command in the background,
+// if it is set, with the number of entries of each block list source
+// given set as environment variables. The hook failing is only logged,
+// and does not affect the DNS server.
+func (l *Loop) runPostUpdateHook(ctx context.Context, blacklist settings.DNSBlacklist,
+	sources []blockListSource) {
+	fields := strings.Fields(*blacklist.PostUpdateHook)
+	if len(fields) == 0 {
+		return
+	}
+
+	// The update context can be the context of a control server
+	// request, so do not stop the hook when it is canceled.
+	timeout := *blacklist.PostUpdateHookTimeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	cmd := exec.CommandContext(ctx, fields[0], fields[1:]...) //nolint:gosec
+	cmd.Env = append(os.Environ(), hookEnv(sources)...)
+
+	go func() {
+		defer cancel()
+		l.logger.Info("running post update hook: " + cmd.String())
+		output, err := l.cmder.Run(cmd)
+		if output != "" {
+			l.logger.Info(output)
+		}
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			l.logger.Error(fmt.Sprintf("post update hook timed out after %s", timeout))
+		case err != nil:
+			l.logger.Error("post update hook failed: " + err.Error())
+		}
+	}()
+}
+
+// hookEnv returns the environment variables set for the post update
+// hook, which are the names of the block list sources given, and the
+// number of hostnames, IP addresses and IP prefixes of each source.
+func hookEnv(sources []blockListSource) (env []string) {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.name
+		prefix := "DNS_BLOCKLISTS_" + strings.ToUpper(source.name) + "_"
+		env = append(env,
+			fmt.Sprintf("%sHOSTNAMES=%d", prefix, len(source.result.BlockedHostnames)),
+			fmt.Sprintf("%sIPS=%d", prefix, len(source.result.BlockedIPs)),
+			fmt.Sprintf("%sIP_PREFIXES=%d", prefix, len(source.result.BlockedIPPrefixes)),
+		)
+	}
+	return append(env, "DNS_BLOCKLISTS_SOURCES="+strings.Join(names, ","))
+}
@@ -0,0 +1,127 @@
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/qdm12/dns/v2/pkg/blockbuilder"
+)
+
+// readLocalBlockLists reads all the .txt files in the directory
+// given, where each line is a hostname, an IP address or an IP prefix.
+// Lines starting with # are ignored. If the directory does not exist,
+// an empty result is returned.
+func (l *Loop) readLocalBlockLists(dirPath string) (
+	result blockbuilder.Result, err error) {
+	filePaths, err := filepath.Glob(filepath.Join(dirPath, "*.txt"))
+	if err != nil {
+		return result, fmt.Errorf("listing local block lists: %w", err)
+	}
+
+	for _, filePath := range filePaths {
+		fileResult, err := readLocalBlockList(filePath)
+		if err != nil {
+			return result, err
+		}
+		l.logger.Info(fmt.Sprintf("read %d hostnames, %d IP addresses and %d IP prefixes "+
+			"from local block list %s", len(fileResult.BlockedHostnames),
+			len(fileResult.BlockedIPs), len(fileResult.BlockedIPPrefixes), filePath))
+		result.BlockedHostnames = append(result.BlockedHostnames, fileResult.BlockedHostnames...)
+		result.BlockedIPs = append(result.BlockedIPs, fileResult.BlockedIPs...)
+		result.BlockedIPPrefixes = append(result.BlockedIPPrefixes, fileResult.BlockedIPPrefixes...)
+	}
+
+	return result, nil
+}
+
+func readLocalBlockList(filePath string) (result blockbuilder.Result, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return result, fmt.Errorf("opening local block list: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ip, err := netip.ParseAddr(line)
+		if err == nil {
+			result.BlockedIPs = append(result.BlockedIPs, ip)
+			continue
+		}
+
+		ipPrefix, err := netip.ParsePrefix(line)
+		if err == nil {
+			result.BlockedIPPrefixes = append(result.BlockedIPPrefixes, ipPrefix)
+			continue
+		}
+
+		result.BlockedHostnames = append(result.BlockedHostnames, line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		_ = file.Close()
+		return result, fmt.Errorf("reading local block list %s: %w", filePath, err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return result, fmt.Errorf("closing local block list: %w", err)
+	}
+
+	return result, nil
+}
+
+// mergeBlockLists merges the results of the sources given,
+// removing duplicates.
+func mergeBlockLists(sources []blockListSource) (merged blockbuilder.Result) {
+	for _, source := range sources {
+		merged.BlockedHostnames = mergeUnique(merged.BlockedHostnames, source.result.BlockedHostnames)
+		merged.BlockedIPs = mergeUnique(merged.BlockedIPs, source.result.BlockedIPs)
+		merged.BlockedIPPrefixes = mergeUnique(merged.BlockedIPPrefixes, source.result.BlockedIPPrefixes)
+	}
+
+	// Block lists are built concurrently, so sort the
+	// merged results to have a deterministic ordering.
+	sort.Strings(merged.BlockedHostnames)
+	sort.Slice(merged.BlockedIPs, func(i, j int) bool {
+		return merged.BlockedIPs[i].Less(merged.BlockedIPs[j])
+	})
+	sort.Slice(merged.BlockedIPPrefixes, func(i, j int) bool {
+		return merged.BlockedIPPrefixes[i].String() < merged.BlockedIPPrefixes[j].String()
+	})
+	return merged
+}
+
+func mergeUnique[T comparable](a, b []T) (merged []T) {
+	seen := make(map[T]struct{}, len(a)+len(b))
+	merged = make([]T, 0, len(a)+len(b))
+	for _, slice := range [][]T{a, b} {
+		for _, element := range slice {
+			if _, ok := seen[element]; ok {
+				continue
+			}
+			seen[element] = struct{}{}
+			merged = append(merged, element)
+		}
+	}
+	return merged
+}
+
+// countUnique returns the number of unique elements of the slice given.
+func countUnique[T comparable](slice []T) (count int) {
+	seen := make(map[T]struct{}, len(slice))
+	for _, element := range slice {
+		seen[element] = struct{}{}
+	}
+	return len(seen)
+}
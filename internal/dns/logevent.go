@@ -0,0 +1,36 @@
+package dns
+
+import (
+	"encoding/json"
+)
+
+// eventRecord is a DNS event logged as a JSON object with the JSON
+// log format, or as its message with the text log format.
+type eventRecord struct {
+	Event      string   `json:"event"`
+	Message    string   `json:"message"`
+	Error      string   `json:"error,omitempty"`
+	Backoff    string   `json:"backoff,omitempty"`
+	Providers  []string `json:"providers,omitempty"`
+	Address    string   `json:"address,omitempty"`
+	Hostnames  *int     `json:"hostnames,omitempty"`
+	IPs        *int     `json:"ips,omitempty"`
+	IPPrefixes *int     `json:"ip_prefixes,omitempty"`
+}
+
+// logEvent logs the event record given with the log function
+// given, such as the logger Info method.
+func (l *Loop) logEvent(logf func(s string), record eventRecord) {
+	if !l.jsonLogs {
+		logf(record.Message)
+		return
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		// The record only contains JSON encodable fields,
+		// so an error here is a programming error.
+		panic(err)
+	}
+	logf(string(data))
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// logRecorder is a logger keeping the last warning and error
+// lines in a bounded ring buffer, together with the DNS server
+// run generation they were logged in, before passing all lines
+// to the logger it wraps.
+type logRecorder struct {
+	Logger
+	timeNow func() time.Time
+
+	mutex      sync.Mutex
+	lines      []models.DNSLogLine
+	next       int
+	full       bool
+	generation uint
+}
+
+func newLogRecorder(logger Logger, capacity int) *logRecorder {
+	return &logRecorder{
+		Logger:  logger,
+		timeNow: time.Now,
+		lines:   make([]models.DNSLogLine, capacity),
+	}
+}
+
+func (r *logRecorder) Warn(s string) {
+	r.record("warn", s)
+	r.Logger.Warn(s)
+}
+
+func (r *logRecorder) Error(s string) {
+	r.record("error", s)
+	r.Logger.Error(s)
+}
+
+func (r *logRecorder) record(level, message string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lines[r.next] = models.DNSLogLine{
+		Time:       r.timeNow(),
+		Level:      level,
+		Message:    message,
+		Generation: r.generation,
+	}
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// newGeneration increments the run generation
+// lines logged from now on are marked with.
+func (r *logRecorder) newGeneration() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.generation++
+}
+
+// GetLogs returns the last warning and error lines logged by the
+// DNS loop, from oldest to newest, and the current run generation.
+func (l *Loop) GetLogs() (logs models.DNSLogs) {
+	r := l.logRecorder
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	logs.Generation = r.generation
+	if r.full {
+		logs.Lines = make([]models.DNSLogLine, 0, len(r.lines))
+		logs.Lines = append(logs.Lines, r.lines[r.next:]...)
+	} else {
+		logs.Lines = make([]models.DNSLogLine, 0, r.next)
+	}
+	logs.Lines = append(logs.Lines, r.lines[:r.next]...)
+	return logs
+}
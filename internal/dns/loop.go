@@ -4,10 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qdm12/dns/v2/pkg/blockbuilder"
 	"github.com/qdm12/dns/v2/pkg/dot"
 	"github.com/qdm12/dns/v2/pkg/middlewares/filter/mapfilter"
+	"github.com/qdm12/dns/v2/pkg/provider"
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/dns/state"
@@ -19,25 +25,125 @@ type Loop struct {
 	statusManager *loopstate.State
 	state         *state.State
 	server        *dot.Server
+	drainer       *drainMiddleware
+	relay         *socksRelay
 	filter        *mapfilter.Filter
-	resolvConf    string
-	client        *http.Client
-	logger        Logger
-	userTrigger   bool
-	start         <-chan struct{}
-	running       chan<- models.LoopStatus
-	stop          <-chan struct{}
-	stopped       chan<- struct{}
-	updateTicker  <-chan struct{}
-	backoffTime   time.Duration
-	timeNow       func() time.Time
-	timeSince     func(time.Time) time.Duration
+	metrics       *metrics
+	queryLogger   *queryLogger
+	cacheTracker  *cacheTracker
+	downloaded    blockbuilder.Result
+	sources       []blockListSource
+	blockListsMu  sync.RWMutex
+	// sourceErrors maps the name of the block list sources which
+	// failed to download at the last update to their error, and
+	// is guarded by blockListsMu.
+	sourceErrors map[string]sourceError
+	// updateMu prevents concurrent block lists updates.
+	updateMu   sync.Mutex
+	resolvConf string
+	// originalNameservers are the nameservers of resolv.conf
+	// when the loop is created, before it is overwritten.
+	originalNameservers []netip.Addr
+	client              *http.Client
+	cmder               CmdRunner
+	ipv6Supported       bool
+	logger              Logger
+	logRecorder         *logRecorder
+	jsonLogs            bool
+	userTrigger         bool
+	start               <-chan struct{}
+	running             chan<- models.LoopStatus
+	stop                <-chan struct{}
+	stopped             chan<- struct{}
+	updateTicker        <-chan struct{}
+	fallback            bool
+	// backoffTime is the duration to wait before the next restart,
+	// and is guarded by detailsMu since it is read by the control server.
+	backoffTime time.Duration
+	// paused is true if the periodic block lists updates are
+	// paused, and is kept if the DNS server restarts.
+	paused atomic.Bool
+	// pauseMu serializes pausing and resuming.
+	pauseMu sync.Mutex
+	// userStopped is true if the stopped status was applied through
+	// the control server with status persistence enabled, so the
+	// DNS server is only started again through the control server.
+	userStopped atomic.Bool
+	// resumeTicker signals the restart ticker to
+	// compute its next tick when resuming.
+	resumeTicker chan struct{}
+	// runningSince is the time the server last became ready,
+	// and is the zero time if it failed since. It is guarded
+	// by detailsMu.
+	runningSince time.Time
+	// permanentErr is the message of the last setup error
+	// which cannot be fixed by retrying, and is empty if
+	// the server setup succeeded since.
+	permanentErr string
+	// resolvConfErr is the message of the last resolv.conf
+	// write error, and is empty if the last write succeeded.
+	resolvConfErr string
+	// resolvConfReadOnly is true if resolv.conf was found to
+	// be read-only, so the error is only logged once.
+	resolvConfReadOnly bool
+	// nextUpdate is the time of the next scheduled block lists
+	// update, and is the zero time if none is scheduled.
+	nextUpdate time.Time
+	// encrypted is true if the DNS over TLS server is
+	// used, and false if plaintext DNS or the existing
+	// nameserver is used.
+	encrypted bool
+	// internalResolver is the address last set
+	// for the Go program resolver.
+	internalResolver netip.AddrPort
+	lastUpdate       time.Time
+	blockListCounts  models.DNSBlockListCounts
+	detailsMu        sync.RWMutex
+	events           events
+	// rpzSerial is the serial number of the last Response
+	// Policy Zone written, and is guarded by detailsMu.
+	rpzSerial uint32
+	// dialTracker records the upstream addresses dialed by
+	// the DNS over TLS server.
+	dialTracker *dialTracker
+	// upstreamProviders are the DNS over TLS providers in use,
+	// and upstreamsIPv6 is true if their IPv6 addresses are used
+	// as well. They are guarded by detailsMu.
+	upstreamProviders []provider.Provider
+	upstreamsIPv6     bool
+	// plaintextAddress is the plaintext DNS address in use, and is
+	// the invalid address if plaintext DNS is not in use. It is
+	// guarded by detailsMu.
+	plaintextAddress netip.AddrPort
+	// caches are the caches of the DNS over TLS server in
+	// use, and is nil if it is not running. It is guarded
+	// by detailsMu.
+	caches *serverCaches
+	// swapper hands queries over to the backend server once
+	// the DNS over TLS providers are switched without restarting.
+	swapper *swapMiddleware
+	backend *swapBackend
+	swapMu  sync.Mutex
+	// killSwitch is true if the firewall only allows traffic
+	// through the VPN tunnel, so traffic to the Internet waits
+	// for the VPN tunnel to be up.
+	killSwitch bool
+	// tunnelUp is closed when the VPN tunnel is up.
+	tunnelUp        chan struct{}
+	tunnelInterface string
+	tunnelMu        sync.Mutex
+	timeNow         func() time.Time
+	timeSince       func(time.Time) time.Duration
+	// checkDNS waits for the DNS to resolve a hostname, and
+	// is injected to check the DNS after VPN reconnections.
+	checkDNS func(ctx context.Context, settings settings.DNS) error
 }
 
 const defaultBackoffTime = 10 * time.Second
 
-func NewLoop(settings settings.DNS,
-	client *http.Client, logger Logger) (loop *Loop, err error) {
+func NewLoop(settings settings.DNS, client *http.Client, cmder CmdRunner,
+	ipv6Supported, killSwitch bool, logger Logger, jsonLogs bool,
+	registry prometheus.Registerer) (loop *Loop, err error) {
 	start := make(chan struct{})
 	running := make(chan models.LoopStatus)
 	stop := make(chan struct{})
@@ -47,40 +153,89 @@ func NewLoop(settings settings.DNS,
 	statusManager := loopstate.New(constants.Stopped, start, running, stop, stopped)
 	state := state.New(statusManager, settings, updateTicker)
 
-	filter, err := mapfilter.New(mapfilter.Settings{})
+	metrics, err := newMetrics(registry)
+	if err != nil {
+		return nil, fmt.Errorf("creating metrics: %w", err)
+	}
+
+	filter, err := mapfilter.New(mapfilter.Settings{
+		Metrics: metrics,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("creating map filter: %w", err)
 	}
 
-	return &Loop{
-		statusManager: statusManager,
-		state:         state,
-		server:        nil,
-		filter:        filter,
-		resolvConf:    "/etc/resolv.conf",
-		client:        client,
-		logger:        logger,
-		userTrigger:   true,
-		start:         start,
-		running:       running,
-		stop:          stop,
-		stopped:       stopped,
-		updateTicker:  updateTicker,
-		backoffTime:   defaultBackoffTime,
-		timeNow:       time.Now,
-		timeSince:     time.Since,
-	}, nil
+	const logLinesCapacity = 100
+	logRecorder := newLogRecorder(logger, logLinesCapacity)
+	logger = logRecorder
+
+	// Read the original nameservers before resolv.conf is
+	// overwritten, to resolve the DNS bypass domains with them.
+	const resolvConf = "/etc/resolv.conf"
+	originalNameservers, err := readNameservers(resolvConf)
+	if err != nil {
+		logger.Warn(err.Error())
+	}
+
+	queryLogger := &queryLogger{logger: logger}
+	queryLogger.enabled.Store(*settings.DoT.QueryLog)
+
+	loop = &Loop{
+		statusManager:       statusManager,
+		state:               state,
+		server:              nil,
+		filter:              filter,
+		metrics:             metrics,
+		queryLogger:         queryLogger,
+		cacheTracker:        newCacheTracker(),
+		dialTracker:         newDialTracker(logger),
+		resolvConf:          resolvConf,
+		originalNameservers: originalNameservers,
+		client:              client,
+		cmder:               cmder,
+		ipv6Supported:       ipv6Supported,
+		logger:              logger,
+		logRecorder:         logRecorder,
+		jsonLogs:            jsonLogs,
+		userTrigger:         true,
+		start:               start,
+		running:             running,
+		stop:                stop,
+		stopped:             stopped,
+		updateTicker:        updateTicker,
+		resumeTicker:        make(chan struct{}),
+		backoffTime:         defaultBackoffTime,
+		killSwitch:          killSwitch,
+		tunnelUp:            make(chan struct{}),
+		timeNow:             time.Now,
+		timeSince:           time.Since,
+		checkDNS:            waitForDNS,
+	}
+	loop.restoreStatus(*settings.StatusPath)
+	return loop, nil
 }
 
 func (l *Loop) logAndWait(ctx context.Context, err error) {
-	if err != nil {
+	if err != nil && !l.jsonLogs {
 		l.logger.Warn(err.Error())
 	}
-	l.logger.Info("attempting restart in " + l.backoffTime.String())
-	timer := time.NewTimer(l.backoffTime)
-	l.backoffTime *= 2
+	backoffTime := l.nextBackoffTime()
+	l.metrics.setBackoff(backoffTime)
+	record := eventRecord{
+		Event:   "crashed",
+		Message: "attempting restart in " + backoffTime.String(),
+		Backoff: backoffTime.String(),
+	}
+	logf := l.logger.Info
+	if err != nil {
+		record.Error = err.Error()
+		logf = l.logger.Warn
+	}
+	l.logEvent(logf, record)
+	timer := time.NewTimer(backoffTime)
 	select {
 	case <-timer.C:
+		l.metrics.restartsInc()
 	case <-ctx.Done():
 		if !timer.Stop() {
 			<-timer.C
@@ -88,6 +243,27 @@ func (l *Loop) logAndWait(ctx context.Context, err error) {
 	}
 }
 
+// nextBackoffTime returns the duration to wait before restarting
+// the DNS server, and doubles it for the next restart.
+func (l *Loop) nextBackoffTime() (backoffTime time.Duration) {
+	settings := l.GetSettings()
+	l.detailsMu.Lock()
+	defer l.detailsMu.Unlock()
+	// Only reset the backoff duration if the server was running for
+	// long enough, to slow down restarts of a flapping server.
+	if !l.runningSince.IsZero() &&
+		l.timeSince(l.runningSince) >= *settings.StableUptime {
+		l.backoffTime = defaultBackoffTime
+	}
+	l.runningSince = time.Time{}
+	backoffTime = l.backoffTime
+	l.backoffTime *= 2
+	if maxBackoffTime := *settings.MaxBackoff; l.backoffTime > maxBackoffTime {
+		l.backoffTime = maxBackoffTime
+	}
+	return backoffTime
+}
+
 func (l *Loop) signalOrSetStatus(status models.LoopStatus) {
 	if l.userTrigger {
 		l.userTrigger = false
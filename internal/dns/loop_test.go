@@ -0,0 +1,96 @@
+package dns
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/dns/state"
+	"github.com/qdm12/gluetun/internal/loopstate"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string) {}
+func (noopLogger) Info(string)  {}
+func (noopLogger) Warn(string)  {}
+func (noopLogger) Error(string) {}
+
+// Test_Loop_backoffTime_concurrent is meant to be run with the race
+// detector, to check the backoff state is safe to use from the loop
+// goroutine while the status and settings are changed concurrently.
+func Test_Loop_backoffTime_concurrent(t *testing.T) {
+	t.Parallel()
+
+	const maxBackoff = time.Minute
+	dnsSettings := settings.DNS{
+		MaxBackoff:       ptrTo(maxBackoff),
+		StableUptime:     ptrTo(time.Hour),
+		EDNSClientSubnet: ptrTo(false),
+	}
+
+	metrics, err := newMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	statusManager := loopstate.New(constants.Running, make(chan struct{}),
+		make(chan models.LoopStatus), make(chan struct{}), make(chan struct{}))
+	loop := &Loop{
+		statusManager: statusManager,
+		state:         state.New(statusManager, dnsSettings, make(chan struct{})),
+		metrics:       metrics,
+		logger:        noopLogger{},
+		backoffTime:   defaultBackoffTime,
+		timeNow:       time.Now,
+		timeSince:     time.Since,
+	}
+
+	// A canceled context makes logAndWait return without waiting.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	const iterations = 100
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() { // loop goroutine
+		defer wg.Done()
+		for range iterations {
+			loop.detailsMu.Lock()
+			loop.runningSince = loop.timeNow()
+			loop.detailsMu.Unlock()
+			loop.logAndWait(ctx, nil)
+		}
+	}()
+
+	go func() { // control server status changes
+		defer wg.Done()
+		for i := range iterations {
+			status := constants.Running
+			if i%2 == 0 {
+				status = constants.Crashed
+			}
+			loop.statusManager.SetStatus(status)
+			_ = loop.GetStatusDetail()
+		}
+	}()
+
+	go func() { // control server settings changes
+		defer wg.Done()
+		for range iterations {
+			current := loop.GetSettings()
+			loop.state.SetSettingsLive(current.Copy())
+			_ = loop.GetStatusDetail()
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, maxBackoff, loop.GetStatusDetail().BackoffTime)
+}
@@ -0,0 +1,212 @@
+package dns
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/qdm12/dns/v2/pkg/blockbuilder"
+)
+
+// newLineCheckClient returns a copy of the HTTP client given which
+// counts the malformed lines of each block list downloaded, since
+// the block builder silently drops malformed IP addresses and keeps
+// malformed hostnames.
+func newLineCheckClient(client *http.Client) (checkClient *http.Client,
+	checker *lineChecker) {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	checker = &lineChecker{
+		urlToCounts: make(map[string]*lineCounts),
+	}
+	copied := *client
+	copied.Transport = &lineCheckTransport{
+		checker: checker,
+		next:    transport,
+	}
+	return &copied, checker
+}
+
+// lineChecker counts the lines and malformed lines
+// of each block list URL downloaded.
+type lineChecker struct {
+	mutex       sync.Mutex
+	urlToCounts map[string]*lineCounts
+}
+
+type lineCounts struct {
+	total     int
+	malformed []string
+}
+
+func (c *lineChecker) add(url, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		// the block builder ignores empty lines, and comment
+		// lines are ignored for the block lists URLs, or
+		// dropped as malformed hostnames otherwise.
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	counts, ok := c.urlToCounts[url]
+	if !ok {
+		counts = &lineCounts{}
+		c.urlToCounts[url] = counts
+	}
+	counts.total++
+	if !isValidBlockListLine(line) {
+		counts.malformed = append(counts.malformed, line)
+	}
+}
+
+// blockListHostRegex matches valid hostnames, the same
+// way hostnames are validated in the settings.
+var blockListHostRegex = regexp.MustCompile(`^([a-zA-Z0-9]|[a-zA-Z0-9_][a-zA-Z0-9\-_]{0,61}[a-zA-Z0-9_])(\.([a-zA-Z0-9]|[a-zA-Z0-9_][a-zA-Z0-9\-_]{0,61}[a-zA-Z0-9]))*$`) //nolint:lll
+
+func isValidBlockListLine(line string) (valid bool) {
+	if _, err := netip.ParseAddr(line); err == nil {
+		return true
+	}
+	if _, err := netip.ParsePrefix(line); err == nil {
+		return true
+	}
+	return blockListHostRegex.MatchString(line)
+}
+
+var errBlockListMalformed = errors.New("block list has too many malformed lines")
+
+// check logs the ratio of malformed lines of each block list URL
+// downloaded for the block list source given, and the malformed lines
+// themselves if logMalformed is true. It rejects the source if the
+// malformed lines percentage of one of its URLs exceeds the maximum
+// percentage given, and otherwise drops its malformed hostnames.
+func (c *lineChecker) check(source *blockListSource, maxPercent uint,
+	logMalformed bool, logger Logger) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	urls := make([]string, 0, len(c.urlToCounts))
+	for url := range c.urlToCounts {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var rejectErrs []error
+	for _, url := range urls {
+		counts := c.urlToCounts[url]
+		if len(counts.malformed) == 0 {
+			continue
+		}
+
+		percent := 100 * float64(len(counts.malformed)) / float64(counts.total) //nolint:gomnd
+		if logMalformed {
+			logMalformedLines(logger, source.name, url, counts.malformed)
+		}
+		if percent > float64(maxPercent) {
+			rejectErrs = append(rejectErrs, fmt.Errorf("%w: %s: %d of %d lines (%.1f%%) "+
+				"are malformed, above the maximum of %d%%", errBlockListMalformed,
+				url, len(counts.malformed), counts.total, percent, maxPercent))
+			continue
+		}
+		logger.Warn(fmt.Sprintf("%s block lists: dropping %d of %d lines (%.1f%%) "+
+			"malformed from %s", source.name, len(counts.malformed), counts.total,
+			percent, url))
+	}
+
+	if len(rejectErrs) > 0 {
+		source.result = blockbuilder.Result{
+			Errors: append(source.result.Errors, rejectErrs...),
+		}
+		return
+	}
+
+	source.result.BlockedHostnames = filterMalformedHostnames(source.result.BlockedHostnames)
+}
+
+func logMalformedLines(logger Logger, sourceName, url string, malformed []string) {
+	const maxLogged = 10
+	for i, line := range malformed {
+		if i == maxLogged {
+			logger.Info(fmt.Sprintf("%s block lists: %d more malformed lines from %s",
+				sourceName, len(malformed)-maxLogged, url))
+			return
+		}
+		logger.Info(fmt.Sprintf("%s block lists: malformed line from %s: %q",
+			sourceName, url, line))
+	}
+}
+
+func filterMalformedHostnames(hostnames []string) (filtered []string) {
+	filtered = make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		hostname = strings.TrimSpace(hostname)
+		if isValidBlockListLine(hostname) {
+			filtered = append(filtered, hostname)
+		}
+	}
+	return filtered
+}
+
+type lineCheckTransport struct {
+	checker *lineChecker
+	next    http.RoundTripper
+}
+
+func (t *lineCheckTransport) RoundTrip(request *http.Request) (
+	response *http.Response, err error) {
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = &lineCheckReader{
+		ReadCloser: response.Body,
+		checker:    t.checker,
+		url:        request.URL.String(),
+	}
+	return response, nil
+}
+
+// lineCheckReader splits the content read in lines and
+// adds each line to the checker as soon as it is complete.
+type lineCheckReader struct {
+	io.ReadCloser
+	checker *lineChecker
+	url     string
+	partial []byte
+}
+
+func (r *lineCheckReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	data := p[:n]
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i == -1 {
+			break
+		}
+		r.partial = append(r.partial, data[:i]...)
+		r.checker.add(r.url, string(r.partial))
+		r.partial = r.partial[:0]
+		data = data[i+1:]
+	}
+	r.partial = append(r.partial, data...)
+	return n, err
+}
+
+func (r *lineCheckReader) Close() error {
+	if len(r.partial) > 0 {
+		r.checker.add(r.url, string(r.partial))
+		r.partial = nil
+	}
+	return r.ReadCloser.Close()
+}
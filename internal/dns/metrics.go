@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// metrics implements the filter metrics interface and records
+// DNS upstream latencies and the loop stability to Prometheus collectors.
+type metrics struct {
+	blocked           *prometheus.CounterVec
+	blockedHostnames  prometheus.Gauge
+	blockedIPs        prometheus.Gauge
+	blockedIPPrefixes prometheus.Gauge
+	countedTimestamp  prometheus.Gauge
+	upstreamLatency   prometheus.Histogram
+	restarts          prometheus.Counter
+	crashes           prometheus.Counter
+	backoff           prometheus.Gauge
+	cacheHitRatio     prometheus.Gauge
+}
+
+func newMetrics(registry prometheus.Registerer) (m *metrics, err error) {
+	const namespace, subsystem = "gluetun", "dns"
+	m = &metrics{
+		blocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "blocked_total",
+			Help:      "DNS queries and answers blocked by the filter, by kind",
+		}, []string{"kind"}),
+		blockedHostnames: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "blocked_hostnames",
+			Help:      "Number of hostnames in the filter block lists",
+		}),
+		blockedIPs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "blocked_ips",
+			Help:      "Number of IP addresses in the filter block lists",
+		}),
+		blockedIPPrefixes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "blocked_ip_prefixes",
+			Help:      "Number of IP address prefixes in the filter block lists",
+		}),
+		countedTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "blocked_counted_timestamp_seconds",
+			Help:      "Unix time the filter block lists entries were last counted",
+		}),
+		upstreamLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "upstream_latency_seconds",
+			Help:      "Duration of DNS over TLS exchanges with upstream resolvers",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		restarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "restarts_total",
+			Help:      "Restarts of the DNS server after a crash",
+		}),
+		crashes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "crashes_total",
+			Help:      "Failed setups and unexpected errors of the DNS server",
+		}),
+		backoff: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "backoff_seconds",
+			Help:      "Duration waited before the last DNS server restart",
+		}),
+		cacheHitRatio: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cache_hit_ratio",
+			Help:      "Ratio of DNS queries answered from cache over the last cache statistics period",
+		}),
+	}
+
+	collectors := []prometheus.Collector{m.blocked, m.blockedHostnames,
+		m.blockedIPs, m.blockedIPPrefixes, m.countedTimestamp, m.upstreamLatency,
+		m.restarts, m.crashes, m.backoff, m.cacheHitRatio}
+	for _, collector := range collectors {
+		err = registry.Register(collector)
+		if err != nil {
+			return nil, fmt.Errorf("registering collector: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *metrics) SetBlockedHostnames(n int)  { m.blockedHostnames.Set(float64(n)) }
+func (m *metrics) SetBlockedIPs(n int)        { m.blockedIPs.Set(float64(n)) }
+func (m *metrics) SetBlockedIPPrefixes(n int) { m.blockedIPPrefixes.Set(float64(n)) }
+
+// setBlockListCounts sets the filter block lists gauges. It is called
+// after each filter update, since the filter sets the IP prefixes gauge
+// to its number of IP addresses.
+func (m *metrics) setBlockListCounts(counts models.DNSBlockListCounts) {
+	m.blockedHostnames.Set(float64(counts.Hostnames))
+	m.blockedIPs.Set(float64(counts.IPs))
+	m.blockedIPPrefixes.Set(float64(counts.IPPrefixes))
+	m.countedTimestamp.Set(float64(counts.Time.Unix()))
+}
+
+func (m *metrics) restartsInc() { m.restarts.Inc() }
+func (m *metrics) crashesInc()  { m.crashes.Inc() }
+
+func (m *metrics) setBackoff(backoff time.Duration) {
+	m.backoff.Set(backoff.Seconds())
+}
+
+func (m *metrics) setCacheHitRatio(ratio float64) {
+	m.cacheHitRatio.Set(ratio)
+}
+
+func (m *metrics) HostnamesFilteredInc(_, _ string) {
+	m.blocked.WithLabelValues("hostname").Inc()
+}
+
+func (m *metrics) IPsFilteredInc(_ string) {
+	m.blocked.WithLabelValues("ip").Inc()
+}
+
+// latencyMiddleware records the duration of requests reaching
+// the upstream resolvers. It must be the first middleware so it
+// wraps the DoT handler directly and cache hits are not recorded.
+type latencyMiddleware struct {
+	histogram prometheus.Histogram
+}
+
+func (m *latencyMiddleware) String() string {
+	return "upstream latency metrics"
+}
+
+func (m *latencyMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		start := time.Now()
+		next.ServeDNS(w, request)
+		m.histogram.Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *latencyMiddleware) Stop() (err error) {
+	return nil
+}
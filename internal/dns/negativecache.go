@@ -0,0 +1,173 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// negativeCacheMiddleware caches NXDOMAIN and empty (NODATA) responses,
+// which the LRU cache does not store, as described in RFC 2308. Their
+// TTL is taken from the SOA record of the authority section, bounded
+// by the minimum and maximum TTL given. Responses without SOA record
+// are not cached. Blocked hostnames are answered by the filter before
+// reaching this middleware, so they are never cached and block lists
+// changes take effect immediately.
+type negativeCacheMiddleware struct {
+	maxEntries int
+	minTTL     time.Duration
+	maxTTL     time.Duration
+	timeNow    func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]negativeEntry
+}
+
+type negativeEntry struct {
+	response *dns.Msg
+	expiry   time.Time
+}
+
+func newNegativeCacheMiddleware(maxEntries int,
+	minTTL, maxTTL time.Duration) *negativeCacheMiddleware {
+	return &negativeCacheMiddleware{
+		maxEntries: maxEntries,
+		minTTL:     minTTL,
+		maxTTL:     maxTTL,
+		timeNow:    time.Now,
+		entries:    make(map[string]negativeEntry),
+	}
+}
+
+func (m *negativeCacheMiddleware) String() string {
+	return "negative cache"
+}
+
+func (m *negativeCacheMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if len(request.Question) != 1 {
+			next.ServeDNS(w, request)
+			return
+		}
+
+		key := prefetchKey(request.Question[0])
+		response := m.get(key, request)
+		if response != nil {
+			_ = w.WriteMsg(response)
+			return
+		}
+
+		recorder := &responseRecorder{}
+		next.ServeDNS(recorder, request)
+		if recorder.response == nil {
+			return
+		}
+		m.add(key, recorder.response)
+		_ = w.WriteMsg(recorder.response)
+	})
+}
+
+func (m *negativeCacheMiddleware) Stop() (err error) {
+	return nil
+}
+
+func (m *negativeCacheMiddleware) get(key string, request *dns.Msg) (response *dns.Msg) {
+	now := m.timeNow()
+
+	m.mutex.Lock()
+	entry, ok := m.entries[key]
+	if ok && !now.Before(entry.expiry) {
+		delete(m.entries, key)
+		ok = false
+	}
+	m.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+
+	response = entry.response.Copy()
+	response.Id = request.Id
+	secondsLeft := uint32(entry.expiry.Sub(now) / time.Second)
+	for _, rr := range response.Ns {
+		rr.Header().Ttl = secondsLeft
+	}
+	return response
+}
+
+func (m *negativeCacheMiddleware) add(key string, response *dns.Msg) {
+	ttl, ok := m.negativeTTL(response)
+	if !ok {
+		return
+	}
+	now := m.timeNow()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.entries) >= m.maxEntries {
+		for key, entry := range m.entries {
+			if !now.Before(entry.expiry) {
+				delete(m.entries, key)
+			}
+		}
+		if len(m.entries) >= m.maxEntries {
+			return
+		}
+	}
+	m.entries[key] = negativeEntry{
+		response: response.Copy(),
+		expiry:   now.Add(ttl),
+	}
+}
+
+// flush removes the cached responses for the domain given and its
+// subdomains, or all the cached responses if the domain is empty.
+// It returns the number of responses removed not expired yet.
+func (m *negativeCacheMiddleware) flush(domain string) (count int) {
+	now := m.timeNow()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for key, entry := range m.entries {
+		if domain != "" && !keyInDomain(key, domain) {
+			continue
+		}
+		delete(m.entries, key)
+		if now.Before(entry.expiry) {
+			count++
+		}
+	}
+	return count
+}
+
+// negativeTTL returns the bounded TTL to cache the response for,
+// and false if the response is not a negative response to cache.
+func (m *negativeCacheMiddleware) negativeTTL(response *dns.Msg) (
+	ttl time.Duration, ok bool) {
+	switch {
+	case response.Rcode == dns.RcodeNameError:
+	case response.Rcode == dns.RcodeSuccess && len(response.Answer) == 0:
+	default:
+		return 0, false
+	}
+
+	for _, rr := range response.Ns {
+		soa, isSOA := rr.(*dns.SOA)
+		if !isSOA {
+			continue
+		}
+		seconds := soa.Hdr.Ttl
+		if soa.Minttl < seconds {
+			seconds = soa.Minttl
+		}
+		ttl = time.Duration(seconds) * time.Second
+		switch {
+		case ttl < m.minTTL:
+			ttl = m.minTTL
+		case ttl > m.maxTTL:
+			ttl = m.maxTTL
+		}
+		return ttl, ttl > 0
+	}
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_negativeCacheMiddleware(t *testing.T) {
+	t.Parallel()
+
+	const minTTL = 10 * time.Second
+	const maxTTL = 2 * time.Minute
+	soa := func(ttl, minTTL uint32) []dns.RR {
+		return []dns.RR{&dns.SOA{
+			Hdr: dns.RR_Header{
+				Name:   "com.",
+				Rrtype: dns.TypeSOA,
+				Class:  dns.ClassINET,
+				Ttl:    ttl,
+			},
+			Ns:     "ns.com.",
+			Mbox:   "admin.com.",
+			Minttl: minTTL,
+		}}
+	}
+
+	testCases := map[string]struct {
+		rcode     int
+		answer    bool
+		ns        []dns.RR
+		elapsed   time.Duration
+		nextCalls int
+		nsTTL     uint32
+	}{
+		"positive_response": {
+			answer:    true,
+			ns:        soa(300, 60),
+			elapsed:   time.Second,
+			nextCalls: 2,
+		},
+		"server_failure": {
+			rcode:     dns.RcodeServerFailure,
+			ns:        soa(300, 60),
+			elapsed:   time.Second,
+			nextCalls: 2,
+		},
+		"nxdomain_without_soa": {
+			rcode:     dns.RcodeNameError,
+			elapsed:   time.Second,
+			nextCalls: 2,
+		},
+		"nxdomain_cached": {
+			rcode:     dns.RcodeNameError,
+			ns:        soa(300, 60),
+			elapsed:   20 * time.Second,
+			nextCalls: 1,
+			nsTTL:     40,
+		},
+		"nodata_cached": {
+			ns:        soa(30, 60),
+			elapsed:   20 * time.Second,
+			nextCalls: 1,
+			nsTTL:     10,
+		},
+		"nxdomain_expired": {
+			rcode:     dns.RcodeNameError,
+			ns:        soa(300, 60),
+			elapsed:   time.Minute,
+			nextCalls: 2,
+			nsTTL:     300,
+		},
+		"ttl_raised_to_minimum": {
+			rcode:     dns.RcodeNameError,
+			ns:        soa(300, 1),
+			elapsed:   5 * time.Second,
+			nextCalls: 1,
+			nsTTL:     5,
+		},
+		"ttl_lowered_to_maximum": {
+			rcode:     dns.RcodeNameError,
+			ns:        soa(3600, 3600),
+			elapsed:   maxTTL,
+			nextCalls: 2,
+			nsTTL:     3600,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			middleware := newNegativeCacheMiddleware(10, minTTL, maxTTL) //nolint:gomnd
+			now := time.Unix(0, 0)
+			middleware.timeNow = func() time.Time { return now }
+			nextCalls := 0
+			next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				nextCalls++
+				response := new(dns.Msg).SetRcode(request, testCase.rcode)
+				if testCase.answer {
+					response = newAnswer(request, net.IPv4(1, 1, 1, 1), 60) //nolint:gomnd
+				}
+				for _, rr := range testCase.ns {
+					response.Ns = append(response.Ns, dns.Copy(rr))
+				}
+				_ = w.WriteMsg(response)
+			})
+			handler := middleware.Wrap(next)
+
+			request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			handler.ServeDNS(&responseRecorder{}, request)
+			now = now.Add(testCase.elapsed)
+			request = new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			recorder := &responseRecorder{}
+			handler.ServeDNS(recorder, request)
+
+			assert.Equal(t, testCase.nextCalls, nextCalls)
+			response := recorder.response
+			require.NotNil(t, response)
+			assert.Equal(t, request.Id, response.Id)
+			assert.Equal(t, testCase.rcode, response.Rcode)
+			if testCase.nsTTL > 0 {
+				require.Len(t, response.Ns, 1)
+				assert.Equal(t, testCase.nsTTL, response.Ns[0].Header().Ttl)
+			}
+		})
+	}
+}
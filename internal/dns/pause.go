@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/constants"
+)
+
+var errPauseNotRunning = errors.New("DNS server can only be paused when running")
+
+// pause pauses the periodic block lists updates and restarts,
+// keeping the DNS server running to resolve hostnames.
+func (l *Loop) pause() (outcome string, err error) {
+	l.pauseMu.Lock()
+	defer l.pauseMu.Unlock()
+
+	status := l.GetStatus()
+	switch status {
+	case constants.Paused:
+		return "already " + status.String(), nil
+	case constants.Running:
+	default:
+		return "", fmt.Errorf("%w: status is %s", errPauseNotRunning, status)
+	}
+
+	l.paused.Store(true)
+	l.statusManager.SetStatus(constants.Paused)
+	l.logger.Info("paused periodic block lists updates")
+	return constants.Paused.String(), nil
+}
+
+// unpause clears the pause and signals the restart ticker to
+// compute its next tick from the time elapsed since its last tick.
+// It returns false if the loop was not paused.
+func (l *Loop) unpause(ctx context.Context) (wasPaused bool) {
+	wasPaused = l.paused.Swap(false)
+	if !wasPaused {
+		return false
+	}
+
+	select {
+	case l.resumeTicker <- struct{}{}:
+	case <-ctx.Done():
+	}
+	return true
+}
+
+// resume resumes the periodic block lists updates of the
+// paused DNS server.
+func (l *Loop) resume(ctx context.Context) (outcome string) {
+	l.pauseMu.Lock()
+	defer l.pauseMu.Unlock()
+
+	if l.GetStatus() == constants.Paused {
+		l.statusManager.SetStatus(constants.Running)
+	}
+	l.unpause(ctx)
+	l.logger.Info("resumed periodic block lists updates")
+	return constants.Running.String()
+}
@@ -0,0 +1,108 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// persistedStatus is the JSON file format of the
+// DNS loop status persisted on disk.
+type persistedStatus struct {
+	Status models.LoopStatus `json:"status"`
+}
+
+// ApplyUserStatus applies the status given like ApplyStatus, and
+// persists it if a status path is set, to restore it on the next
+// start. A stopped status is then kept until the running status is
+// applied again with this method, so the DNS server is not started
+// again when the VPN reconnects.
+func (l *Loop) ApplyUserStatus(ctx context.Context, status models.LoopStatus) (
+	outcome string, err error) {
+	statusPath := *l.GetSettings().StatusPath
+	if statusPath == "" {
+		return l.ApplyStatus(ctx, status)
+	}
+
+	if status != constants.Stopped {
+		l.userStopped.Store(false)
+	}
+	outcome, err = l.ApplyStatus(ctx, status)
+	if err != nil {
+		return "", err
+	}
+	l.userStopped.Store(status == constants.Stopped)
+
+	err = writeStatus(statusPath, status)
+	if err != nil {
+		l.logger.Warn(err.Error())
+	}
+	return outcome, nil
+}
+
+// restoreStatus restores the status persisted at the status path
+// given, if any. A persisted crashed status is restored as stopped.
+func (l *Loop) restoreStatus(statusPath string) {
+	if statusPath == "" {
+		return
+	}
+
+	status, err := readStatus(statusPath)
+	if err != nil {
+		l.logger.Warn(err.Error())
+		return
+	}
+
+	switch status {
+	case constants.Stopped, constants.Crashed:
+		l.userStopped.Store(true)
+		l.logger.Info("restored stopped status, the DNS server is not started " +
+			"until the running status is applied through the control server")
+	case constants.Paused:
+		l.paused.Store(true)
+		l.logger.Info("restored paused status, periodic block lists updates " +
+			"are paused until the running status is applied through the control server")
+	}
+}
+
+func writeStatus(path string, status models.LoopStatus) (err error) {
+	err = writeFileAtomically(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(persistedStatus{Status: status})
+	})
+	if err != nil {
+		return fmt.Errorf("writing status: %w", err)
+	}
+	return nil
+}
+
+// readStatus reads the status persisted at the path given,
+// and returns the empty status if the file does not exist.
+func readStatus(path string) (status models.LoopStatus, err error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("opening status file: %w", err)
+	}
+
+	var persisted persistedStatus
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&persisted)
+	if err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("decoding status file: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return "", fmt.Errorf("closing status file: %w", err)
+	}
+
+	return persisted.Status, nil
+}
@@ -1,44 +1,137 @@
 package dns
 
 import (
+	"errors"
+	"fmt"
 	"net/netip"
 	"time"
 
-	"github.com/qdm12/dns/v2/pkg/nameserver"
+	"github.com/miekg/dns"
+	"github.com/qdm12/gluetun/internal/models"
 )
 
+// useUnencryptedDNS uses plaintext DNS, unless in strict mode
+// where the DNS over TLS server is used instead, so hostnames fail
+// to resolve until the DNS over TLS server is ready.
 func (l *Loop) useUnencryptedDNS(fallback bool) {
 	settings := l.GetSettings()
+	if *settings.Strict {
+		l.logger.Warn("strict mode: not using plaintext DNS, " +
+			"hostnames cannot be resolved until the DNS over TLS server is ready")
+		l.useDNSServer(settings)
+		return
+	}
 
-	// Try with user provided plaintext ip address
-	// if it's not 127.0.0.1 (default for DoT), otherwise
-	// use the first DoT provider ipv4 address found.
-	var targetIP netip.Addr
-	if settings.ServerAddress.Compare(netip.AddrFrom4([4]byte{127, 0, 0, 1})) != 0 {
+	// Try with the first user provided plaintext address responding,
+	// then with the user provided plaintext ip address if it's not
+	// 127.0.0.1 (default for DoT), otherwise use the first DoT provider
+	// ipv4 address found, or on fallback the first one responding.
+	targetIP, ok := l.pickPlaintextAddress(settings.PlaintextAddresses, *settings.PlaintextPort)
+	switch {
+	case ok:
+		// user provided plaintext address selected
+	case settings.ServerAddress.Compare(netip.AddrFrom4([4]byte{127, 0, 0, 1})) != 0:
 		targetIP = settings.ServerAddress
-	} else {
-		targetIP = settings.DoT.GetFirstPlaintextIPv4()
+	default:
+		var err error
+		targetIP, err = l.pickPlaintextIP(settings.DoT.GetPlaintextIPs(l.useIPv6(settings)),
+			*settings.PlaintextPort, fallback)
+		if err != nil {
+			l.logger.Error("using plaintext DNS: " + err.Error())
+			return
+		}
 	}
 
+	targetAddress := netip.AddrPortFrom(targetIP, *settings.PlaintextPort)
+
+	l.detailsMu.Lock()
+	wasFallback := l.fallback
+	l.fallback = fallback
+	l.plaintextAddress = targetAddress
+	l.detailsMu.Unlock()
 	if fallback {
-		l.logger.Info("falling back on plaintext DNS at address " + targetIP.String())
+		l.logEvent(l.logger.Info, eventRecord{
+			Event:   "plaintext_fallback",
+			Message: "falling back on plaintext DNS at address " + targetAddress.String(),
+			Address: targetAddress.String(),
+		})
+		if !wasFallback {
+			l.publish(models.DNSEventDegraded)
+		}
 	} else {
-		l.logger.Info("using plaintext DNS at address " + targetIP.String())
+		l.logger.Info("using plaintext DNS at address " + targetAddress.String())
 	}
 
 	const dialTimeout = 3 * time.Second
-	settingsInternalDNS := nameserver.SettingsInternalDNS{
-		IP:      targetIP,
-		Timeout: dialTimeout,
+	const encrypted = false
+	l.useDNSInternally(settings, targetAddress, dialTimeout, encrypted)
+
+	_ = l.useDNSSystemWide(targetIP)
+
+	const defaultDNSPort = 53
+	if *settings.PlaintextPort != defaultDNSPort {
+		l.logger.Warn(fmt.Sprintf("resolv.conf cannot specify port %d, so programs "+
+			"other than gluetun use port %d to reach %s",
+			*settings.PlaintextPort, defaultDNSPort, targetIP))
 	}
-	nameserver.UseDNSInternally(settingsInternalDNS)
+}
 
-	settingsSystemWide := nameserver.SettingsSystemDNS{
-		IP:         targetIP,
-		ResolvPath: l.resolvConf,
+// pickPlaintextAddress returns the first of the addresses given
+// answering a DNS query on the port given, and false if none of
+// them answer or if no address is given.
+func (l *Loop) pickPlaintextAddress(addresses []netip.Addr, port uint16) (
+	address netip.Addr, ok bool) {
+	if len(addresses) == 0 {
+		return address, false
 	}
-	err := nameserver.UseDNSSystemWide(settingsSystemWide)
-	if err != nil {
-		l.logger.Error(err.Error())
+
+	for _, candidate := range addresses {
+		err := probePlaintext(candidate, port)
+		if err == nil {
+			l.logger.Info("selected plaintext DNS address " + candidate.String())
+			return candidate, true
+		}
+		l.logger.Warn("plaintext DNS at address " + candidate.String() + " is not responding: " + err.Error())
 	}
+
+	l.logger.Warn("none of the plaintext DNS addresses is responding")
+	return address, false
+}
+
+var errNoPlaintextIP = errors.New("no plaintext DNS IP address")
+
+// pickPlaintextIP returns the first IP address of the slice given if
+// fallback is false or if there is only one IP address. Otherwise, it
+// returns the first IP address answering a DNS query on the port given,
+// or the first IP address if none of them answer. It returns an error
+// if the slice given is empty.
+func (l *Loop) pickPlaintextIP(ips []netip.Addr, port uint16,
+	fallback bool) (ip netip.Addr, err error) {
+	switch {
+	case len(ips) == 0:
+		return ip, fmt.Errorf("%w", errNoPlaintextIP)
+	case !fallback || len(ips) == 1:
+		return ips[0], nil
+	}
+
+	for _, ip := range ips {
+		err := probePlaintext(ip, port)
+		if err == nil {
+			return ip, nil
+		}
+		l.logger.Debug("plaintext DNS at address " + ip.String() + " is not responding: " + err.Error())
+	}
+
+	return ips[0], nil
+}
+
+// probePlaintext returns an error if the plaintext DNS server
+// at the IP address and port given does not answer a DNS query.
+func probePlaintext(ip netip.Addr, port uint16) (err error) {
+	const timeout = time.Second
+	client := &dns.Client{Timeout: timeout}
+	request := new(dns.Msg).SetQuestion("github.com.", dns.TypeA)
+	address := netip.AddrPortFrom(ip, port).String()
+	_, _, err = client.Exchange(request, address)
+	return err
 }
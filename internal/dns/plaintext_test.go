@@ -0,0 +1,104 @@
+package dns
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startPlaintextServer starts a plaintext DNS server answering
+// all queries on 127.0.0.1 and returns its port.
+func startPlaintextServer(t *testing.T) (port uint16) {
+	t.Helper()
+	return startUDPServer(t, dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		_ = w.WriteMsg(new(dns.Msg).SetReply(request))
+	}))
+}
+
+// startUDPServer starts a plaintext DNS server on 127.0.0.1
+// serving queries with the handler given, and returns its port.
+func startUDPServer(t *testing.T, handler dns.Handler) (port uint16) {
+	t.Helper()
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{
+		PacketConn: packetConn,
+		Handler:    handler,
+	}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return uint16(packetConn.LocalAddr().(*net.UDPAddr).Port) //nolint:gosec
+}
+
+func Test_Loop_pickPlaintextIP(t *testing.T) {
+	t.Parallel()
+
+	port := startPlaintextServer(t)
+	answering := netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	// The plaintext server only listens on 127.0.0.1,
+	// so other loopback addresses do not answer.
+	silent := netip.AddrFrom4([4]byte{127, 0, 0, 2})
+
+	testCases := map[string]struct {
+		ips        []netip.Addr
+		fallback   bool
+		ip         netip.Addr
+		errWrapped error
+		errMessage string
+	}{
+		"no_ip": {
+			errWrapped: errNoPlaintextIP,
+			errMessage: "no plaintext DNS IP address",
+		},
+		"no_ip_fallback": {
+			fallback:   true,
+			errWrapped: errNoPlaintextIP,
+			errMessage: "no plaintext DNS IP address",
+		},
+		"not_fallback": {
+			ips: []netip.Addr{silent, answering},
+			ip:  silent,
+		},
+		"fallback_single_ip": {
+			ips:      []netip.Addr{silent},
+			fallback: true,
+			ip:       silent,
+		},
+		"fallback_first_answering": {
+			ips:      []netip.Addr{silent, answering},
+			fallback: true,
+			ip:       answering,
+		},
+		"fallback_none_answering": {
+			ips:      []netip.Addr{silent, netip.AddrFrom4([4]byte{127, 0, 0, 3})},
+			fallback: true,
+			ip:       silent,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			loop := &Loop{logger: noopLogger{}}
+
+			ip, err := loop.pickPlaintextIP(testCase.ips, port, testCase.fallback)
+
+			assert.ErrorIs(t, err, testCase.errWrapped)
+			if testCase.errWrapped != nil {
+				assert.EqualError(t, err, testCase.errMessage)
+			}
+			assert.Equal(t, testCase.ip, ip)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/dns/v2/pkg/middlewares/cache"
+)
+
+// prefetchCache wraps a cache and refreshes cached responses in the
+// background when they are requested in the last tenth of their TTL,
+// to keep popular records in the cache.
+type prefetchCache struct {
+	cache      cache.Cache
+	maxEntries int
+	// refresher is the middleware placed just before the cache
+	// middleware, giving access to the handler the cache wraps.
+	refresher *prefetchMiddleware
+	timeNow   func() time.Time
+
+	mutex       sync.Mutex
+	expirations map[string]expiration
+	prefetching map[string]struct{}
+}
+
+type expiration struct {
+	expiry time.Time
+	ttl    time.Duration
+}
+
+func newPrefetchCache(cache cache.Cache, maxEntries int,
+	refresher *prefetchMiddleware) *prefetchCache {
+	return &prefetchCache{
+		cache:       cache,
+		maxEntries:  maxEntries,
+		refresher:   refresher,
+		timeNow:     time.Now,
+		expirations: make(map[string]expiration),
+		prefetching: make(map[string]struct{}),
+	}
+}
+
+func (c *prefetchCache) Get(request *dns.Msg) (response *dns.Msg) {
+	response = c.cache.Get(request)
+	if response == nil || len(request.Question) == 0 {
+		return response
+	}
+
+	key := prefetchKey(request.Question[0])
+	now := c.timeNow()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expiration, ok := c.expirations[key]
+	if !ok || expiration.expiry.Sub(now) > expiration.ttl/10 { //nolint:gomnd
+		return response
+	}
+	if _, prefetching := c.prefetching[key]; prefetching {
+		return response
+	}
+
+	if !c.refresher.background.start() {
+		// The DNS server is stopping.
+		return response
+	}
+	c.prefetching[key] = struct{}{}
+	go c.prefetch(key, request.Copy())
+	return response
+}
+
+func (c *prefetchCache) prefetch(key string, request *dns.Msg) {
+	defer c.refresher.background.done()
+	response := c.refresher.exchange(request)
+	if response != nil {
+		c.Add(request, response)
+	}
+
+	c.mutex.Lock()
+	delete(c.prefetching, key)
+	c.mutex.Unlock()
+}
+
+func (c *prefetchCache) Add(request, response *dns.Msg) {
+	c.cache.Add(request, response)
+	if len(request.Question) == 0 || response == nil || len(response.Answer) == 0 {
+		return
+	}
+
+	ttl := minAnswerTTL(response)
+	now := c.timeNow()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.expirations) >= c.maxEntries {
+		for key, expiration := range c.expirations {
+			if now.After(expiration.expiry) {
+				delete(c.expirations, key)
+			}
+		}
+		if len(c.expirations) >= c.maxEntries {
+			return
+		}
+	}
+	c.expirations[prefetchKey(request.Question[0])] = expiration{
+		expiry: now.Add(ttl),
+		ttl:    ttl,
+	}
+}
+
+func prefetchKey(question dns.Question) string {
+	return question.Name + "|" + dns.Class(question.Qclass).String() +
+		"|" + dns.Type(question.Qtype).String()
+}
+
+// prefetchMiddleware keeps a reference to the handler it wraps,
+// so the prefetch cache can refresh responses through it. It also
+// tracks the refreshes running, so stopping it with the DNS server
+// waits for them and prevents new refreshes.
+type prefetchMiddleware struct {
+	next       dns.Handler
+	background backgroundQueries
+}
+
+func (m *prefetchMiddleware) String() string {
+	return "prefetch"
+}
+
+func (m *prefetchMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	m.next = next
+	return next
+}
+
+func (m *prefetchMiddleware) Stop() (err error) {
+	m.background.stop()
+	return nil
+}
+
+func (m *prefetchMiddleware) exchange(request *dns.Msg) (response *dns.Msg) {
+	writer := &responseRecorder{}
+	m.next.ServeDNS(writer, request)
+	return writer.response
+}
+
+// responseRecorder is a dns.ResponseWriter recording the response written.
+type responseRecorder struct {
+	response *dns.Msg
+}
+
+func (r *responseRecorder) LocalAddr() net.Addr       { return &net.UDPAddr{} }
+func (r *responseRecorder) RemoteAddr() net.Addr      { return &net.UDPAddr{} }
+func (r *responseRecorder) Write([]byte) (int, error) { return 0, nil }
+func (r *responseRecorder) Close() error              { return nil }
+func (r *responseRecorder) TsigStatus() error         { return nil }
+func (r *responseRecorder) TsigTimersOnly(bool)       {}
+func (r *responseRecorder) Hijack()                   {}
+
+func (r *responseRecorder) WriteMsg(response *dns.Msg) error {
+	r.response = response
+	return nil
+}
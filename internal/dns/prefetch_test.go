@@ -0,0 +1,155 @@
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapCache is a cache without expiry keeping the
+// last response added for each question.
+type mapCache struct {
+	mutex     sync.Mutex
+	responses map[string]*dns.Msg
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{responses: make(map[string]*dns.Msg)}
+}
+
+func (c *mapCache) Get(request *dns.Msg) *dns.Msg {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.responses[prefetchKey(request.Question[0])]
+}
+
+func (c *mapCache) Add(request, response *dns.Msg) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.responses[prefetchKey(request.Question[0])] = response
+}
+
+func Test_prefetchCache_Get(t *testing.T) {
+	t.Parallel()
+
+	cachedIP := net.IPv4(1, 1, 1, 1)
+	refreshedIP := net.IPv4(2, 2, 2, 2)
+	const ttl = 60
+
+	testCases := map[string]struct {
+		cached    bool
+		elapsed   time.Duration
+		stopped   bool
+		refreshes int
+		ip        net.IP
+	}{
+		"not_cached": {},
+		"first_part_of_ttl": {
+			cached:  true,
+			elapsed: 50 * time.Second,
+			ip:      cachedIP,
+		},
+		"last_tenth_of_ttl": {
+			cached:    true,
+			elapsed:   55 * time.Second,
+			refreshes: 1,
+			ip:        refreshedIP,
+		},
+		"last_tenth_of_ttl_stopped": {
+			cached:  true,
+			elapsed: 55 * time.Second,
+			stopped: true,
+			ip:      cachedIP,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var mutex sync.Mutex
+			refreshes := 0
+			refresher := &prefetchMiddleware{}
+			refresher.Wrap(dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				mutex.Lock()
+				refreshes++
+				mutex.Unlock()
+				_ = w.WriteMsg(newAnswer(request, refreshedIP, ttl))
+			}))
+			cache := newPrefetchCache(newMapCache(), 10, refresher) //nolint:gomnd
+			start := time.Unix(0, 0)
+			cache.timeNow = func() time.Time { return start }
+
+			request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			if testCase.cached {
+				cache.Add(request, newAnswer(request, cachedIP, ttl))
+			}
+			cache.timeNow = func() time.Time { return start.Add(testCase.elapsed) }
+			if testCase.stopped {
+				err := refresher.Stop()
+				require.NoError(t, err)
+			}
+
+			response := cache.Get(request)
+			// Stop waits for the refresh started in the background.
+			err := refresher.Stop()
+			require.NoError(t, err)
+
+			if !testCase.cached {
+				assert.Nil(t, response)
+			} else {
+				require.NotNil(t, response)
+				answer := response.Answer[0].(*dns.A) //nolint:forcetypeassert
+				assert.True(t, cachedIP.Equal(answer.A))
+			}
+			assert.Equal(t, testCase.refreshes, refreshes)
+
+			response = cache.Get(request)
+			if testCase.ip == nil {
+				assert.Nil(t, response)
+				return
+			}
+			require.NotNil(t, response)
+			answer := response.Answer[0].(*dns.A) //nolint:forcetypeassert
+			assert.True(t, testCase.ip.Equal(answer.A))
+		})
+	}
+}
+
+func Test_prefetchMiddleware_Stop(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	refresher := &prefetchMiddleware{}
+	refresher.Wrap(dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		<-release
+		_ = w.WriteMsg(newAnswer(request, net.IPv4(2, 2, 2, 2), 60)) //nolint:gomnd
+	}))
+	cache := newPrefetchCache(newMapCache(), 10, refresher) //nolint:gomnd
+	start := time.Unix(0, 0)
+	cache.timeNow = func() time.Time { return start }
+	request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+	cache.Add(request, newAnswer(request, net.IPv4(1, 1, 1, 1), 60)) //nolint:gomnd
+	cache.timeNow = func() time.Time { return start.Add(59 * time.Second) }
+
+	_ = cache.Get(request)
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = refresher.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the refresh running in the background")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+}
@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// aggregatePrefixes returns the IP prefixes given without the prefixes
+// contained in other prefixes, and with adjacent prefixes merged into
+// their parent prefix, such as 10.0.0.0/24 and 10.0.1.0/24 merged into
+// 10.0.0.0/23. The prefixes returned block the same IP addresses, and
+// are sorted by address with IPv4 prefixes first.
+func aggregatePrefixes(prefixes []netip.Prefix) (aggregated []netip.Prefix) {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if !prefix.IsValid() {
+			continue
+		}
+		sorted = append(sorted, prefix.Masked())
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		comparison := sorted[i].Addr().Compare(sorted[j].Addr())
+		if comparison != 0 {
+			return comparison < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	aggregated = make([]netip.Prefix, 0, len(sorted))
+	for _, prefix := range sorted {
+		if len(aggregated) > 0 {
+			last := aggregated[len(aggregated)-1]
+			if last.Bits() <= prefix.Bits() && last.Contains(prefix.Addr()) {
+				// contained in the previous prefix
+				continue
+			}
+		}
+		aggregated = append(aggregated, prefix)
+
+		for len(aggregated) >= 2 { //nolint:gomnd
+			first := aggregated[len(aggregated)-2]
+			second := aggregated[len(aggregated)-1]
+			parent, ok := siblingsParent(first, second)
+			if !ok {
+				break
+			}
+			aggregated = aggregated[:len(aggregated)-2]
+			aggregated = append(aggregated, parent)
+		}
+	}
+	return aggregated
+}
+
+// siblingsParent returns the parent prefix of the two prefixes
+// given, and true if they are the two halves of this parent.
+func siblingsParent(first, second netip.Prefix) (parent netip.Prefix, ok bool) {
+	if first.Bits() != second.Bits() || first.Bits() == 0 ||
+		first.Addr().BitLen() != second.Addr().BitLen() || first == second {
+		return parent, false
+	}
+	parent = netip.PrefixFrom(first.Addr(), first.Bits()-1).Masked()
+	secondParent := netip.PrefixFrom(second.Addr(), second.Bits()-1).Masked()
+	if parent != secondParent {
+		return parent, false
+	}
+	return parent, true
+}
@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_aggregatePrefixes(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		prefixes   []string
+		aggregated []string
+	}{
+		"empty": {
+			aggregated: []string{},
+		},
+		"single": {
+			prefixes:   []string{"10.0.0.0/24"},
+			aggregated: []string{"10.0.0.0/24"},
+		},
+		"duplicates": {
+			prefixes:   []string{"10.0.0.0/24", "10.0.0.0/24"},
+			aggregated: []string{"10.0.0.0/24"},
+		},
+		"unmasked": {
+			prefixes:   []string{"10.0.0.1/24"},
+			aggregated: []string{"10.0.0.0/24"},
+		},
+		"contained": {
+			prefixes:   []string{"10.0.0.0/25", "10.0.0.0/16", "10.0.5.0/24"},
+			aggregated: []string{"10.0.0.0/16"},
+		},
+		"adjacent": {
+			prefixes:   []string{"10.0.1.0/24", "10.0.0.0/24"},
+			aggregated: []string{"10.0.0.0/23"},
+		},
+		"adjacent_not_siblings": {
+			prefixes:   []string{"10.0.1.0/24", "10.0.2.0/24"},
+			aggregated: []string{"10.0.1.0/24", "10.0.2.0/24"},
+		},
+		"cascading_merges": {
+			prefixes: []string{
+				"10.0.0.0/25", "10.0.0.128/25", "10.0.1.0/24", "10.0.2.0/23",
+			},
+			aggregated: []string{"10.0.0.0/22"},
+		},
+		"different_sizes": {
+			prefixes:   []string{"10.0.0.0/24", "10.0.1.0/25"},
+			aggregated: []string{"10.0.0.0/24", "10.0.1.0/25"},
+		},
+		"ipv4_and_ipv6": {
+			prefixes: []string{
+				"2001:db8::/33", "2001:db8:8000::/33", "192.168.0.0/24",
+			},
+			aggregated: []string{"192.168.0.0/24", "2001:db8::/32"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			prefixes := make([]netip.Prefix, len(testCase.prefixes))
+			for i, s := range testCase.prefixes {
+				prefixes[i] = netip.MustParsePrefix(s)
+			}
+
+			aggregated := aggregatePrefixes(prefixes)
+
+			aggregatedStrings := make([]string, len(aggregated))
+			for i, prefix := range aggregated {
+				aggregatedStrings[i] = prefix.String()
+			}
+			assert.Equal(t, testCase.aggregated, aggregatedStrings)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package dns
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// newProgressClient returns a copy of the HTTP client given which
+// logs the number of bytes downloaded for the block list source
+// name given, at most once per interval given.
+func newProgressClient(client *http.Client, logger Logger, name string,
+	interval time.Duration) (progressClient *http.Client, progress *downloadProgress) {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	progress = &downloadProgress{
+		name:     name,
+		logger:   logger,
+		interval: interval,
+		timeNow:  time.Now,
+	}
+	progress.lastLog = progress.timeNow()
+	copied := *client
+	copied.Transport = &progressTransport{
+		progress: progress,
+		next:     transport,
+	}
+	return &copied, progress
+}
+
+// downloadProgress counts the bytes downloaded for a block list source.
+type downloadProgress struct {
+	name     string
+	logger   Logger
+	interval time.Duration
+	timeNow  func() time.Time
+
+	mutex   sync.Mutex
+	bytes   int64
+	lastLog time.Time
+}
+
+func (p *downloadProgress) add(n int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes += int64(n)
+	now := p.timeNow()
+	if now.Sub(p.lastLog) < p.interval {
+		return
+	}
+	p.lastLog = now
+	p.logger.Info(fmt.Sprintf("%s block lists: %s downloaded so far",
+		p.name, formatBytes(p.bytes)))
+}
+
+func (p *downloadProgress) total() (bytes int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.bytes
+}
+
+type progressTransport struct {
+	progress *downloadProgress
+	next     http.RoundTripper
+}
+
+func (t *progressTransport) RoundTrip(request *http.Request) (
+	response *http.Response, err error) {
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = &progressReader{
+		ReadCloser: response.Body,
+		progress:   t.progress,
+	}
+	return response, nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+	progress *downloadProgress
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		r.progress.add(n)
+	}
+	return n, err
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value := float64(bytes) / unit
+	for _, suffix := range []string{"KiB", "MiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f%s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1fGiB", value)
+}
@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+)
+
+// queryLogger implements the log middleware logger interface
+// and logs DNS queries only if it is enabled, so query logging
+// can be toggled without restarting the DNS server.
+type queryLogger struct {
+	enabled atomic.Bool
+	logger  Logger
+}
+
+func (q *queryLogger) Log(remoteAddr net.Addr, request, response *dns.Msg) {
+	if !q.enabled.Load() {
+		return
+	}
+
+	questions := make([]string, len(request.Question))
+	for i, question := range request.Question {
+		questions[i] = question.Name + " " + dns.Type(question.Qtype).String()
+	}
+
+	answers := make([]string, len(response.Answer))
+	for i, answer := range response.Answer {
+		answers[i] = strings.ReplaceAll(strings.TrimPrefix(answer.String(), "\t"), "\t", " ")
+	}
+
+	q.logger.Info(fmt.Sprintf("query from %s: %s => %s [%s]", remoteAddr,
+		strings.Join(questions, ", "), dns.RcodeToString[response.Rcode],
+		strings.Join(answers, ", ")))
+}
+
+func (q *queryLogger) Error(id uint16, errorString string) {
+	if !q.enabled.Load() {
+		return
+	}
+	q.logger.Warn(fmt.Sprintf("query id %d: %s", id, errorString))
+}
+
+// SetQueryLog enables or disables the DNS query logging,
+// without restarting the DNS server.
+func (l *Loop) SetQueryLog(enabled bool) (outcome string) {
+	l.statusManager.Lock()
+	defer l.statusManager.Unlock()
+
+	settings := l.GetSettings()
+	if *settings.DoT.QueryLog == enabled {
+		return "query log left unchanged"
+	}
+
+	settings = settings.Copy()
+	*settings.DoT.QueryLog = enabled
+	l.state.SetSettingsLive(settings)
+	l.queryLogger.enabled.Store(enabled)
+
+	if enabled {
+		return "query log enabled"
+	}
+	return "query log disabled"
+}
@@ -0,0 +1,41 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qdm12/dns/v2/pkg/check"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+var errDNSNotReady = errors.New("DNS server is not ready")
+
+// waitForDNS waits for the DNS to resolve a hostname, retrying
+// at the readiness retry interval until the readiness timeout
+// elapses or the context is canceled.
+func waitForDNS(ctx context.Context, settings settings.DNS) (err error) {
+	timeout := *settings.ReadinessTimeout
+	interval := *settings.ReadinessRetryInterval
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The context timeout is what ends the check, so set the
+	// maximum number of tries to fit at least the timeout duration.
+	maxTries := int(timeout/interval) + 1
+	err = check.WaitForDNS(ctx, check.Settings{
+		MaxTries: maxTries,
+		WaitTime: interval,
+		// AddWaitTime cannot be zero since zero is the unset value,
+		// so use a negligible value to keep a fixed interval.
+		AddWaitTime: 1,
+	})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: after waiting %s", errDNSNotReady, timeout)
+		}
+		return fmt.Errorf("%w: %w", errDNSNotReady, err)
+	}
+	return nil
+}
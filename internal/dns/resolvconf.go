@@ -0,0 +1,168 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/qdm12/dns/v2/pkg/nameserver"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// readNameservers returns the valid nameserver IP addresses
+// of the resolv.conf file at the path given.
+func readNameservers(resolvPath string) (nameservers []netip.Addr, err error) {
+	data, err := os.ReadFile(resolvPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading resolv file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		const expectedFields = 2
+		if len(fields) != expectedFields || fields[0] != "nameserver" {
+			continue
+		}
+		ip, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		nameservers = append(nameservers, ip)
+	}
+	return nameservers, nil
+}
+
+// withBypassZones returns the forward zones of the settings given,
+// together with a forward zone to the original nameserver for each
+// bypass domain. Bypass domains are ignored with a warning if no
+// original nameserver was found when the loop was created.
+func (l *Loop) withBypassZones(dnsSettings settings.DNS) (
+	zones []settings.DNSForwardZone) {
+	zones = dnsSettings.ForwardZones
+	if len(dnsSettings.BypassDomains) == 0 {
+		return zones
+	}
+
+	var originalNameserver netip.Addr
+	for _, nameserver := range l.originalNameservers {
+		if nameserver != dnsSettings.ServerAddress &&
+			nameserver != dnsSettings.ResolvConfAddress &&
+			nameserver != dnsSettings.InternalAddress {
+			originalNameserver = nameserver
+			break
+		}
+	}
+	if !originalNameserver.IsValid() {
+		l.logger.Warn("ignoring DNS bypass domains: no original nameserver found in " +
+			l.resolvConf)
+		return zones
+	}
+
+	zones = make([]settings.DNSForwardZone, len(dnsSettings.ForwardZones),
+		len(dnsSettings.ForwardZones)+len(dnsSettings.BypassDomains))
+	copy(zones, dnsSettings.ForwardZones)
+	for _, domain := range dnsSettings.BypassDomains {
+		zones = append(zones, settings.DNSForwardZone{
+			Suffix:   domain,
+			Upstream: originalNameserver.String(),
+		})
+	}
+	return zones
+}
+
+// addNameserver adds a nameserver line for the IP address given
+// right after the first nameserver line of the resolv.conf file,
+// if it is not already present.
+func addNameserver(resolvPath string, ip netip.Addr) (err error) {
+	data, err := os.ReadFile(resolvPath)
+	if err != nil {
+		return fmt.Errorf("reading resolv file: %w", err)
+	}
+
+	newLine := "nameserver " + ip.String()
+	lines := strings.Split(string(data), "\n")
+	patchedLines := make([]string, 0, len(lines)+1)
+	added := false
+	for _, line := range lines {
+		if line == newLine {
+			return nil
+		}
+		patchedLines = append(patchedLines, line)
+		if !added && strings.HasPrefix(line, "nameserver ") {
+			patchedLines = append(patchedLines, newLine)
+			added = true
+		}
+	}
+	if !added {
+		patchedLines = append([]string{newLine}, patchedLines...)
+	}
+
+	const filePermissions os.FileMode = 0o600
+	err = os.WriteFile(resolvPath, []byte(strings.Join(patchedLines, "\n")), filePermissions)
+	if err != nil {
+		return fmt.Errorf("writing resolv file: %w", err)
+	}
+	return nil
+}
+
+// listensOnIPv6Loopback returns true if the listening address
+// given listens on the IPv6 loopback address ::1.
+func listensOnIPv6Loopback(listeningAddress string) bool {
+	host, _, err := net.SplitHostPort(listeningAddress)
+	if err != nil {
+		return false
+	}
+	return host == "" || host == "::" || host == "::1"
+}
+
+// useDNSSystemWide sets the nameserver IP address given in resolv.conf,
+// retrying a bounded number of times on failure. The last error is
+// recorded for the status and the health check, since the system does
+// not use the DNS server expected. A read-only resolv.conf is reported
+// once with an actionable error, since retrying cannot fix it.
+func (l *Loop) useDNSSystemWide(ip netip.Addr) (err error) {
+	settings := nameserver.SettingsSystemDNS{
+		IP:         ip,
+		ResolvPath: l.resolvConf,
+	}
+	const maxAttempts = 3
+	const retryDelay = 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err = nameserver.UseDNSSystemWide(settings)
+		if err == nil || isReadOnlyError(err) || attempt == maxAttempts {
+			break
+		}
+		time.Sleep(retryDelay)
+	}
+
+	l.detailsMu.Lock()
+	defer l.detailsMu.Unlock()
+	switch {
+	case err == nil:
+		l.resolvConfErr = ""
+		l.resolvConfReadOnly = false
+		return nil
+	case isReadOnlyError(err):
+		l.resolvConfErr = err.Error()
+		if !l.resolvConfReadOnly {
+			l.resolvConfReadOnly = true
+			l.logger.Error(fmt.Sprintf("%s is read-only, so the system cannot use %s "+
+				"as nameserver (%s): please mount %s read-write, for example by removing "+
+				"its read-only bind mount", l.resolvConf, ip, err, l.resolvConf))
+		}
+	default:
+		l.resolvConfErr = err.Error()
+		l.logger.Error(fmt.Sprintf("setting nameserver in %s after %d attempts: %s",
+			l.resolvConf, maxAttempts, err))
+	}
+	return err
+}
+
+func isReadOnlyError(err error) bool {
+	return errors.Is(err, syscall.EROFS) || errors.Is(err, os.ErrPermission)
+}
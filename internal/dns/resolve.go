@@ -0,0 +1,201 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+var (
+	ErrResolveTypeNotValid = errors.New("record type is not valid")
+	errNoResolver          = errors.New("no resolver to query")
+)
+
+// Resolve resolves the name for the record type given, such as `A`,
+// through the resolver currently used by the Go program, which is
+// either the DNS over TLS server or a plaintext DNS server.
+func (l *Loop) Resolve(ctx context.Context, name, recordType string) (
+	resolution models.DNSResolution, err error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(recordType)]
+	if !ok {
+		return resolution, fmt.Errorf("%w: %s", ErrResolveTypeNotValid, recordType)
+	}
+
+	l.detailsMu.RLock()
+	resolver := l.internalResolver
+	fallback := l.fallback
+	l.detailsMu.RUnlock()
+	if !resolver.IsValid() {
+		resolver, err = l.systemResolver()
+		if err != nil {
+			return resolution, err
+		}
+	}
+
+	settings := l.GetSettings()
+	resolution = models.DNSResolution{
+		Name:     dns.Fqdn(name),
+		Type:     dns.TypeToString[qtype],
+		Resolver: resolver.String(),
+		Answers:  []string{},
+	}
+	switch {
+	case resolver.Addr() == settings.InternalAddress && *settings.DoT.Enabled:
+		resolution.Mode = "dot"
+	case fallback:
+		resolution.Mode = "plaintext_fallback"
+	default:
+		resolution.Mode = "plaintext"
+	}
+
+	request := new(dns.Msg).SetQuestion(resolution.Name, qtype)
+	trace := l.cacheTracker.track(request.Id)
+	defer l.cacheTracker.untrack(request.Id)
+
+	client := &dns.Client{}
+	response, duration, err := client.ExchangeContext(ctx, request, resolver.String())
+	if err != nil {
+		return resolution, fmt.Errorf("resolving %s %s: %w", resolution.Name,
+			resolution.Type, err)
+	}
+
+	resolution.Rcode = dns.RcodeToString[response.Rcode]
+	for _, rr := range response.Answer {
+		resolution.Answers = append(resolution.Answers, rr.String())
+	}
+	resolution.Duration = duration
+	resolution.Cached = resolution.Mode == "dot" && trace.cached()
+	return resolution, nil
+}
+
+// systemResolver returns the first nameserver of resolv.conf,
+// used when the Go program uses its default resolver.
+func (l *Loop) systemResolver() (resolver netip.AddrPort, err error) {
+	nameservers, err := readNameservers(l.resolvConf)
+	if err != nil {
+		return resolver, err
+	}
+	if len(nameservers) == 0 {
+		return resolver, fmt.Errorf("%w: no nameserver in %s", errNoResolver, l.resolvConf)
+	}
+	const defaultDNSPort = 53
+	return netip.AddrPortFrom(nameservers[0], defaultDNSPort), nil
+}
+
+// cacheTracker records if the queries tracked reached the DNS
+// over TLS server caches, and if they went past them, to tell
+// if they were answered from cache. Queries are identified by
+// their message ID, so another client query with the same ID
+// at the same time can be mistaken for the query tracked.
+// It also counts all the queries reaching the caches, and
+// the queries going past them, to report the cache hit ratio.
+type cacheTracker struct {
+	mutex  sync.Mutex
+	traces map[uint16]*cacheTrace
+
+	queries atomic.Uint64
+	misses  atomic.Uint64
+}
+
+type cacheTrace struct {
+	mutex        sync.Mutex
+	reachedCache bool
+	pastCache    bool
+}
+
+func newCacheTracker() *cacheTracker {
+	return &cacheTracker{
+		traces: make(map[uint16]*cacheTrace),
+	}
+}
+
+func (t *cacheTracker) track(id uint16) (trace *cacheTrace) {
+	trace = &cacheTrace{}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.traces[id] = trace
+	return trace
+}
+
+func (t *cacheTracker) untrack(id uint16) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.traces, id)
+}
+
+func (t *cacheTracker) get(id uint16) (trace *cacheTrace) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.traces[id]
+}
+
+// counts returns the number of queries which reached
+// the caches and went past them since the tracker creation.
+func (t *cacheTracker) counts() (counts cacheCounts) {
+	return cacheCounts{
+		queries: t.queries.Load(),
+		misses:  t.misses.Load(),
+	}
+}
+
+func (c *cacheTrace) cached() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.reachedCache && !c.pastCache
+}
+
+// middlewares returns the middleware to place right before the
+// caches middlewares, and the middleware to place right after them.
+func (t *cacheTracker) middlewares() (inner, outer *cacheTrackerMiddleware) {
+	return &cacheTrackerMiddleware{tracker: t, inner: true},
+		&cacheTrackerMiddleware{tracker: t}
+}
+
+// cacheTrackerMiddleware marks the queries tracked as reaching
+// the caches if it is outer to them, or as going past them
+// if it is inner to them.
+type cacheTrackerMiddleware struct {
+	tracker *cacheTracker
+	inner   bool
+}
+
+func (m *cacheTrackerMiddleware) String() string {
+	return "cache tracker"
+}
+
+func (m *cacheTrackerMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if m.inner {
+			// Prefetch refreshes go past the caches without
+			// reaching them, so they are not counted.
+			if _, prefetch := w.(*responseRecorder); !prefetch {
+				m.tracker.misses.Add(1)
+			}
+		} else {
+			m.tracker.queries.Add(1)
+		}
+
+		trace := m.tracker.get(request.Id)
+		if trace != nil {
+			trace.mutex.Lock()
+			if m.inner {
+				trace.pastCache = true
+			} else {
+				trace.reachedCache = true
+			}
+			trace.mutex.Unlock()
+		}
+		next.ServeDNS(w, request)
+	})
+}
+
+func (m *cacheTrackerMiddleware) Stop() (err error) {
+	return nil
+}
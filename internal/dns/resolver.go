@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/qdm12/dns/v2/pkg/nameserver"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// GetResolver returns the nameservers currently set in resolv.conf,
+// the address last set for the Go program resolver, and the original
+// nameservers of the container.
+func (l *Loop) GetResolver() (resolver models.DNSResolver) {
+	systemNameservers, err := readNameservers(l.resolvConf)
+	if err != nil {
+		resolver.SystemError = err.Error()
+	}
+	resolver.SystemNameservers = emptyIfNil(systemNameservers)
+
+	l.detailsMu.RLock()
+	internalResolver := l.internalResolver
+	l.detailsMu.RUnlock()
+	if internalResolver.IsValid() {
+		resolver.InternalResolver = internalResolver.String()
+	}
+
+	resolver.KeepNameserver = *l.GetSettings().KeepNameserver
+	resolver.OriginalNameservers = emptyIfNil(l.originalNameservers)
+	return resolver
+}
+
+// useDNSInternally sets the address given as the Go program resolver,
+// with the dial timeout given, or the internal resolver of the settings
+// if it is set. It also records whether the DNS over TLS server is used,
+// such that DNS answers are received encrypted.
+func (l *Loop) useDNSInternally(settings settings.DNS, address netip.AddrPort,
+	dialTimeout time.Duration, encrypted bool) {
+	if settings.InternalResolver.IsValid() {
+		address = settings.InternalResolver
+	}
+	nameserver.UseDNSInternally(nameserver.SettingsInternalDNS{
+		IP:      address.Addr(),
+		Port:    address.Port(),
+		Timeout: dialTimeout,
+	})
+
+	l.detailsMu.Lock()
+	defer l.detailsMu.Unlock()
+	l.internalResolver = address
+	l.encrypted = encrypted
+}
+
+// emptyIfNil returns an empty slice for a nil slice,
+// so it is encoded as an empty JSON array.
+func emptyIfNil(addresses []netip.Addr) []netip.Addr {
+	if addresses == nil {
+		return []netip.Addr{}
+	}
+	return addresses
+}
@@ -0,0 +1,87 @@
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// exportRPZ writes the blocked hostnames, IP addresses and IP prefixes
+// given to the file path given as a Response Policy Zone, answering
+// NXDOMAIN for them. The SOA record has the serial number given, which
+// must increase with each write so resolvers reload the zone.
+func exportRPZ(path string, serial uint32, hostnames []string,
+	ips []netip.Addr, ipPrefixes []netip.Prefix) (err error) {
+	return writeFileAtomically(path, func(w io.Writer) (err error) {
+		buffered := bufio.NewWriter(w)
+		const ttl = 300
+		_, _ = buffered.WriteString("$TTL " + strconv.Itoa(ttl) + "\n")
+		_, _ = fmt.Fprintf(buffered, "@ IN SOA localhost. root.localhost. %d 3600 600 86400 %d\n",
+			serial, ttl)
+		_, _ = buffered.WriteString("@ IN NS localhost.\n")
+
+		// The filter blocks subdomains of blocked hostnames as well,
+		// so each hostname has a wildcard trigger for its subdomains.
+		for _, hostname := range mergeUnique(hostnames, nil) {
+			_, _ = buffered.WriteString(hostname + " CNAME .\n")
+			_, _ = buffered.WriteString("*." + hostname + " CNAME .\n")
+		}
+		for _, ip := range ips {
+			ip = ip.Unmap()
+			prefix := netip.PrefixFrom(ip, ip.BitLen())
+			_, _ = buffered.WriteString(rpzIPTrigger(prefix) + " CNAME .\n")
+		}
+		for _, ipPrefix := range ipPrefixes {
+			_, _ = buffered.WriteString(rpzIPTrigger(ipPrefix) + " CNAME .\n")
+		}
+		return buffered.Flush()
+	})
+}
+
+// rpzIPTrigger returns the owner name of the Response Policy Zone
+// IP trigger matching the answers with an IP address in the prefix
+// given, such as 24.0.2.0.192.rpz-ip for 192.0.2.0/24.
+func rpzIPTrigger(prefix netip.Prefix) (owner string) {
+	prefix = prefix.Masked()
+	address := prefix.Addr()
+
+	var labels []string
+	if address.Is4() {
+		labels = strings.Split(address.String(), ".")
+	} else {
+		// The longest run of zero groups compressed as :: in the
+		// IPv6 address text is written as zz in the owner name.
+		head, tail, compressed := strings.Cut(address.String(), "::")
+		if head != "" {
+			labels = strings.Split(head, ":")
+		}
+		if compressed {
+			labels = append(labels, "zz")
+			if tail != "" {
+				labels = append(labels, strings.Split(tail, ":")...)
+			}
+		}
+	}
+	slices.Reverse(labels)
+
+	return strconv.Itoa(prefix.Bits()) + "." + strings.Join(labels, ".") + ".rpz-ip"
+}
+
+// nextRPZSerial returns the serial number of the next Response
+// Policy Zone written. It is the current Unix time, so it keeps
+// increasing across restarts, and is greater than the previous
+// serial number even if the zone is written twice in a second.
+func (l *Loop) nextRPZSerial() (serial uint32) {
+	l.detailsMu.Lock()
+	defer l.detailsMu.Unlock()
+	serial = uint32(l.timeNow().Unix()) //nolint:gosec
+	if serial <= l.rpzSerial {
+		serial = l.rpzSerial + 1
+	}
+	l.rpzSerial = serial
+	return serial
+}
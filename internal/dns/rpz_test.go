@@ -0,0 +1,79 @@
+package dns
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rpzIPTrigger(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		prefix netip.Prefix
+		owner  string
+	}{
+		"ipv4_address": {
+			prefix: netip.MustParsePrefix("192.0.2.1/32"),
+			owner:  "32.1.2.0.192.rpz-ip",
+		},
+		"ipv4_prefix_not_masked": {
+			prefix: netip.MustParsePrefix("192.0.2.1/24"),
+			owner:  "24.0.2.0.192.rpz-ip",
+		},
+		"ipv6_compressed_middle": {
+			prefix: netip.MustParsePrefix("2001:db8::1/128"),
+			owner:  "128.1.zz.db8.2001.rpz-ip",
+		},
+		"ipv6_compressed_end": {
+			prefix: netip.MustParsePrefix("2001:db8::/32"),
+			owner:  "32.zz.db8.2001.rpz-ip",
+		},
+		"ipv6_not_compressed": {
+			prefix: netip.MustParsePrefix("2001:db8:1:2:3:4:5:6/128"),
+			owner:  "128.6.5.4.3.2.1.db8.2001.rpz-ip",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			owner := rpzIPTrigger(testCase.prefix)
+
+			assert.Equal(t, testCase.owner, owner)
+		})
+	}
+}
+
+func Test_exportRPZ(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "blocklists.rpz")
+	const serial = 1700000000
+	hostnames := []string{"example.com", "example.org", "example.com"}
+	ips := []netip.Addr{netip.MustParseAddr("::ffff:192.0.2.1")}
+	ipPrefixes := []netip.Prefix{netip.MustParsePrefix("2001:db8::/32")}
+
+	err := exportRPZ(path, serial, hostnames, ips, ipPrefixes)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	const expected = `$TTL 300
+@ IN SOA localhost. root.localhost. 1700000000 3600 600 86400 300
+@ IN NS localhost.
+example.com CNAME .
+*.example.com CNAME .
+example.org CNAME .
+*.example.org CNAME .
+32.1.2.0.192.rpz-ip CNAME .
+32.zz.db8.2001.rpz-ip CNAME .
+`
+	assert.Equal(t, expected, string(data))
+}
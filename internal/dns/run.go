@@ -3,20 +3,29 @@ package dns
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
 )
 
 func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 	defer close(done)
 
-	if *l.GetSettings().KeepNameserver {
+	settings := l.GetSettings()
+	switch {
+	case *settings.KeepNameserver:
 		l.logger.Warn("⚠️⚠️⚠️  keeping the default container nameservers, " +
 			"this will likely leak DNS traffic outside the VPN " +
 			"and go through your container network DNS outside the VPN tunnel!")
-	} else {
+	case (*settings.BootstrapPlaintext && !*settings.Strict) || !*settings.DoT.Enabled:
 		const fallback = false
 		l.useUnencryptedDNS(fallback)
+	default:
+		// Point to the DNS over TLS server address even if it is not
+		// listening yet, so no DNS query is sent in plaintext.
+		l.logger.Info("hostnames cannot be resolved until the DNS over TLS server is ready")
+		l.useDNSServer(settings)
 	}
 
 	select {
@@ -25,6 +34,13 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		return
 	}
 
+	l.waitForTunnelOnStart(ctx)
+
+	// Setup failures are retried quietly until the DNS over TLS
+	// server is ready for the first time or the grace period ends.
+	graceEnd := l.timeNow().Add(*l.GetSettings().StartupGrace)
+	ready := false
+
 	for ctx.Err() == nil {
 		// Upper scope variables for the DNS over TLS server only
 		// Their values are to be used if DOT=off
@@ -33,15 +49,36 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 		settings := l.GetSettings()
 		for !*settings.KeepNameserver && *settings.DoT.Enabled {
 			var err error
+			l.logRecorder.newGeneration()
 			runError, err = l.setupServer(ctx)
 			if err == nil {
-				l.backoffTime = defaultBackoffTime
-				l.logger.Info("ready")
-				l.signalOrSetStatus(constants.Running)
+				l.detailsMu.Lock()
+				l.runningSince = l.timeNow()
+				l.permanentErr = ""
+				wasFallback := l.fallback
+				l.fallback = false
+				l.detailsMu.Unlock()
+				if wasFallback {
+					l.publish(models.DNSEventRestored)
+				}
+				ready = true
+				l.logEvent(l.logger.Info, eventRecord{Event: "ready", Message: "ready"})
+				status := constants.Running
+				if l.paused.Load() {
+					// Keep the pause across restarts of the DNS server.
+					status = constants.Paused
+				}
+				l.signalOrSetStatus(status)
 				break
 			}
 
+			if ctx.Err() == nil && !ready && l.waitStartupRetry(ctx, err, graceEnd) {
+				settings = l.GetSettings()
+				continue
+			}
+
 			l.signalOrSetStatus(constants.Crashed)
+			l.metrics.crashesInc()
 
 			if ctx.Err() != nil {
 				return
@@ -51,6 +88,18 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 				const fallback = true
 				l.useUnencryptedDNS(fallback)
 			}
+			if errors.Is(err, errMisconfigured) {
+				// Retrying quickly would not fix the error,
+				// so report it and wait the maximum backoff.
+				l.logger.Error(err.Error())
+				l.logger.Error("this error cannot be fixed by retrying, " +
+					"please check your DNS settings")
+				l.detailsMu.Lock()
+				l.permanentErr = err.Error()
+				l.backoffTime = *settings.MaxBackoff
+				l.detailsMu.Unlock()
+				err = nil // already logged
+			}
 			l.logAndWait(ctx, err)
 			settings = l.GetSettings()
 		}
@@ -70,6 +119,31 @@ func (l *Loop) Run(ctx context.Context, done chan<- struct{}) {
 	}
 }
 
+const startupRetryWait = time.Second
+
+// waitStartupRetry logs the setup error given at the debug level and
+// waits before retrying, if the startup grace period is not over and
+// the error can be fixed by retrying. It returns false otherwise.
+func (l *Loop) waitStartupRetry(ctx context.Context, err error,
+	graceEnd time.Time) (retry bool) {
+	remaining := graceEnd.Sub(l.timeNow())
+	if remaining <= 0 || errors.Is(err, errMisconfigured) {
+		return false
+	}
+
+	l.logger.Debug(err.Error())
+	l.logger.Info("DNS over TLS server not ready yet, retrying during the startup grace period")
+	timer := time.NewTimer(min(startupRetryWait, remaining))
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		if !timer.Stop() {
+			<-timer.C
+		}
+	}
+	return true
+}
+
 func (l *Loop) runWait(ctx context.Context, runError <-chan error) (exitLoop bool) {
 	for {
 		select {
@@ -82,6 +156,7 @@ func (l *Loop) runWait(ctx context.Context, runError <-chan error) (exitLoop boo
 			l.logger.Info("stopping")
 			const fallback = false
 			l.useUnencryptedDNS(fallback)
+			l.drainServer()
 			l.stopServer()
 			l.stopped <- struct{}{}
 		case <-l.start:
@@ -90,6 +165,7 @@ func (l *Loop) runWait(ctx context.Context, runError <-chan error) (exitLoop boo
 			return false
 		case err := <-runError: // unexpected error
 			l.statusManager.SetStatus(constants.Crashed)
+			l.metrics.crashesInc()
 			const fallback = true
 			l.useUnencryptedDNS(fallback)
 			l.logAndWait(ctx, err)
@@ -98,9 +174,35 @@ func (l *Loop) runWait(ctx context.Context, runError <-chan error) (exitLoop boo
 	}
 }
 
+// drainServer refuses new queries and waits for the queries in
+// flight to complete, for at most the stop grace duration.
+func (l *Loop) drainServer() {
+	grace := *l.GetSettings().StopGrace
+	if grace == 0 || l.drainer == nil {
+		return
+	}
+
+	drained := l.drainer.drain(grace)
+	if !drained {
+		l.logger.Warn("queries still in flight after " + grace.String() +
+			", stopping DoT server anyway")
+	}
+}
+
 func (l *Loop) stopServer() {
 	stopErr := l.server.Stop()
 	if stopErr != nil {
 		l.logger.Error("stopping DoT server: " + stopErr.Error())
 	}
+	l.swapMu.Lock()
+	l.swapper = nil
+	l.swapMu.Unlock()
+	// Queries handed over to the backend server were
+	// already drained through the stopped server.
+	const grace = 0
+	l.stopBackend(l.takeBackend(), grace)
+	l.closeRelay()
+	const ipv6 = false
+	l.setUpstreams(nil, ipv6)
+	l.setCaches(nil)
 }
@@ -2,72 +2,271 @@ package dns
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 
 	"github.com/qdm12/dns/v2/pkg/dot"
 	cachemiddleware "github.com/qdm12/dns/v2/pkg/middlewares/cache"
 	"github.com/qdm12/dns/v2/pkg/middlewares/cache/lru"
-	filtermiddleware "github.com/qdm12/dns/v2/pkg/middlewares/filter"
 	"github.com/qdm12/dns/v2/pkg/middlewares/filter/mapfilter"
+	logmiddleware "github.com/qdm12/dns/v2/pkg/middlewares/log"
+	"github.com/qdm12/dns/v2/pkg/middlewares/substituter"
 	"github.com/qdm12/dns/v2/pkg/provider"
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
 
 func (l *Loop) GetSettings() (settings settings.DNS) { return l.state.GetSettings() }
 
+// useIPv6 returns true if IPv6 should be used for DNS,
+// depending on the settings and the container IPv6 support.
+func (l *Loop) useIPv6(settings settings.DNS) bool {
+	switch settings.IPv6 {
+	case "on":
+		return true
+	case "auto":
+		return l.ipv6Supported
+	default:
+		return false
+	}
+}
+
 func (l *Loop) SetSettings(ctx context.Context, settings settings.DNS) (
-	outcome string) {
+	outcome string, err error) {
+	err = l.validateServerSettings(settings)
+	if err != nil {
+		return "", err
+	}
+
+	if l.onlyProvidersChanged(settings) {
+		err = l.swapProviders(ctx, settings)
+		if err == nil {
+			l.state.SetSettingsLive(settings)
+			return "settings applied without restarting the DNS server", nil
+		} else if !errors.Is(err, ErrDNSServerNotRunning) {
+			l.logger.Warn("switching DNS over TLS providers without restarting: " +
+				err.Error() + ", restarting instead")
+		}
+	}
+
 	return l.state.SetSettings(ctx, settings)
 }
 
-func buildDoTSettings(settings settings.DNS,
-	filter *mapfilter.Filter, logger Logger) (
+// onlyProvidersChanged returns true if the settings given only
+// differ from the current settings by their DNS over TLS providers.
+func (l *Loop) onlyProvidersChanged(settings settings.DNS) bool {
+	current := l.GetSettings()
+	if !*settings.DoT.Enabled || *settings.KeepNameserver ||
+		slices.Equal(settings.DoT.Providers, current.DoT.Providers) {
+		return false
+	}
+	settings = settings.Copy()
+	settings.DoT.Providers = current.DoT.Providers
+	return reflect.DeepEqual(settings, current)
+}
+
+// validateServerSettings validates the settings given and checks
+// the DNS over TLS server can be created with them, without starting
+// it, so settings breaking the DNS server are refused before the
+// DNS server is restarted with them.
+func (l *Loop) validateServerSettings(settings settings.DNS) (err error) {
+	err = settings.Validate()
+	if err != nil {
+		return fmt.Errorf("validating settings: %w", err)
+	}
+
+	if !*settings.DoT.Enabled || *settings.KeepNameserver {
+		return nil
+	}
+
+	_, err = l.newServer(settings, settings.DoT.GetProviders(),
+		&serverCaches{}, &drainMiddleware{}, &swapMiddleware{})
+	return err
+}
+
+func buildDoTSettings(settings settings.DNS, upstreams []provider.Provider, ipv6 bool,
+	filter *mapfilter.Filter, metrics *metrics, queryLogger *queryLogger,
+	cacheTracker *cacheTracker, dialTracker *dialTracker, statusTexts func() []string,
+	caches *serverCaches, drainer *drainMiddleware, swapper *swapMiddleware,
+	logger Logger) (
 	dotSettings dot.ServerSettings, err error) {
-	var middlewares []dot.Middleware
+	middlewares := []dot.Middleware{
+		&latencyMiddleware{histogram: metrics.upstreamLatency},
+	}
+
+	// Forwarded queries bypass the DoT upstream resolvers, but are
+	// still subject to DNSSEC, caching, filtering and static records.
+	if len(settings.ForwardZones) > 0 {
+		forwardMiddleware, err := newForwardMiddleware(settings.ForwardZones,
+			ipv6, logger)
+		if err != nil {
+			return dot.ServerSettings{}, fmt.Errorf("creating forward middleware: %w", err)
+		}
+		middlewares = append(middlewares, forwardMiddleware)
+	}
+
+	middlewares = append(middlewares,
+		&dnssecMiddleware{enabled: *settings.DNSSEC, logger: logger})
+
+	if !*settings.EDNSClientSubnet {
+		middlewares = append(middlewares, &ecsMiddleware{})
+	}
+
+	// TTL clamping is inner to the caches, so cached
+	// records expire according to the clamped TTL.
+	if *settings.DoT.TTLMin > 0 || *settings.DoT.TTLMax > 0 {
+		middlewares = append(middlewares,
+			newTTLMiddleware(*settings.DoT.TTLMin, *settings.DoT.TTLMax))
+	}
 
 	if *settings.DoT.Caching {
-		lruCache, err := lru.New(lru.Settings{})
+		// The cache tracker middlewares surround the caches
+		// to tell if resolve queries are answered from cache.
+		innerTracker, outerTracker := cacheTracker.middlewares()
+
+		// Serving expired responses is inner to the cache tracker,
+		// so expired responses answered are counted as cache misses.
+		if *settings.DoT.ServeExpired {
+			caches.stale = newStaleMiddleware(int(*settings.DoT.CacheSize),
+				*settings.DoT.ServeExpiredTTL, *settings.DoT.ServeExpiredClientTimeout)
+			middlewares = append(middlewares, caches.stale)
+		}
+
+		middlewares = append(middlewares, innerTracker)
+
+		// The LRU cache does not store negative responses,
+		// so they are cached by a separate middleware.
+		if *settings.DoT.NegativeCacheSize > 0 {
+			caches.negative = newNegativeCacheMiddleware(
+				int(*settings.DoT.NegativeCacheSize),
+				*settings.DoT.NegativeTTLMin, *settings.DoT.NegativeTTLMax)
+			middlewares = append(middlewares, caches.negative)
+		}
+
+		lruSettings := lru.Settings{
+			MaxEntries: int(*settings.DoT.CacheSize),
+		}
+		lruCache, err := lru.New(lruSettings)
 		if err != nil {
 			return dot.ServerSettings{}, fmt.Errorf("creating LRU cache: %w", err)
 		}
+		// The flush cache replaces the LRU cache with a new one to
+		// flush all the cached responses, so it wraps the LRU cache.
+		caches.responses = newFlushCache(lruCache, int(*settings.DoT.CacheSize),
+			func() cachemiddleware.Cache {
+				// The LRU settings are valid since the first
+				// LRU cache was created with them.
+				lruCache, _ := lru.New(lruSettings)
+				return lruCache
+			})
+		var cache cachemiddleware.Cache = caches.responses
+		if *settings.DoT.CachePrefetch {
+			// The prefetch middleware must be right before the cache
+			// middleware, to refresh responses through the handler
+			// the cache middleware wraps.
+			refresher := &prefetchMiddleware{}
+			middlewares = append(middlewares, refresher)
+			cache = newPrefetchCache(caches.responses, int(*settings.DoT.CacheSize), refresher)
+		}
 		cacheMiddleware, err := cachemiddleware.New(cachemiddleware.Settings{
-			Cache: lruCache,
+			Cache: cache,
 		})
 		if err != nil {
 			return dot.ServerSettings{}, fmt.Errorf("creating cache middleware: %w", err)
 		}
-		middlewares = append(middlewares, cacheMiddleware)
+		middlewares = append(middlewares, cacheMiddleware, outerTracker)
 	}
 
-	filterMiddleware, err := filtermiddleware.New(filtermiddleware.Settings{
-		Filter: filter,
+	middlewares = append(middlewares, &filterMiddleware{
+		filter:        filter,
+		blockResponse: settings.DoT.Blacklist.BlockResponse,
+	})
+
+	// DNS64 is after the filter so the A queries it sends
+	// to synthesize AAAA records are filtered as well.
+	if *settings.DNS64.Enabled {
+		middlewares = append(middlewares, &dns64Middleware{prefix: settings.DNS64.Prefix})
+	}
+
+	// Substituter is the last middleware so static records
+	// are answered before being filtered or cached.
+	substituterMiddleware, err := substituter.New(substituter.Settings{
+		Substitutions: recordsToSubstitutions(settings.Records),
 	})
 	if err != nil {
-		return dot.ServerSettings{}, fmt.Errorf("creating filter middleware: %w", err)
+		return dot.ServerSettings{}, fmt.Errorf("creating substituter middleware: %w", err)
 	}
-	middlewares = append(middlewares, filterMiddleware)
+	middlewares = append(middlewares, substituterMiddleware)
 
-	providersData := provider.NewProviders()
-	providers := make([]provider.Provider, len(settings.DoT.Providers))
-	for i := range settings.DoT.Providers {
-		var err error
-		providers[i], err = providersData.Get(settings.DoT.Providers[i])
-		if err != nil {
-			panic(err) // this should already had been checked
-		}
+	if *settings.StatusDomain != "" {
+		middlewares = append(middlewares,
+			newStatusMiddleware(*settings.StatusDomain, statusTexts))
 	}
 
+	logMiddleware, err := logmiddleware.New(logmiddleware.Settings{
+		Logger: queryLogger,
+	})
+	if err != nil {
+		return dot.ServerSettings{}, fmt.Errorf("creating log middleware: %w", err)
+	}
+	middlewares = append(middlewares, logMiddleware)
+
+	// Access control is outer to the log middleware,
+	// so refused queries from other hosts are not logged.
+	middlewares = append(middlewares,
+		&accessMiddleware{allowedSubnets: settings.AllowedSubnets})
+
+	// Drain is the last middleware so new queries are
+	// refused as early as possible when draining.
+	middlewares = append(middlewares, drainer)
+
+	// Swap is outer to the drain middleware, so queries handed
+	// over to another server are still tracked when draining.
+	middlewares = append(middlewares, swapper)
+
 	ipVersion := "ipv4"
-	if *settings.DoT.IPv6 {
+	if ipv6 {
 		ipVersion = "ipv6"
 	}
 	return dot.ServerSettings{
 		Resolver: dot.ResolverSettings{
-			UpstreamResolvers: providers,
+			UpstreamResolvers: upstreams,
 			IPVersion:         ipVersion,
 			Warner:            logger,
+			Metrics:           dialTracker,
 		},
-		Middlewares: middlewares,
-		Logger:      logger,
+		ListeningAddress: ptrTo(settings.ListeningAddress),
+		Middlewares:      middlewares,
+		Logger:           logger,
 	}, nil
 }
+
+// recordsToSubstitutions groups the records by hostname and IP version,
+// to produce one A and/or one AAAA substitution per hostname.
+func recordsToSubstitutions(records []settings.DNSRecord) (
+	substitutions []substituter.Substitution) {
+	type key struct {
+		hostname string
+		ipv6     bool
+	}
+	keyToIndex := make(map[key]int, len(records))
+	for _, record := range records {
+		k := key{hostname: record.Hostname, ipv6: record.IP.Is6()}
+		index, ok := keyToIndex[k]
+		if !ok {
+			recordType := "A"
+			if k.ipv6 {
+				recordType = "AAAA"
+			}
+			index = len(substitutions)
+			keyToIndex[k] = index
+			substitutions = append(substitutions, substituter.Substitution{
+				Name: record.Hostname,
+				Type: recordType,
+			})
+		}
+		substitutions[index].IPs = append(substitutions[index].IPs, record.IP)
+	}
+	return substitutions
+}
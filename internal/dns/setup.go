@@ -4,55 +4,219 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
+	"strings"
+	"time"
 
-	"github.com/qdm12/dns/v2/pkg/check"
 	"github.com/qdm12/dns/v2/pkg/dot"
-	"github.com/qdm12/dns/v2/pkg/nameserver"
+	"github.com/qdm12/dns/v2/pkg/provider"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
 )
 
-var errUpdateBlockLists = errors.New("cannot update filter block lists")
+var (
+	errUpdateBlockLists = errors.New("cannot update filter block lists")
+	// errMisconfigured is wrapped by setup errors which cannot
+	// be fixed by retrying, such as settings errors.
+	errMisconfigured  = errors.New("DNS over TLS server is misconfigured")
+	errStartupTimeout = errors.New("DNS over TLS server setup timed out")
+)
 
 func (l *Loop) setupServer(ctx context.Context) (runError <-chan error, err error) {
-	err = l.updateFiles(ctx)
+	err = l.waitForEgress(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", errUpdateBlockLists, err)
+		return nil, err
+	}
+
+	// The startup timeout starts once traffic can egress, and the
+	// background block lists update can outlive the setup, so it
+	// uses the context without the timeout.
+	backgroundCtx := ctx
+	if startupTimeout := *l.GetSettings().StartupTimeout; startupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, startupTimeout)
+		defer cancel()
+		start := l.timeNow()
+		defer func() {
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: aborted after %s: %w", errStartupTimeout,
+					l.timeSince(start).Round(time.Millisecond), err)
+			}
+		}()
+	}
+
+	if l.loadCachedBlockLists() {
+		go l.updateFilesInBackground(backgroundCtx)
+	} else {
+		err = l.updateFiles(ctx)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrTunnelNotUp):
+			// Set up the server with the custom block lists only,
+			// and build the block lists at the next update.
+			l.logger.Warn(err.Error())
+		default:
+			return nil, fmt.Errorf("%w: %w", errUpdateBlockLists, err)
+		}
 	}
 
 	settings := l.GetSettings()
 
-	dotSettings, err := buildDoTSettings(settings, l.filter, l.logger)
+	// Try all the providers together first, and then each
+	// provider alone in order if there is more than one provider.
+	providerSets := [][]string{settings.DoT.Providers}
+	if len(settings.DoT.Providers) > 1 {
+		for _, provider := range settings.DoT.Providers {
+			providerSets = append(providerSets, []string{provider})
+		}
+	}
+
+	for i, providers := range providerSets {
+		settings.DoT.Providers = providers
+		runError, err = l.startServer(ctx, settings)
+		if err == nil {
+			l.logEvent(l.logger.Info, eventRecord{
+				Event:     "providers_selected",
+				Message:   "using DNS over TLS providers: " + strings.Join(providers, ", "),
+				Providers: providers,
+			})
+			l.setUpstreams(settings.DoT.GetProviders(), l.useIPv6(settings))
+			return runError, nil
+		}
+
+		isLast := i == len(providerSets)-1
+		if ctx.Err() != nil || isLast {
+			break
+		}
+		l.logger.Warn(err.Error())
+		l.logger.Info("trying DNS over TLS provider " + providerSets[i+1][0])
+	}
+
+	return nil, err
+}
+
+func (l *Loop) startServer(ctx context.Context, settings settings.DNS) (
+	runError <-chan error, err error) {
+	l.queryLogger.enabled.Store(*settings.DoT.QueryLog)
+
+	settings.ForwardZones = l.withBypassZones(settings)
+
+	upstreams, err := l.relayUpstreams(settings)
 	if err != nil {
-		return nil, fmt.Errorf("building DoT settings: %w", err)
+		return nil, err
 	}
 
-	server, err := dot.NewServer(dotSettings)
+	caches := &serverCaches{}
+	drainer := &drainMiddleware{}
+	swapper := &swapMiddleware{}
+	server, err := l.newServer(settings, upstreams, caches, drainer, swapper)
 	if err != nil {
-		return nil, fmt.Errorf("creating DoT server: %w", err)
+		l.closeRelay()
+		return nil, err
 	}
 
 	runError, err = server.Start()
 	if err != nil {
+		l.closeRelay()
 		return nil, fmt.Errorf("starting server: %w", err)
 	}
 	l.server = server
+	l.drainer = drainer
+	l.swapMu.Lock()
+	l.swapper = swapper
+	l.swapMu.Unlock()
+	l.setCaches(caches)
 
-	// use internal DNS server
-	nameserver.UseDNSInternally(nameserver.SettingsInternalDNS{
-		IP: settings.ServerAddress,
-	})
-	err = nameserver.UseDNSSystemWide(nameserver.SettingsSystemDNS{
-		IP:         settings.ServerAddress,
-		ResolvPath: l.resolvConf,
-	})
-	if err != nil {
-		l.logger.Error(err.Error())
-	}
+	l.useDNSServer(settings)
 
-	err = check.WaitForDNS(ctx, check.Settings{})
+	err = waitForDNS(ctx, settings)
 	if err != nil {
 		l.stopServer()
+		if len(settings.DoT.ServerNames) > 0 {
+			l.logger.Warn("TLS server names are set for DNS over TLS providers, " +
+				"check they match the providers certificates since " +
+				"TLS handshakes fail otherwise")
+		}
 		return nil, err
 	}
 
 	return runError, nil
 }
+
+// relayUpstreams returns the DNS over TLS upstream resolvers, with
+// their addresses replaced by local relays to the SOCKS5 upstream
+// proxy if it is set. Relays from a previous start are closed.
+func (l *Loop) relayUpstreams(settings settings.DNS) (
+	upstreams []provider.Provider, err error) {
+	l.closeRelay()
+	upstreams = settings.DoT.GetProviders()
+	if *settings.UpstreamProxy == "" {
+		return upstreams, nil
+	}
+
+	relay, err := newSOCKSRelay(*settings.UpstreamProxy, l.logger)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errMisconfigured, err)
+	}
+	upstreams, err = relay.relayProviders(upstreams)
+	if err != nil {
+		relay.close()
+		return nil, fmt.Errorf("relaying through SOCKS5 proxy: %w", err)
+	}
+	l.relay = relay
+	return upstreams, nil
+}
+
+func (l *Loop) closeRelay() {
+	if l.relay == nil {
+		return
+	}
+	l.relay.close()
+	l.relay = nil
+}
+
+// newServer creates the DNS over TLS server without starting it,
+// using the upstream resolvers given.
+func (l *Loop) newServer(settings settings.DNS, upstreams []provider.Provider,
+	caches *serverCaches, drainer *drainMiddleware, swapper *swapMiddleware) (
+	server *dot.Server, err error) {
+	dotSettings, err := buildDoTSettings(settings, upstreams, l.useIPv6(settings), l.filter,
+		l.metrics, l.queryLogger, l.cacheTracker, l.dialTracker, l.statusTexts,
+		caches, drainer, swapper, l.logger)
+	if err != nil {
+		return nil, fmt.Errorf("%w: building DoT settings: %w", errMisconfigured, err)
+	}
+
+	server, err = dot.NewServer(dotSettings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: creating DoT server: %w", errMisconfigured, err)
+	}
+	return server, nil
+}
+
+// useDNSServer sets the internal address, or the internal resolver
+// if it is set, as the nameserver for the Go program, and the
+// resolv.conf address as the nameserver system wide.
+func (l *Loop) useDNSServer(settings settings.DNS) {
+	l.detailsMu.Lock()
+	l.plaintextAddress = netip.AddrPort{}
+	l.detailsMu.Unlock()
+
+	const encrypted = true
+	port := settings.ListeningPort()
+	l.useDNSInternally(settings, netip.AddrPortFrom(settings.InternalAddress, port),
+		0, encrypted)
+	err := l.useDNSSystemWide(settings.ResolvConfAddress)
+	const defaultDNSPort = 53
+	if port != defaultDNSPort {
+		l.logger.Warn(fmt.Sprintf("resolv.conf cannot specify port %d, so programs "+
+			"other than gluetun use port %d to reach %s",
+			port, defaultDNSPort, settings.ResolvConfAddress))
+	}
+	if err == nil && l.useIPv6(settings) && settings.ResolvConfAddress.IsLoopback() &&
+		settings.ResolvConfAddress.Is4() && listensOnIPv6Loopback(settings.ListeningAddress) {
+		err = addNameserver(l.resolvConf, netip.IPv6Loopback())
+		if err != nil {
+			l.logger.Error(err.Error())
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/qdm12/dns/v2/pkg/provider"
+	"golang.org/x/net/proxy"
+)
+
+// socksRelay relays TCP connections to the DNS over TLS upstream
+// resolvers through a SOCKS5 proxy, since the DNS over TLS server
+// dials its upstream resolvers itself. Each upstream address gets a
+// local listener forwarding its connections as they are, so the TLS
+// handshake and certificate verification remain end to end.
+type socksRelay struct {
+	dialer    proxy.ContextDialer
+	logger    Logger
+	listeners []net.Listener
+	wg        sync.WaitGroup
+}
+
+var errSOCKSDialerNotContext = errors.New("SOCKS5 dialer does not support contexts")
+
+func newSOCKSRelay(proxyURL string, logger Logger) (relay *socksRelay, err error) {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		// Settings should be validated before, so an error
+		// happening here is a programming error.
+		panic(err)
+	}
+
+	var auth *proxy.Auth
+	if parsed.User != nil {
+		password, _ := parsed.User.Password()
+		auth = &proxy.Auth{
+			User:     parsed.User.Username(),
+			Password: password,
+		}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", parsed.Host, auth, proxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("creating SOCKS5 dialer: %w", err)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("%w", errSOCKSDialerNotContext)
+	}
+
+	return &socksRelay{
+		dialer: contextDialer,
+		logger: logger,
+	}, nil
+}
+
+// relayProviders starts a local listener for each DNS over TLS address
+// of the providers given, and returns copies of the providers with their
+// addresses replaced by the local listeners addresses.
+func (r *socksRelay) relayProviders(providers []provider.Provider) (
+	relayed []provider.Provider, err error) {
+	relayed = make([]provider.Provider, len(providers))
+	for i, upstream := range providers {
+		relayed[i] = upstream
+		relayed[i].DoT.IPv4, err = r.listenAll(upstream.DoT.IPv4)
+		if err != nil {
+			return nil, err
+		}
+		relayed[i].DoT.IPv6, err = r.listenAll(upstream.DoT.IPv6)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return relayed, nil
+}
+
+func (r *socksRelay) listenAll(targets []netip.AddrPort) (
+	addresses []netip.AddrPort, err error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	addresses = make([]netip.AddrPort, len(targets))
+	for i, target := range targets {
+		addresses[i], err = r.listen(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return addresses, nil
+}
+
+func (r *socksRelay) listen(target netip.AddrPort) (address netip.AddrPort, err error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return address, fmt.Errorf("listening to relay %s: %w", target, err)
+	}
+	r.listeners = append(r.listeners, listener)
+
+	r.wg.Add(1)
+	go r.serve(listener, target)
+
+	return listener.Addr().(*net.TCPAddr).AddrPort(), nil //nolint:forcetypeassert
+}
+
+func (r *socksRelay) serve(listener net.Listener, target netip.AddrPort) {
+	defer r.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			// listener closed
+			return
+		}
+		go r.relay(conn, target)
+	}
+}
+
+// relay forwards the connection given to the target address through
+// the SOCKS5 proxy. If the proxy cannot be reached, the connection is
+// closed so the DNS over TLS query fails as if the upstream resolver
+// was not reachable.
+func (r *socksRelay) relay(conn net.Conn, target netip.AddrPort) {
+	defer conn.Close()
+
+	const dialTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	upstream, err := r.dialer.DialContext(ctx, "tcp", target.String())
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("dialing %s through SOCKS5 proxy: %s", target, err))
+		return
+	}
+	defer upstream.Close()
+
+	// Close both connections as soon as one direction ends.
+	done := make(chan struct{}, 2) //nolint:gomnd
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// close closes the local listeners. Connections being relayed
+// end when the DNS over TLS server closes them.
+func (r *socksRelay) close() {
+	for _, listener := range r.listeners {
+		_ = listener.Close()
+	}
+	r.wg.Wait()
+}
@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// sourceError is the download error of a block list source
+// at the last block lists update.
+type sourceError struct {
+	message string
+	// since is the time of the first update the source
+	// failed at since it last succeeded.
+	since time.Time
+}
+
+// recordSourceErrors records the download errors of each source given
+// at the update time given, replacing the errors recorded previously,
+// so sources downloaded without error and sources not configured
+// anymore have their error cleared.
+func (l *Loop) recordSourceErrors(sources []blockListSource, updateTime time.Time) {
+	errs := make(map[string]sourceError, len(sources))
+
+	l.blockListsMu.Lock()
+	defer l.blockListsMu.Unlock()
+	for _, source := range sources {
+		previous, failed := l.sourceErrors[source.name]
+		if len(source.result.Errors) == 0 {
+			if failed {
+				l.logger.Info(source.name + " block lists downloaded without error again, " +
+					"after failing since " + previous.since.Format(time.RFC3339))
+			}
+			continue
+		}
+
+		// Errors come from concurrent downloads, so sort them
+		// to have a stable error message across updates.
+		messages := make([]string, len(source.result.Errors))
+		for i, err := range source.result.Errors {
+			messages[i] = err.Error()
+		}
+		sort.Strings(messages)
+		since := updateTime
+		if failed {
+			since = previous.since
+		}
+		errs[source.name] = sourceError{
+			message: strings.Join(messages, "; "),
+			since:   since,
+		}
+	}
+	l.sourceErrors = errs
+}
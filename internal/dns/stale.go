@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// staleMiddleware keeps the last successful response of each query to
+// answer it once expired, as described in RFC 8767, if the upstream
+// resolvers fail to answer or take longer than the client timeout to
+// answer. In the latter case, the upstream answer is still awaited in
+// the background to refresh the response kept. Expired responses are
+// kept up to the maximum stale duration given after their expiry.
+// Once stopped, queries are answered by the upstream resolvers only.
+type staleMiddleware struct {
+	maxEntries    int
+	maxStale      time.Duration
+	clientTimeout time.Duration
+	timeNow       func() time.Time
+	background    backgroundQueries
+
+	mutex   sync.Mutex
+	entries map[string]staleEntry
+}
+
+type staleEntry struct {
+	response *dns.Msg
+	expiry   time.Time
+}
+
+// newStaleMiddleware returns a serve expired middleware. The client
+// timeout must be strictly positive, since the upstream answer would
+// otherwise never be awaited before answering the stale response.
+func newStaleMiddleware(maxEntries int,
+	maxStale, clientTimeout time.Duration) *staleMiddleware {
+	return &staleMiddleware{
+		maxEntries:    maxEntries,
+		maxStale:      maxStale,
+		clientTimeout: clientTimeout,
+		timeNow:       time.Now,
+		entries:       make(map[string]staleEntry),
+	}
+}
+
+func (m *staleMiddleware) String() string {
+	return "serve expired"
+}
+
+func (m *staleMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if len(request.Question) != 1 || !m.background.start() {
+			next.ServeDNS(w, request)
+			return
+		}
+
+		key := prefetchKey(request.Question[0])
+		responses := make(chan *dns.Msg, 1)
+		go func() {
+			defer m.background.done()
+			recorder := &responseRecorder{}
+			next.ServeDNS(recorder, request)
+			m.add(key, recorder.response)
+			responses <- recorder.response
+		}()
+
+		timer := time.NewTimer(m.clientTimeout)
+		defer timer.Stop()
+		var response *dns.Msg
+		select {
+		case response = <-responses:
+			if response != nil && response.Rcode != dns.RcodeServerFailure {
+				_ = w.WriteMsg(response)
+				return
+			}
+		case <-timer.C:
+		}
+
+		stale := m.get(key, request)
+		if stale != nil {
+			_ = w.WriteMsg(stale)
+			return
+		}
+
+		if response == nil {
+			// Client timeout elapsed without stale response to answer.
+			response = <-responses
+		}
+		if response != nil {
+			_ = w.WriteMsg(response)
+		}
+	})
+}
+
+// Stop waits for the upstream answers awaited in the background.
+func (m *staleMiddleware) Stop() (err error) {
+	m.background.stop()
+	return nil
+}
+
+// get returns the response kept for the key given, or nil if
+// there is none or it expired for longer than the maximum stale
+// duration.
+func (m *staleMiddleware) get(key string, request *dns.Msg) (response *dns.Msg) {
+	now := m.timeNow()
+
+	m.mutex.Lock()
+	entry, ok := m.entries[key]
+	if ok && now.Sub(entry.expiry) > m.maxStale {
+		delete(m.entries, key)
+		ok = false
+	}
+	m.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+
+	// RFC 8767 recommends a TTL of 30 seconds for stale records,
+	// so clients retry resolving them shortly.
+	const staleTTL = 30
+	response = entry.response.Copy()
+	response.Id = request.Id
+	for _, rr := range response.Answer {
+		rr.Header().Ttl = staleTTL
+	}
+	return response
+}
+
+func (m *staleMiddleware) add(key string, response *dns.Msg) {
+	if response == nil || response.Rcode != dns.RcodeSuccess ||
+		len(response.Answer) == 0 {
+		return
+	}
+	now := m.timeNow()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= m.maxEntries {
+		for key, entry := range m.entries {
+			if now.Sub(entry.expiry) > m.maxStale {
+				delete(m.entries, key)
+			}
+		}
+		if len(m.entries) >= m.maxEntries {
+			return
+		}
+	}
+	m.entries[key] = staleEntry{
+		response: response.Copy(),
+		expiry:   now.Add(minAnswerTTL(response)),
+	}
+}
+
+// flush removes the responses kept for the domain given and its
+// subdomains, or all the responses kept if the domain is empty.
+func (m *staleMiddleware) flush(domain string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for key := range m.entries {
+		if domain != "" && !keyInDomain(key, domain) {
+			continue
+		}
+		delete(m.entries, key)
+	}
+}
@@ -0,0 +1,186 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAnswer returns a response to the request given, with an
+// A record answer with the IP address and TTL given.
+func newAnswer(request *dns.Msg, ip net.IP, ttl uint32) *dns.Msg {
+	response := new(dns.Msg).SetReply(request)
+	response.Answer = []dns.RR{&dns.A{
+		Hdr: dns.RR_Header{
+			Name:   request.Question[0].Name,
+			Rrtype: dns.TypeA,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		},
+		A: ip,
+	}}
+	return response
+}
+
+func Test_staleMiddleware(t *testing.T) {
+	t.Parallel()
+
+	keptIP := net.IPv4(1, 1, 1, 1)
+	freshIP := net.IPv4(2, 2, 2, 2)
+	const keptTTL = 60
+	const maxStale = time.Hour
+	const clientTimeout = 10 * time.Millisecond
+
+	// upstream returns the handler answering with a fresh answer,
+	// after the delay given, or with the rcode given if not success.
+	upstream := func(rcode int, delay time.Duration) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+			time.Sleep(delay)
+			if rcode != dns.RcodeSuccess {
+				_ = w.WriteMsg(new(dns.Msg).SetRcode(request, rcode))
+				return
+			}
+			_ = w.WriteMsg(newAnswer(request, freshIP, keptTTL))
+		})
+	}
+
+	testCases := map[string]struct {
+		next    dns.Handler
+		kept    bool
+		elapsed time.Duration
+		rcode   int
+		ip      net.IP
+		ttl     uint32
+	}{
+		"upstream_success": {
+			next:  upstream(dns.RcodeSuccess, 0),
+			kept:  true,
+			rcode: dns.RcodeSuccess,
+			ip:    freshIP,
+			ttl:   keptTTL,
+		},
+		"upstream_failure_nothing_kept": {
+			next:  upstream(dns.RcodeServerFailure, 0),
+			rcode: dns.RcodeServerFailure,
+		},
+		"upstream_failure_answer_expired": {
+			next:    upstream(dns.RcodeServerFailure, 0),
+			kept:    true,
+			elapsed: time.Minute + time.Second,
+			rcode:   dns.RcodeSuccess,
+			ip:      keptIP,
+			ttl:     30,
+		},
+		"upstream_timeout_answer_expired": {
+			next:    upstream(dns.RcodeSuccess, 10*clientTimeout),
+			kept:    true,
+			elapsed: time.Minute + time.Second,
+			rcode:   dns.RcodeSuccess,
+			ip:      keptIP,
+			ttl:     30,
+		},
+		"upstream_timeout_nothing_kept": {
+			next:  upstream(dns.RcodeSuccess, 10*clientTimeout),
+			rcode: dns.RcodeSuccess,
+			ip:    freshIP,
+			ttl:   keptTTL,
+		},
+		"upstream_failure_answer_past_stale_window": {
+			next:    upstream(dns.RcodeServerFailure, 0),
+			kept:    true,
+			elapsed: time.Minute + maxStale + time.Second,
+			rcode:   dns.RcodeServerFailure,
+		},
+		"upstream_timeout_answer_past_stale_window": {
+			next:    upstream(dns.RcodeSuccess, 10*clientTimeout),
+			kept:    true,
+			elapsed: time.Minute + maxStale + time.Second,
+			rcode:   dns.RcodeSuccess,
+			ip:      freshIP,
+			ttl:     keptTTL,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			middleware := newStaleMiddleware(10, maxStale, clientTimeout) //nolint:gomnd
+			start := time.Unix(0, 0)
+			now := start
+			middleware.timeNow = func() time.Time { return now }
+			if testCase.kept {
+				middleware.add(prefetchKey(request.Question[0]),
+					newAnswer(request, keptIP, keptTTL))
+			}
+			now = start.Add(testCase.elapsed)
+
+			recorder := &responseRecorder{}
+			middleware.Wrap(testCase.next).ServeDNS(recorder, request)
+			err := middleware.Stop()
+			require.NoError(t, err)
+
+			response := recorder.response
+			require.NotNil(t, response)
+			assert.Equal(t, request.Id, response.Id)
+			assert.Equal(t, testCase.rcode, response.Rcode)
+			if testCase.ip == nil {
+				assert.Empty(t, response.Answer)
+				return
+			}
+			require.Len(t, response.Answer, 1)
+			answer := response.Answer[0].(*dns.A) //nolint:forcetypeassert
+			assert.True(t, testCase.ip.Equal(answer.A))
+			assert.Equal(t, testCase.ttl, answer.Hdr.Ttl)
+		})
+	}
+}
+
+func Test_staleMiddleware_Stop(t *testing.T) {
+	t.Parallel()
+
+	request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+	release := make(chan struct{})
+	next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		<-release
+		_ = w.WriteMsg(newAnswer(request, net.IPv4(2, 2, 2, 2), 60)) //nolint:gomnd
+	})
+	const clientTimeout = time.Millisecond
+	middleware := newStaleMiddleware(10, time.Hour, clientTimeout) //nolint:gomnd
+	middleware.add(prefetchKey(request.Question[0]),
+		newAnswer(request, net.IPv4(1, 1, 1, 1), 0))
+	handler := middleware.Wrap(next)
+
+	// The expired answer is answered after the client timeout,
+	// while the upstream answer is still awaited in the background.
+	recorder := &responseRecorder{}
+	handler.ServeDNS(recorder, request)
+	require.NotNil(t, recorder.response)
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = middleware.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the upstream answer awaited in the background")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	// Once stopped, queries are answered by the upstream only,
+	// without starting any goroutine in the background.
+	recorder = &responseRecorder{}
+	handler.ServeDNS(recorder, request)
+	require.NotNil(t, recorder.response)
+	answer := recorder.response.Answer[0].(*dns.A) //nolint:forcetypeassert
+	assert.True(t, net.IPv4(2, 2, 2, 2).Equal(answer.A))
+}
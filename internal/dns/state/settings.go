@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
@@ -14,33 +15,93 @@ func (s *State) GetSettings() (settings settings.DNS) {
 	return s.settings
 }
 
+// SetSettings validates and sets the settings given, and restarts
+// the loop if needed. It returns an error if the settings are not valid.
 func (s *State) SetSettings(ctx context.Context, settings settings.DNS) (
-	outcome string) {
+	outcome string, err error) {
+	err = settings.Validate()
+	if err != nil {
+		return "", fmt.Errorf("validating settings: %w", err)
+	}
+
 	s.settingsMu.Lock()
 
 	settingsUnchanged := reflect.DeepEqual(s.settings, settings)
 	if settingsUnchanged {
 		s.settingsMu.Unlock()
-		return "settings left unchanged"
+		return "settings left unchanged", nil
 	}
 
-	// Check for only update period change
-	tempSettings := s.settings.Copy()
-	*tempSettings.DoT.UpdatePeriod = *settings.DoT.UpdatePeriod
-	onlyUpdatePeriodChanged := reflect.DeepEqual(tempSettings, settings)
+	// Check if only settings used without restarting the DNS server changed
+	onlyLiveChanged := reflect.DeepEqual(withLiveFields(settings, s.settings), s.settings)
+	scheduleChanged := *settings.DoT.UpdatePeriod != *s.settings.DoT.UpdatePeriod ||
+		settings.DoT.UpdateSchedule != s.settings.DoT.UpdateSchedule ||
+		*settings.DoT.CacheStatsPeriod != *s.settings.DoT.CacheStatsPeriod
 
 	s.settings = settings
 	s.settingsMu.Unlock()
 
-	if onlyUpdatePeriodChanged {
-		s.updateTicker <- struct{}{}
-		return "update period changed"
+	if onlyLiveChanged {
+		if scheduleChanged {
+			s.updateTicker <- struct{}{}
+		}
+		return "settings applied without restarting the DNS server", nil
 	}
 
 	// Restart
 	_, _ = s.statusApplier.ApplyStatus(ctx, constants.Stopped)
-	if *settings.DoT.Enabled {
-		outcome, _ = s.statusApplier.ApplyStatus(ctx, constants.Running)
+	if !*settings.DoT.Enabled {
+		return "settings applied, DNS server stopped", nil
 	}
-	return outcome
+	outcome, _ = s.statusApplier.ApplyStatus(ctx, constants.Running)
+	return "settings applied, DNS server restarted: " + outcome, nil
+}
+
+// withLiveFields returns a copy of the settings given with the fields
+// used without restarting the DNS server set from the reference settings
+// given. These fields are used for block lists updates, for restarts
+// of the DNS server or for the control server, so they take effect
+// without restarting the DNS server.
+func withLiveFields(settings, reference settings.DNS) (copied settings.DNS) {
+	copied = settings.Copy()
+	copied.MaxBackoff = reference.MaxBackoff
+	copied.StableUptime = reference.StableUptime
+	copied.StartupGrace = reference.StartupGrace
+	copied.StartupTimeout = reference.StartupTimeout
+	copied.TunnelWaitTimeout = reference.TunnelWaitTimeout
+	copied.KeepOnReconnect = reference.KeepOnReconnect
+	copied.ReadinessTimeout = reference.ReadinessTimeout
+	copied.ReadinessRetryInterval = reference.ReadinessRetryInterval
+	copied.StopGrace = reference.StopGrace
+	copied.StatusPath = reference.StatusPath
+	copied.DoT.UpdatePeriod = reference.DoT.UpdatePeriod
+	copied.DoT.UpdateSchedule = reference.DoT.UpdateSchedule
+	copied.DoT.UpdateRetries = reference.DoT.UpdateRetries
+	copied.DoT.CacheStatsPeriod = reference.DoT.CacheStatsPeriod
+
+	blacklist := &copied.DoT.Blacklist
+	referenceBlacklist := reference.DoT.Blacklist
+	blacklist.CachePath = referenceBlacklist.CachePath
+	blacklist.CacheMaxAge = referenceBlacklist.CacheMaxAge
+	blacklist.MaxConcurrentDownloads = referenceBlacklist.MaxConcurrentDownloads
+	blacklist.MinEntries = referenceBlacklist.MinEntries
+	blacklist.DownloadTimeout = referenceBlacklist.DownloadTimeout
+	blacklist.MaxIdleConnsPerHost = referenceBlacklist.MaxIdleConnsPerHost
+	blacklist.IdleConnTimeout = referenceBlacklist.IdleConnTimeout
+	blacklist.RequireVPN = referenceBlacklist.RequireVPN
+	blacklist.SourceAddress = referenceBlacklist.SourceAddress
+	blacklist.MaxMalformedPercent = referenceBlacklist.MaxMalformedPercent
+	blacklist.LogMalformed = referenceBlacklist.LogMalformed
+	blacklist.PostUpdateHook = referenceBlacklist.PostUpdateHook
+	blacklist.PostUpdateHookTimeout = referenceBlacklist.PostUpdateHookTimeout
+	return copied
+}
+
+// SetSettingsLive sets the settings without restarting the loop.
+// It should only be used for settings changes already applied
+// to the running server by the caller.
+func (s *State) SetSettingsLive(settings settings.DNS) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.settings = settings
 }
@@ -3,6 +3,7 @@ package dns
 import (
 	"context"
 
+	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/models"
 )
 
@@ -10,7 +11,53 @@ func (l *Loop) GetStatus() (status models.LoopStatus) {
 	return l.statusManager.GetStatus()
 }
 
+// GetStatusDetail returns the loop status together with
+// details on the DNS server state.
+func (l *Loop) GetStatusDetail() (detail models.DNSStatus) {
+	detail.Status = l.statusManager.GetStatus()
+	detail.EDNSClientSubnet = *l.GetSettings().EDNSClientSubnet
+	detail.Upstreams = l.getUpstreams()
+	l.detailsMu.RLock()
+	defer l.detailsMu.RUnlock()
+	detail.PlaintextFallback = l.fallback
+	detail.Encrypted = l.encrypted
+	if l.plaintextAddress.IsValid() {
+		detail.PlaintextAddress = l.plaintextAddress.String()
+	}
+	detail.BackoffTime = l.backoffTime
+	detail.PermanentError = l.permanentErr
+	detail.ResolvConfError = l.resolvConfErr
+	detail.LastUpdate = l.lastUpdate
+	detail.BlockListCounts = l.blockListCounts
+	detail.LastEvent = l.getLastEvent()
+	return detail
+}
+
+// ApplyStatus applies the status given, which can be running, stopped
+// or paused. The paused status keeps the DNS server running but skips
+// its periodic block lists updates, until the running status is applied.
 func (l *Loop) ApplyStatus(ctx context.Context, status models.LoopStatus) (
 	outcome string, err error) {
+	switch status {
+	case constants.Paused:
+		return l.pause()
+	case constants.Running:
+		if l.userStopped.Load() {
+			return "stopped through the control server", nil
+		}
+		switch l.GetStatus() {
+		case constants.Paused:
+			return l.resume(ctx), nil
+		case constants.Stopped:
+			// Keep a pause restored on start, the paused status
+			// is set once the DNS server is ready.
+		default:
+			l.unpause(ctx)
+		}
+	case constants.Stopped:
+		outcome, err = l.statusManager.ApplyStatus(ctx, status)
+		l.unpause(ctx)
+		return outcome, err
+	}
 	return l.statusManager.ApplyStatus(ctx, status)
 }
@@ -0,0 +1,74 @@
+package dns
+
+import (
+	"strconv"
+
+	"github.com/miekg/dns"
+)
+
+// statusMiddleware answers TXT queries for the status domain with
+// the status texts given, and queries of other types for the status
+// domain with no answer. Other queries are passed to the next handler.
+type statusMiddleware struct {
+	// name is the fully qualified status domain name.
+	name   string
+	status func() (texts []string)
+}
+
+func newStatusMiddleware(domain string, status func() (texts []string)) *statusMiddleware {
+	return &statusMiddleware{
+		name:   dns.CanonicalName(domain),
+		status: status,
+	}
+}
+
+func (m *statusMiddleware) String() string {
+	return "status domain"
+}
+
+func (m *statusMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if len(request.Question) == 0 ||
+			dns.CanonicalName(request.Question[0].Name) != m.name {
+			next.ServeDNS(w, request)
+			return
+		}
+
+		response := new(dns.Msg).SetReply(request)
+		response.Authoritative = true
+		question := request.Question[0]
+		if question.Qtype == dns.TypeTXT && question.Qclass == dns.ClassINET {
+			response.Answer = []dns.RR{&dns.TXT{
+				Hdr: dns.RR_Header{
+					Name:   question.Name,
+					Rrtype: dns.TypeTXT,
+					Class:  dns.ClassINET,
+					Ttl:    0, // the status can change at any time
+				},
+				Txt: m.status(),
+			}}
+		}
+		_ = w.WriteMsg(response)
+	})
+}
+
+func (m *statusMiddleware) Stop() (err error) {
+	return nil
+}
+
+// statusTexts returns the texts answered for the status domain,
+// in the format key=value.
+func (l *Loop) statusTexts() (texts []string) {
+	vpn := "down"
+	if l.isTunnelUp() {
+		vpn = "up"
+	}
+	l.detailsMu.RLock()
+	encrypted := l.encrypted
+	l.detailsMu.RUnlock()
+	return []string{
+		"dns=" + string(l.GetStatus()),
+		"vpn=" + vpn,
+		"encrypted=" + strconv.FormatBool(encrypted),
+	}
+}
@@ -0,0 +1,190 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/qdm12/dns/v2/pkg/dot"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+)
+
+// swapMiddleware is the outermost middleware of the DNS over TLS
+// server. Once swapped, it serves queries with the handler of another
+// DNS over TLS server instead, so the upstream resolvers can be changed
+// without stopping the server listening on the DNS port.
+type swapMiddleware struct {
+	own      dns.Handler
+	delegate atomic.Pointer[dns.Handler]
+}
+
+func (m *swapMiddleware) String() string {
+	return "swap"
+}
+
+func (m *swapMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	m.own = next
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		if delegate := m.delegate.Load(); delegate != nil {
+			(*delegate).ServeDNS(w, request)
+			return
+		}
+		next.ServeDNS(w, request)
+	})
+}
+
+func (m *swapMiddleware) Stop() (err error) {
+	return nil
+}
+
+// swap serves the next queries with the handler given.
+func (m *swapMiddleware) swap(handler dns.Handler) {
+	m.delegate.Store(&handler)
+}
+
+// swapBackend is a DNS over TLS server started on a loopback port,
+// serving the queries the server listening on the DNS port hands
+// over to it.
+type swapBackend struct {
+	server  *dot.Server
+	drainer *drainMiddleware
+}
+
+var errSwapNotSupported = errors.New("DNS over TLS providers cannot be switched without restarting")
+
+// swapProviders switches the DNS over TLS upstream resolvers to the ones
+// of the settings given without downtime. Another DNS over TLS server is
+// started on a loopback port with the settings given, and the server
+// listening on the DNS port hands queries over to it once it resolves
+// hostnames. The previous backend server, if any, is then drained and
+// stopped. An error is returned if the server is not running or if the
+// backend server fails, so the caller can restart the server instead.
+func (l *Loop) swapProviders(ctx context.Context, settings settings.DNS) (err error) {
+	l.statusManager.Lock()
+	defer l.statusManager.Unlock()
+
+	status := l.GetStatus()
+	switch {
+	case status != constants.Running && status != constants.Paused:
+		return fmt.Errorf("%w: status is %s", ErrDNSServerNotRunning, status)
+	case *settings.UpstreamProxy != "":
+		return fmt.Errorf("%w: the upstream proxy is used", errSwapNotSupported)
+	}
+
+	l.swapMu.Lock()
+	swapper := l.swapper
+	l.swapMu.Unlock()
+	if swapper == nil {
+		return fmt.Errorf("%w: no DNS over TLS server", errSwapNotSupported)
+	}
+
+	backendSettings := settings.Copy()
+	backendSettings.ListeningAddress = "127.0.0.1:0"
+	backendSettings.ForwardZones = l.withBypassZones(settings)
+	caches := &serverCaches{}
+	drainer := &drainMiddleware{}
+	backendSwapper := &swapMiddleware{}
+	server, err := l.newServer(backendSettings, settings.DoT.GetProviders(),
+		caches, drainer, backendSwapper)
+	if err != nil {
+		return err
+	}
+
+	_, err = server.Start()
+	if err != nil {
+		return fmt.Errorf("starting server: %w", err)
+	}
+
+	err = waitForDNSAt(ctx, settings, server)
+	if err != nil {
+		_ = server.Stop()
+		return err
+	}
+
+	swapper.swap(backendSwapper.own)
+	l.setCaches(caches)
+	l.swapMu.Lock()
+	previous := l.backend
+	l.backend = &swapBackend{server: server, drainer: drainer}
+	l.swapMu.Unlock()
+	l.stopBackend(previous, *settings.StopGrace)
+	l.setUpstreams(settings.DoT.GetProviders(), l.useIPv6(settings))
+
+	l.logEvent(l.logger.Info, eventRecord{
+		Event: "providers_selected",
+		Message: "switched to DNS over TLS providers " +
+			strings.Join(settings.DoT.Providers, ", ") + " without restarting",
+		Providers: settings.DoT.Providers,
+	})
+	return nil
+}
+
+// stopBackend drains the backend server given, if any, for at most
+// the grace duration given, and stops it.
+func (l *Loop) stopBackend(backend *swapBackend, grace time.Duration) {
+	if backend == nil {
+		return
+	}
+
+	if grace > 0 && !backend.drainer.drain(grace) {
+		l.logger.Warn("queries still in flight after " + grace.String() +
+			", stopping previous DoT server anyway")
+	}
+	err := backend.server.Stop()
+	if err != nil {
+		l.logger.Error("stopping previous DoT server: " + err.Error())
+	}
+}
+
+// takeBackend returns the backend server, if any, and forgets it.
+func (l *Loop) takeBackend() (backend *swapBackend) {
+	l.swapMu.Lock()
+	defer l.swapMu.Unlock()
+	backend = l.backend
+	l.backend = nil
+	return backend
+}
+
+// waitForDNSAt waits for the DNS server given to successfully resolve
+// a hostname, retrying at the readiness retry interval until the
+// readiness timeout elapses or the context is canceled.
+func waitForDNSAt(ctx context.Context, settings settings.DNS, server *dot.Server) (err error) {
+	listeningAddress, err := server.ListeningAddress()
+	if err != nil {
+		return fmt.Errorf("getting listening address: %w", err)
+	}
+	address := listeningAddress.(*net.UDPAddr).AddrPort() //nolint:forcetypeassert
+	address = netip.AddrPortFrom(address.Addr().Unmap(), address.Port())
+
+	timeout := *settings.ReadinessTimeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := &dns.Client{}
+	request := new(dns.Msg).SetQuestion("github.com.", dns.TypeA)
+	for {
+		var response *dns.Msg
+		response, _, err = client.ExchangeContext(ctx, request, address.String())
+		if err == nil && response.Rcode != dns.RcodeSuccess {
+			err = fmt.Errorf("response code is %s", dns.RcodeToString[response.Rcode])
+		}
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(*settings.ReadinessRetryInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: after waiting %s: %w", errDNSNotReady, timeout, err)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package dns
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/cron"
+	"github.com/qdm12/gluetun/internal/models"
 )
 
 func (l *Loop) RunRestartTicker(ctx context.Context, done chan<- struct{}) {
@@ -13,28 +18,66 @@ func (l *Loop) RunRestartTicker(ctx context.Context, done chan<- struct{}) {
 	timer := time.NewTimer(time.Hour)
 	timer.Stop()
 	timerIsStopped := true
-	settings := l.GetSettings()
-	if period := *settings.DoT.UpdatePeriod; period > 0 {
-		timer.Reset(period)
-		timerIsStopped = false
-	}
 	lastTick := time.Unix(0, 0)
+	settings := l.GetSettings()
+	timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
+	statsTimer := time.NewTimer(time.Hour)
+	statsTimer.Stop()
+	statsTimerIsStopped := resetCacheStatsTimer(statsTimer, settings)
+	var statsReporter cacheStatsReporter
 	for {
 		select {
 		case <-ctx.Done():
 			if !timerIsStopped && !timer.Stop() {
 				<-timer.C
 			}
+			if !statsTimerIsStopped && !statsTimer.Stop() {
+				<-statsTimer.C
+			}
 			return
+		case <-statsTimer.C:
+			settings := l.GetSettings()
+			l.reportCacheStats(&statsReporter, settings)
+			statsTimerIsStopped = resetCacheStatsTimer(statsTimer, settings)
 		case <-timer.C:
+			timerIsStopped = true
+			if l.paused.Load() {
+				// Leave the timer stopped without updating the last
+				// tick time, so resuming computes the next tick from
+				// the time elapsed since the last update.
+				l.logger.Info("skipping block lists update since DNS is paused")
+				l.setNextUpdate(time.Time{})
+				continue
+			}
 			lastTick = l.timeNow()
 
+			settings := l.GetSettings()
 			status := l.GetStatus()
 			if status == constants.Running {
-				if err := l.updateFiles(ctx); err != nil {
-					l.statusManager.SetStatus(constants.Crashed)
+				// The filter is shared with the running server, so updating
+				// it takes effect without restarting the DNS server.
+				err := l.updateFilesWithRetries(ctx, *settings.DoT.UpdateRetries)
+				switch {
+				case err == nil:
+					l.logger.Info("block lists updated")
+					timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
+					continue
+				case errors.Is(err, ErrTunnelNotUp):
+					l.logger.Warn(err.Error())
+					l.logger.Info("block lists update skipped, keeping previous block lists")
+					timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
+					continue
+				case errors.Is(err, errUpdateFilter):
+					l.logger.Warn(err.Error())
+					l.logger.Info("restarting DNS server to update block lists")
+				case ctx.Err() != nil:
+					return
+				default:
+					// The running DNS server is healthy and keeps
+					// using the previous block lists.
 					l.logger.Error(err.Error())
-					l.logger.Warn("skipping DNS server restart due to failed files update")
+					l.logger.Warn("keeping previous block lists due to failed files update")
+					timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
 					continue
 				}
 			}
@@ -42,25 +85,120 @@ func (l *Loop) RunRestartTicker(ctx context.Context, done chan<- struct{}) {
 			_, _ = l.statusManager.ApplyStatus(ctx, constants.Stopped)
 			_, _ = l.statusManager.ApplyStatus(ctx, constants.Running)
 
-			settings := l.GetSettings()
-			timer.Reset(*settings.DoT.UpdatePeriod)
+			timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
 		case <-l.updateTicker:
-			if !timer.Stop() {
+			if !timerIsStopped && !timer.Stop() {
 				<-timer.C
 			}
-			timerIsStopped = true
-			settings := l.GetSettings()
-			newUpdatePeriod := *settings.DoT.UpdatePeriod
-			if newUpdatePeriod == 0 {
-				continue
+			if !statsTimerIsStopped && !statsTimer.Stop() {
+				<-statsTimer.C
 			}
-			var waited time.Duration
-			if lastTick.UnixNano() != 0 {
-				waited = l.timeSince(lastTick)
+			settings := l.GetSettings()
+			timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
+			statsTimerIsStopped = resetCacheStatsTimer(statsTimer, settings)
+		case <-l.resumeTicker:
+			if !timerIsStopped && !timer.Stop() {
+				<-timer.C
 			}
-			leftToWait := newUpdatePeriod - waited
-			timer.Reset(leftToWait)
-			timerIsStopped = false
+			settings := l.GetSettings()
+			timerIsStopped = l.resetUpdateTimer(timer, settings, lastTick)
+		}
+	}
+}
+
+// resetUpdateTimer resets the stopped or expired timer given to fire at
+// the next block lists update, and returns true if updates are disabled
+// so the timer is left stopped.
+func (l *Loop) resetUpdateTimer(timer *time.Timer, settings settings.DNS,
+	lastTick time.Time) (timerIsStopped bool) {
+	wait, ok := l.nextUpdateWait(settings, lastTick)
+	if !ok {
+		l.setNextUpdate(time.Time{})
+		return true
+	}
+	l.setNextUpdate(l.timeNow().Add(wait))
+	timer.Reset(wait)
+	return false
+}
+
+func (l *Loop) setNextUpdate(next time.Time) {
+	l.detailsMu.Lock()
+	defer l.detailsMu.Unlock()
+	l.nextUpdate = next
+}
+
+// GetNextUpdate returns the time of the next scheduled block lists
+// update, together with the update schedule or period.
+func (l *Loop) GetNextUpdate() (next models.DNSNextUpdate) {
+	settings := l.GetSettings()
+	next.Schedule = settings.DoT.UpdateSchedule
+	if next.Schedule == "" {
+		next.Period = *settings.DoT.UpdatePeriod
+	}
+	next.Enabled = next.Schedule != "" || next.Period > 0
+	next.Paused = l.paused.Load()
+
+	l.detailsMu.RLock()
+	next.Time = l.nextUpdate
+	l.detailsMu.RUnlock()
+	return next
+}
+
+// nextUpdateWait returns the duration to wait until the next block lists
+// update, either at the next occurrence of the cron schedule or after the
+// update period since the last tick. It returns false if updates are disabled.
+func (l *Loop) nextUpdateWait(settings settings.DNS, lastTick time.Time) (
+	wait time.Duration, ok bool) {
+	if settings.DoT.UpdateSchedule != "" {
+		schedule, err := cron.Parse(settings.DoT.UpdateSchedule)
+		if err != nil {
+			// Settings should be validated before, so an error
+			// happening here is a programming error.
+			panic(err)
+		}
+		now := l.timeNow()
+		next := schedule.Next(now)
+		if next.IsZero() {
+			return 0, false
+		}
+		return next.Sub(now), true
+	}
+
+	updatePeriod := *settings.DoT.UpdatePeriod
+	if updatePeriod == 0 {
+		return 0, false
+	}
+	var waited time.Duration
+	if lastTick.UnixNano() != 0 {
+		waited = l.timeSince(lastTick)
+	}
+	wait = updatePeriod - waited
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// updateFilesWithRetries updates the block lists, retrying up to
+// the number of retries given with an exponential backoff.
+func (l *Loop) updateFilesWithRetries(ctx context.Context, retries uint) (err error) {
+	backoff := 5 * time.Second //nolint:gomnd
+	for attempt := uint(0); ; attempt++ {
+		err = l.updateFiles(ctx)
+		if err == nil || errors.Is(err, errUpdateFilter) ||
+			errors.Is(err, ErrTunnelNotUp) || attempt == retries {
+			return err
+		}
+
+		l.logger.Warn(fmt.Sprintf("updating block lists (attempt %d of %d): %s, retrying in %s",
+			attempt+1, retries+1, err, backoff))
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
 		}
+		backoff *= 2
 	}
 }
@@ -0,0 +1,103 @@
+package dns
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"golang.org/x/sys/unix"
+)
+
+// newDownloadClient returns a copy of the HTTP client given with the
+// timeout and idle connections settings of the block list settings given,
+// and with its connections bound to the network interface and to the
+// local source address given, resolving hostnames with the resolver given.
+// An empty network interface, an invalid source address or a nil
+// resolver are ignored.
+func newDownloadClient(client *http.Client, blacklist settings.DNSBlacklist,
+	networkInterface string, resolver *net.Resolver) *http.Client {
+	dialer := &net.Dialer{
+		Resolver: resolver,
+	}
+	if blacklist.SourceAddress.IsValid() {
+		dialer.LocalAddr = &net.TCPAddr{IP: blacklist.SourceAddress.AsSlice()}
+	}
+	if networkInterface != "" {
+		dialer.Control = func(_, _ string, rawConn syscall.RawConn) (err error) {
+			controlErr := rawConn.Control(func(fd uintptr) {
+				err = unix.SetsockoptString(int(fd), unix.SOL_SOCKET,
+					unix.SO_BINDTODEVICE, networkInterface)
+			})
+			if controlErr != nil {
+				return controlErr
+			}
+			return err
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConnsPerHost = int(*blacklist.MaxIdleConnsPerHost)
+	transport.IdleConnTimeout = *blacklist.IdleConnTimeout
+	downloadClient := *client
+	downloadClient.Transport = transport
+	downloadClient.Timeout = *blacklist.DownloadTimeout
+	return &downloadClient
+}
+
+// newLimitedClient returns a copy of the HTTP client given which
+// runs at most maxConcurrent requests at the same time, including
+// the download of their response body until it is closed.
+func newLimitedClient(client *http.Client, maxConcurrent uint) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	limitedClient := *client
+	limitedClient.Transport = &limitedTransport{
+		slots: make(chan struct{}, maxConcurrent),
+		next:  transport,
+	}
+	return &limitedClient
+}
+
+type limitedTransport struct {
+	slots chan struct{}
+	next  http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(request *http.Request) (
+	response *http.Response, err error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-request.Context().Done():
+		return nil, request.Context().Err()
+	}
+	response, err = t.next.RoundTrip(request)
+	if err != nil {
+		<-t.slots
+		return nil, err
+	}
+	// The slot is released once the body is closed,
+	// so the body download is limited as well.
+	response.Body = &slotBody{
+		ReadCloser: response.Body,
+		release:    func() { <-t.slots },
+	}
+	return response, nil
+}
+
+// slotBody releases its download slot once closed.
+type slotBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newLimitedClient_maxInFlight(t *testing.T) {
+	t.Parallel()
+
+	const maxConcurrent = 2
+	const downloads = 8
+
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			mutex.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mutex.Unlock()
+
+			// Send the headers first, so the body is
+			// downloaded after the round trip returns.
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			const bodyDelay = 20 * time.Millisecond
+			time.Sleep(bodyDelay)
+			_, _ = w.Write([]byte("example.com\n"))
+
+			mutex.Lock()
+			inFlight--
+			mutex.Unlock()
+		}))
+	t.Cleanup(server.Close)
+
+	client := newLimitedClient(server.Client(), maxConcurrent)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, downloads)
+	for range downloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := client.Get(server.URL) //nolint:noctx
+			if err != nil {
+				errs <- err
+				return
+			}
+			_, err = io.ReadAll(response.Body)
+			_ = response.Body.Close()
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, maxConcurrent, maxInFlight)
+}
+
+type roundTripFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func Test_newLimitedClient_releaseOnError(t *testing.T) {
+	t.Parallel()
+
+	errDummy := errors.New("dummy")
+	client := &http.Client{
+		Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errDummy
+		}),
+	}
+	const maxConcurrent = 1
+	client = newLimitedClient(client, maxConcurrent)
+
+	// The second request would block forever if the
+	// first failed request did not release its slot.
+	for range 2 {
+		response, err := client.Get("http://example.com") //nolint:noctx
+		if response != nil {
+			_ = response.Body.Close()
+		}
+		assert.ErrorIs(t, err, errDummy)
+	}
+}
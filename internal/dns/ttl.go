@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ttlMiddleware clamps the TTL of the records of responses between
+// a minimum and a maximum TTL. A zero minimum or maximum is ignored.
+// Records of the authority section are not clamped, since their TTL
+// is the negative caching duration of negative responses.
+type ttlMiddleware struct {
+	minTTL uint32
+	maxTTL uint32
+}
+
+func newTTLMiddleware(minTTL, maxTTL time.Duration) *ttlMiddleware {
+	return &ttlMiddleware{
+		minTTL: uint32(minTTL / time.Second),
+		maxTTL: uint32(maxTTL / time.Second),
+	}
+}
+
+func (m *ttlMiddleware) String() string {
+	return "TTL clamping"
+}
+
+func (m *ttlMiddleware) Wrap(next dns.Handler) dns.Handler { //nolint:ireturn
+	return dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+		recorder := &responseRecorder{}
+		next.ServeDNS(recorder, request)
+		response := recorder.response
+		if response == nil {
+			return
+		}
+
+		for _, rr := range response.Answer {
+			m.clamp(rr.Header())
+		}
+		for _, rr := range response.Extra {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue // the OPT record TTL field holds EDNS flags
+			}
+			m.clamp(rr.Header())
+		}
+		_ = w.WriteMsg(response)
+	})
+}
+
+func (m *ttlMiddleware) Stop() (err error) {
+	return nil
+}
+
+func (m *ttlMiddleware) clamp(header *dns.RR_Header) {
+	switch {
+	case m.minTTL > 0 && header.Ttl < m.minTTL:
+		header.Ttl = m.minTTL
+	case m.maxTTL > 0 && header.Ttl > m.maxTTL:
+		header.Ttl = m.maxTTL
+	}
+}
@@ -0,0 +1,107 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ttlMiddleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		minTTL time.Duration
+		maxTTL time.Duration
+		ttl    uint32
+		// clampedTTL is the expected TTL of the answer and
+		// additional records, other than the OPT record.
+		clampedTTL uint32
+	}{
+		"within_bounds": {
+			minTTL:     time.Minute,
+			maxTTL:     time.Hour,
+			ttl:        300,
+			clampedTTL: 300,
+		},
+		"below_minimum": {
+			minTTL:     time.Minute,
+			maxTTL:     time.Hour,
+			ttl:        5,
+			clampedTTL: 60,
+		},
+		"above_maximum": {
+			minTTL:     time.Minute,
+			maxTTL:     time.Hour,
+			ttl:        86400,
+			clampedTTL: 3600,
+		},
+		"no_minimum": {
+			maxTTL:     time.Hour,
+			ttl:        5,
+			clampedTTL: 5,
+		},
+		"no_maximum": {
+			minTTL:     time.Minute,
+			ttl:        86400,
+			clampedTTL: 86400,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			middleware := newTTLMiddleware(testCase.minTTL, testCase.maxTTL)
+			next := dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+				response := newAnswer(request, net.IPv4(1, 1, 1, 1), testCase.ttl)
+				response.Ns = []dns.RR{&dns.NS{
+					Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeNS,
+						Class: dns.ClassINET, Ttl: testCase.ttl},
+					Ns: "ns.example.com.",
+				}}
+				response.Extra = []dns.RR{&dns.A{
+					Hdr: dns.RR_Header{Name: "ns.example.com.", Rrtype: dns.TypeA,
+						Class: dns.ClassINET, Ttl: testCase.ttl},
+					A: net.IPv4(2, 2, 2, 2),
+				}}
+				const dnssecOK = true
+				response.SetEdns0(1232, dnssecOK) //nolint:gomnd
+				_ = w.WriteMsg(response)
+			})
+
+			request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			recorder := &responseRecorder{}
+			middleware.Wrap(next).ServeDNS(recorder, request)
+
+			response := recorder.response
+			require.NotNil(t, response)
+			require.Len(t, response.Answer, 1)
+			assert.Equal(t, testCase.clampedTTL, response.Answer[0].Header().Ttl)
+			require.Len(t, response.Ns, 1)
+			assert.Equal(t, testCase.ttl, response.Ns[0].Header().Ttl)
+			require.Len(t, response.Extra, 2)
+			assert.Equal(t, testCase.clampedTTL, response.Extra[0].Header().Ttl)
+			opt := response.IsEdns0()
+			require.NotNil(t, opt)
+			assert.True(t, opt.Do())
+		})
+	}
+}
+
+func Test_ttlMiddleware_noResponse(t *testing.T) {
+	t.Parallel()
+
+	middleware := newTTLMiddleware(time.Minute, time.Hour)
+	next := dns.HandlerFunc(func(dns.ResponseWriter, *dns.Msg) {})
+
+	request := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+	recorder := &responseRecorder{}
+	middleware.Wrap(next).ServeDNS(recorder, request)
+
+	assert.Nil(t, recorder.response)
+}
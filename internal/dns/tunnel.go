@@ -0,0 +1,159 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/constants"
+)
+
+// SetTunnelUp signals the VPN tunnel is up on the network
+// interface given, so block lists can be downloaded through it.
+func (l *Loop) SetTunnelUp(vpnInterface string) {
+	l.tunnelMu.Lock()
+	defer l.tunnelMu.Unlock()
+	l.tunnelInterface = vpnInterface
+	select {
+	case <-l.tunnelUp:
+	default:
+		close(l.tunnelUp)
+	}
+}
+
+// SetTunnelDown signals the VPN tunnel is down.
+func (l *Loop) SetTunnelDown() {
+	l.tunnelMu.Lock()
+	defer l.tunnelMu.Unlock()
+	l.tunnelInterface = ""
+	select {
+	case <-l.tunnelUp:
+		l.tunnelUp = make(chan struct{})
+	default:
+	}
+}
+
+var ErrTunnelNotUp = errors.New("VPN tunnel is not up")
+
+func (l *Loop) isTunnelUp() (up bool) {
+	l.tunnelMu.Lock()
+	tunnelUp := l.tunnelUp
+	l.tunnelMu.Unlock()
+	select {
+	case <-tunnelUp:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForEgress waits for the VPN tunnel to be up if the firewall
+// kill switch only allows traffic through the VPN tunnel, so the
+// DNS server does not try to reach its upstream resolvers before.
+func (l *Loop) waitForEgress(ctx context.Context) (err error) {
+	if !l.killSwitch {
+		return nil
+	}
+
+	l.tunnelMu.Lock()
+	tunnelUp := l.tunnelUp
+	l.tunnelMu.Unlock()
+	select {
+	case <-tunnelUp:
+		return nil
+	default:
+	}
+
+	l.logger.Info("waiting for the VPN tunnel to be up, " +
+		"since the firewall only allows traffic through it")
+	select {
+	case <-tunnelUp:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitForTunnel waits for the VPN tunnel to be up for at most
+// the timeout given, and returns the VPN network interface name.
+func (l *Loop) waitForTunnel(ctx context.Context, timeout time.Duration) (
+	vpnInterface string, err error) {
+	l.tunnelMu.Lock()
+	tunnelUp := l.tunnelUp
+	l.tunnelMu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-tunnelUp:
+	case <-timer.C:
+		return "", fmt.Errorf("%w: after waiting %s", ErrTunnelNotUp, timeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	l.tunnelMu.Lock()
+	defer l.tunnelMu.Unlock()
+	return l.tunnelInterface, nil
+}
+
+// waitForTunnelOnStart waits for the VPN tunnel to be up for at most
+// the tunnel wait timeout, before the DNS over TLS server is first
+// set up. The server is set up anyway if the timeout elapses.
+func (l *Loop) waitForTunnelOnStart(ctx context.Context) {
+	settings := l.GetSettings()
+	timeout := *settings.TunnelWaitTimeout
+	if timeout == 0 || *settings.Standalone || *settings.KeepNameserver ||
+		!*settings.DoT.Enabled || l.isTunnelUp() {
+		return
+	}
+
+	l.logger.Info(fmt.Sprintf("waiting up to %s for the VPN tunnel to be up "+
+		"before setting up the DNS over TLS server", timeout))
+	_, err := l.waitForTunnel(ctx, timeout)
+	if errors.Is(err, ErrTunnelNotUp) {
+		l.logger.Warn(err.Error() + ", setting up the DNS over TLS server anyway")
+	}
+}
+
+// OnTunnelUp starts the DNS over TLS server once the VPN tunnel is up,
+// and leaves it paused if it is paused. If the server is already running,
+// the VPN reconnected, so the server is kept running and, if the keep on
+// reconnect setting is enabled, checked in the background to still
+// resolve hostnames through the new VPN tunnel.
+func (l *Loop) OnTunnelUp(ctx context.Context) {
+	switch l.GetStatus() {
+	case constants.Paused:
+		// Keep the pause requested by the user.
+		return
+	case constants.Running:
+	default:
+		_, _ = l.ApplyStatus(ctx, constants.Running)
+		return
+	}
+
+	if !*l.GetSettings().KeepOnReconnect {
+		return
+	}
+	// The check runs in the background so the VPN loop
+	// does not wait for it after the VPN reconnection.
+	go l.checkAfterReconnect(ctx)
+}
+
+// checkAfterReconnect checks the DNS over TLS server still resolves
+// hostnames after the VPN reconnected, and restarts it otherwise.
+func (l *Loop) checkAfterReconnect(ctx context.Context) {
+	err := l.checkDNS(ctx, l.GetSettings())
+	switch {
+	case err == nil:
+		l.logger.Info("DNS over TLS server still resolves after the VPN reconnection")
+		return
+	case ctx.Err() != nil:
+		return
+	}
+
+	l.logger.Warn(err.Error() + " after the VPN reconnection, restarting DNS over TLS server")
+	_, _ = l.statusManager.ApplyStatus(ctx, constants.Stopped)
+	_, _ = l.statusManager.ApplyStatus(ctx, constants.Running)
+}
@@ -0,0 +1,194 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/dns/state"
+	"github.com/qdm12/gluetun/internal/loopstate"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTunnelTestLoop returns a loop with the status and keep on
+// reconnect setting given, and a channel receiving the "start" and
+// "stop" signals received by its fake run loop.
+func newTunnelTestLoop(t *testing.T, status models.LoopStatus, keepOnReconnect bool,
+	checkDNS func(ctx context.Context, settings settings.DNS) error) (
+	loop *Loop, signals <-chan string) {
+	t.Helper()
+
+	start := make(chan struct{})
+	running := make(chan models.LoopStatus)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	statusManager := loopstate.New(status, start, running, stop, stopped)
+	dnsSettings := settings.DNS{KeepOnReconnect: ptrTo(keepOnReconnect)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	signalsCh := make(chan string, 2) //nolint:gomnd
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-start:
+				signalsCh <- "start"
+				running <- constants.Running
+			case <-stop:
+				signalsCh <- "stop"
+				stopped <- struct{}{}
+			}
+		}
+	}()
+
+	loop = &Loop{
+		statusManager: statusManager,
+		state:         state.New(statusManager, dnsSettings, make(chan struct{})),
+		logger:        noopLogger{},
+		resumeTicker:  make(chan struct{}),
+		checkDNS:      checkDNS,
+	}
+	return loop, signalsCh
+}
+
+func Test_Loop_OnTunnelUp(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		status          models.LoopStatus
+		keepOnReconnect bool
+		checked         bool
+		signals         []string
+		finalStatus     models.LoopStatus
+	}{
+		"stopped_keep_off": {
+			status:      constants.Stopped,
+			signals:     []string{"start"},
+			finalStatus: constants.Running,
+		},
+		"stopped_keep_on": {
+			status:          constants.Stopped,
+			keepOnReconnect: true,
+			signals:         []string{"start"},
+			finalStatus:     constants.Running,
+		},
+		"paused_keep_off": {
+			status:      constants.Paused,
+			finalStatus: constants.Paused,
+		},
+		"paused_keep_on": {
+			status:          constants.Paused,
+			keepOnReconnect: true,
+			finalStatus:     constants.Paused,
+		},
+		"running_keep_off": {
+			status:      constants.Running,
+			finalStatus: constants.Running,
+		},
+		"running_keep_on": {
+			status:          constants.Running,
+			keepOnReconnect: true,
+			checked:         true,
+			finalStatus:     constants.Running,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			checked := make(chan struct{})
+			checkDNS := func(context.Context, settings.DNS) error {
+				close(checked)
+				return nil
+			}
+			loop, signals := newTunnelTestLoop(t, testCase.status,
+				testCase.keepOnReconnect, checkDNS)
+
+			loop.OnTunnelUp(context.Background())
+
+			if testCase.checked {
+				const timeout = time.Second
+				select {
+				case <-checked:
+				case <-time.After(timeout):
+					t.Fatal("DNS not checked after the VPN reconnection")
+				}
+			} else {
+				select {
+				case <-checked:
+					t.Fatal("DNS checked without VPN reconnection")
+				default:
+				}
+			}
+			assert.Equal(t, testCase.signals, drainSignals(signals))
+			assert.Equal(t, testCase.finalStatus, loop.GetStatus())
+		})
+	}
+}
+
+func Test_Loop_checkAfterReconnect(t *testing.T) {
+	t.Parallel()
+
+	errDummy := errors.New("dummy")
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := map[string]struct {
+		ctx      context.Context //nolint:containedctx
+		checkErr error
+		signals  []string
+	}{
+		"still_resolving": {
+			ctx: context.Background(),
+		},
+		"not_resolving": {
+			ctx:      context.Background(),
+			checkErr: errDummy,
+			signals:  []string{"stop", "start"},
+		},
+		"context_canceled": {
+			ctx:      canceledCtx,
+			checkErr: context.Canceled,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			checkDNS := func(context.Context, settings.DNS) error {
+				return testCase.checkErr
+			}
+			const keepOnReconnect = true
+			loop, signals := newTunnelTestLoop(t, constants.Running,
+				keepOnReconnect, checkDNS)
+
+			loop.checkAfterReconnect(testCase.ctx)
+
+			assert.Equal(t, testCase.signals, drainSignals(signals))
+			assert.Equal(t, constants.Running, loop.GetStatus())
+		})
+	}
+}
+
+// drainSignals returns the signals already received, and
+// nil if none was received.
+func drainSignals(signals <-chan string) (received []string) {
+	for {
+		select {
+		case signal := <-signals:
+			received = append(received, signal)
+		default:
+			return received
+		}
+	}
+}
@@ -2,45 +2,473 @@ package dns
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/qdm12/dns/v2/pkg/blockbuilder"
 	"github.com/qdm12/dns/v2/pkg/middlewares/filter/update"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+var (
+	errUpdateFilter       = errors.New("cannot update filter")
+	errAllDownloadsFailed = errors.New("all block lists downloads failed")
+	errTooFewEntries      = errors.New("downloaded block lists have too few entries")
 )
 
 func (l *Loop) updateFiles(ctx context.Context) (err error) {
+	l.updateMu.Lock()
+	defer l.updateMu.Unlock()
+
 	settings := l.GetSettings()
 
 	l.logger.Info("downloading hostnames and IP block lists")
-	blacklistSettings := settings.DoT.Blacklist.ToBlockBuilderSettings(l.client)
+	blacklist := settings.DoT.Blacklist
+	client := l.client
+	var vpnInterface string
+	if *blacklist.RequireVPN || l.killSwitch {
+		// Downloading while the VPN tunnel is down would either fail
+		// due to the firewall kill switch, or leak outside the VPN.
+		const tunnelTimeout = time.Minute
+		vpnInterface, err = l.waitForTunnel(ctx, tunnelTimeout)
+		if err != nil {
+			// Keep the custom blocked hosts and IPs up to date, and
+			// let the caller report the block lists build as skipped.
+			filterErr := l.updateFilter(l.GetSettings())
+			if filterErr != nil {
+				return filterErr
+			}
+			return fmt.Errorf("skipping block lists build: %w", err)
+		}
+		if !*blacklist.RequireVPN {
+			// Traffic goes through the VPN tunnel with the
+			// kill switch, without binding to its interface.
+			vpnInterface = ""
+		}
+	}
+	var resolver *net.Resolver
+	if !*settings.BootstrapPlaintext || *settings.Strict {
+		// The system DNS cannot be used before the DNS over TLS
+		// server is ready, so use its upstream resolvers directly.
+		resolver, err = l.newUpstreamResolver(settings)
+		if err != nil {
+			return fmt.Errorf("creating upstream resolver: %w", err)
+		}
+	}
+	client = newDownloadClient(client, blacklist, vpnInterface, resolver)
+	// The transport is created for each update, so close its idle
+	// connections once done since it is not used anymore.
+	defer client.CloseIdleConnections()
+	client = newLimitedClient(client, *blacklist.MaxConcurrentDownloads)
+	client = newGzipClient(client)
 
-	blockBuilder, err := blockbuilder.New(blacklistSettings)
+	l.blockListsMu.RLock()
+	previous := l.sources
+	l.blockListsMu.RUnlock()
+	sources, err := downloadBlockLists(ctx, blacklist, client, previous, l.logger)
 	if err != nil {
-		return fmt.Errorf("creating block builder: %w", err)
+		return err
 	}
+	l.recordSourceErrors(sources, l.timeNow())
 
-	result := blockBuilder.BuildAll(ctx)
-	for _, resultErr := range result.Errors {
-		if err != nil {
-			err = fmt.Errorf("%w, %w", err, resultErr)
-			continue
+	var errs []error
+	for _, source := range sources {
+		errs = append(errs, source.result.Errors...)
+	}
+	downloaded := mergeBlockLists(sources)
+	// Errors come from concurrent downloads, so sort them
+	// to log them in a deterministic order.
+	errorMessages := make([]string, len(errs))
+	for i, downloadErr := range errs {
+		errorMessages[i] = downloadErr.Error()
+	}
+	sort.Strings(errorMessages)
+	for _, errorMessage := range errorMessages {
+		l.logger.Warn("downloading block list: " + errorMessage)
+	}
+	if len(errs) > 0 && isEmptyResult(downloaded) {
+		err = fmt.Errorf("%w: %d download errors", errAllDownloadsFailed, len(errs))
+	}
+
+	if len(sources) > 0 && err == nil {
+		entries := len(downloaded.BlockedHostnames) + len(downloaded.BlockedIPs) +
+			len(downloaded.BlockedIPPrefixes)
+		if minEntries := *blacklist.MinEntries; uint(entries) < minEntries {
+			// Keep the previous block lists, without falling
+			// back on the local block lists only.
+			err = fmt.Errorf("%w: %d entries is below the minimum of %d, "+
+				"keeping previous block lists", errTooFewEntries, entries, minEntries)
+			return err
+		}
+	}
+
+	updateTime := l.timeNow()
+	if err == nil && *blacklist.CachePath != "" {
+		cacheErr := writeBlockListsCache(*blacklist.CachePath, sources, updateTime)
+		if cacheErr != nil {
+			l.logger.Warn(cacheErr.Error())
 		}
-		err = resultErr
 	}
 
+	local, localErr := l.readLocalSource(blacklist)
+	if localErr != nil {
+		return localErr
+	}
+
+	switch {
+	case err != nil && isEmptyResult(local):
+		return err
+	case err != nil:
+		l.logger.Warn(err.Error())
+		l.logger.Info("using local block lists only")
+	default:
+		hostnames := len(downloaded.BlockedHostnames)
+		ips := len(downloaded.BlockedIPs)
+		ipPrefixes := len(downloaded.BlockedIPPrefixes)
+		l.logEvent(l.logger.Info, eventRecord{
+			Event: "block_lists_updated",
+			Message: fmt.Sprintf("downloaded %d hostnames, %d IP addresses and %d IP prefixes "+
+				"from remote block lists", hostnames, ips, ipPrefixes),
+			Hostnames:  &hostnames,
+			IPs:        &ips,
+			IPPrefixes: &ipPrefixes,
+		})
+	}
+
+	if !isEmptyResult(local) {
+		sources = append(sources, blockListSource{name: "local", result: local})
+	}
+	downloadErr := err
+	// Settings may have changed during the download, for example with
+	// hostnames added through the control server, so use the current
+	// ones to not drop them from the filter.
+	settings = l.GetSettings()
+	err = l.setBlockLists(settings, sources, updateTime)
 	if err != nil {
 		return err
 	}
 
+	if downloadErr == nil {
+		l.runPostUpdateHook(ctx, blacklist, sources)
+	}
+	return nil
+}
+
+// readLocalSource reads the local block lists, if enabled,
+// without the hostnames allowed by the settings given.
+func (l *Loop) readLocalSource(blacklist settings.DNSBlacklist) (
+	local blockbuilder.Result, err error) {
+	localPath := *blacklist.LocalListsPath
+	if localPath == "" {
+		return local, nil
+	}
+	local, err = l.readLocalBlockLists(localPath)
+	if err != nil {
+		return local, err
+	}
+	local.BlockedHostnames = filterAllowedHosts(local.BlockedHostnames, blacklist.AllowedHosts)
+	return local, nil
+}
+
+// setBlockLists merges the sources given, updates the filter
+// with them and records the update time given.
+func (l *Loop) setBlockLists(settings settings.DNS, sources []blockListSource,
+	updateTime time.Time) (err error) {
+	result := mergeBlockLists(sources)
+
+	l.blockListsMu.Lock()
+	l.downloaded = result
+	l.sources = sources
+	l.blockListsMu.Unlock()
+
+	err = l.updateFilter(settings)
+	if err != nil {
+		return err
+	}
+
+	l.detailsMu.Lock()
+	l.lastUpdate = updateTime
+	l.detailsMu.Unlock()
+	return nil
+}
+
+// updateFilter updates the filter using the last downloaded block lists
+// and the custom blocked hostnames and IPs from the settings given.
+func (l *Loop) updateFilter(settings settings.DNS) (err error) {
+	l.blockListsMu.RLock()
+	downloaded := l.downloaded
+	l.blockListsMu.RUnlock()
+
+	customHostnames := filterAllowedHosts(settings.DoT.Blacklist.AddBlockedHosts,
+		settings.DoT.Blacklist.AllowedHosts)
+	blockedHostnames := make([]string, 0, len(downloaded.BlockedHostnames)+len(customHostnames))
+	blockedHostnames = append(blockedHostnames, downloaded.BlockedHostnames...)
+	blockedHostnames = append(blockedHostnames, customHostnames...)
+
+	ipPrefixes := mergeUnique(downloaded.BlockedIPPrefixes,
+		settings.DoT.Blacklist.AddBlockedIPPrefixes)
+	aggregatedIPPrefixes := aggregatePrefixes(ipPrefixes)
+	if len(aggregatedIPPrefixes) < len(ipPrefixes) {
+		l.logger.Info(fmt.Sprintf("aggregated %d blocked IP prefixes into %d IP prefixes",
+			len(ipPrefixes), len(aggregatedIPPrefixes)))
+	}
+
 	updateSettings := update.Settings{
-		IPs:        result.BlockedIPs,
-		IPPrefixes: result.BlockedIPPrefixes,
+		IPs: mergeUnique(downloaded.BlockedIPs,
+			settings.DoT.Blacklist.AddBlockedIPs),
+		IPPrefixes: aggregatedIPPrefixes,
 	}
-	updateSettings.BlockHostnames(result.BlockedHostnames)
+	updateSettings.BlockHostnames(blockedHostnames)
 	err = l.filter.Update(updateSettings)
 	if err != nil {
-		return fmt.Errorf("updating filter: %w", err)
+		return fmt.Errorf("%w: %w", errUpdateFilter, err)
+	}
+
+	counts := models.DNSBlockListCounts{
+		Hostnames:  countUnique(updateSettings.FqdnHostnames),
+		IPs:        len(updateSettings.IPs),
+		IPPrefixes: len(updateSettings.IPPrefixes),
+		Time:       l.timeNow(),
+	}
+	l.metrics.setBlockListCounts(counts)
+	l.detailsMu.Lock()
+	l.blockListCounts = counts
+	l.detailsMu.Unlock()
+
+	if exportPath := *settings.DoT.Blacklist.ExportPath; exportPath != "" {
+		err = exportBlockLists(exportPath, blockedHostnames,
+			updateSettings.IPs, updateSettings.IPPrefixes)
+		if err != nil {
+			// the filter is updated, so only log the export error
+			l.logger.Warn("exporting block lists: " + err.Error())
+		}
+	}
+
+	if rpzPath := *settings.DoT.Blacklist.RPZPath; rpzPath != "" {
+		err = exportRPZ(rpzPath, l.nextRPZSerial(), blockedHostnames,
+			updateSettings.IPs, updateSettings.IPPrefixes)
+		if err != nil {
+			// the filter is updated, so only log the export error
+			l.logger.Warn("exporting block lists as RPZ: " + err.Error())
+		}
 	}
 
 	return nil
 }
+
+// blockListSource is a block list source, such as a
+// built-in category or the local block lists, with the
+// entries it contributed at the last update and the
+// validators of its block lists.
+type blockListSource struct {
+	name       string
+	result     blockbuilder.Result
+	validators blockListValidators
+}
+
+// downloadBlockLists downloads the block lists of each enabled
+// category, and the block lists URLs as the `urls` source,
+// concurrently, keeping the results of each category separate.
+// Custom blocked hosts and IPs are not included, since
+// they are merged in updateFilter. The download progress and a
+// summary of each category are logged with the logger given.
+// Categories with too many malformed lines are rejected.
+// Block lists of categories found in the previous sources given
+// are requested conditionally, and the previous entries of a
+// category are kept if none of its block lists changed.
+func downloadBlockLists(ctx context.Context, blacklist settings.DNSBlacklist,
+	client *http.Client, previous []blockListSource, logger Logger) (
+	sources []blockListSource, err error) {
+	categories := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "malicious", enabled: *blacklist.BlockMalicious},
+		{name: "ads", enabled: *blacklist.BlockAds},
+		{name: "surveillance", enabled: *blacklist.BlockSurveillance},
+	}
+
+	allowed := allowedKey(blacklist.AllowedHosts)
+	downloads := make([]categoryDownload, 0, len(categories))
+	for _, category := range categories {
+		if !category.enabled {
+			continue
+		}
+		categoryBlacklist := blacklist
+		categoryBlacklist.BlockMalicious = ptrTo(category.name == "malicious")
+		categoryBlacklist.BlockAds = ptrTo(category.name == "ads")
+		categoryBlacklist.BlockSurveillance = ptrTo(category.name == "surveillance")
+		categoryBlacklist.AddBlockedHosts = nil
+		categoryBlacklist.AddBlockedIPs = nil
+		categoryBlacklist.AddBlockedIPPrefixes = nil
+		downloads = append(downloads, categoryDownload{
+			name:      category.name,
+			blacklist: categoryBlacklist,
+			allowed:   allowed,
+			previous:  findPreviousSource(previous, category.name, allowed),
+		})
+	}
+
+	if len(blacklist.HostnamesURLs) > 0 || len(blacklist.IPsURLs) > 0 {
+		urlsAllowed := urlsKey(allowed, blacklist)
+		downloads = append(downloads, categoryDownload{
+			name:      urlsSourceName,
+			blacklist: blacklist,
+			allowed:   urlsAllowed,
+			previous:  findPreviousSource(previous, urlsSourceName, urlsAllowed),
+		})
+	}
+
+	sources = make([]blockListSource, len(downloads))
+	errs := make([]error, len(downloads))
+	var wg sync.WaitGroup
+	for i, download := range downloads {
+		wg.Add(1)
+		go func(i int, download categoryDownload) {
+			defer wg.Done()
+			sources[i], errs[i] = download.run(ctx, client, logger)
+		}(i, download)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
+
+// findPreviousSource returns the previous source with the name and
+// allowed key given having validators, to request its block lists
+// conditionally, or the zero source if there is none.
+func findPreviousSource(previous []blockListSource, name, allowed string) (
+	source blockListSource) {
+	for _, source := range previous {
+		if source.name == name && source.validators.Allowed == allowed &&
+			len(source.validators.URLs) > 0 {
+			return source
+		}
+	}
+	return blockListSource{}
+}
+
+// categoryDownload downloads the block lists of a category.
+type categoryDownload struct {
+	name string
+	// blacklist is the block lists settings with only
+	// the category enabled.
+	blacklist settings.DNSBlacklist
+	// allowed is the key of the allowed hosts of the settings.
+	allowed string
+	// previous is the previous source of the category, and has
+	// no validators if its block lists must be downloaded again.
+	previous blockListSource
+}
+
+func (d categoryDownload) run(ctx context.Context, client *http.Client,
+	logger Logger) (source blockListSource, err error) {
+	start := time.Now()
+	if len(d.previous.validators.URLs) > 0 {
+		source, downloaded, notModified, modified, err := d.build(ctx, client,
+			d.previous.validators.URLs, logger)
+		switch {
+		case err != nil:
+			return source, err
+		case notModified == 0:
+			d.logSummary(source, downloaded, start, logger)
+			return source, nil
+		case modified == 0:
+			logger.Info(fmt.Sprintf("%s block lists are unchanged since the last update",
+				d.name))
+			errs := source.result.Errors
+			source.result = d.previous.result
+			source.result.Errors = errs
+			return source, nil
+		}
+		// Entries of the block lists not modified are missing,
+		// so download all the block lists of the category again.
+		logger.Info(fmt.Sprintf("%s block lists: %d of %d block lists changed, "+
+			"downloading all of them", d.name, modified, notModified+modified))
+	}
+
+	source, downloaded, _, _, err := d.build(ctx, client, nil, logger)
+	if err != nil {
+		return source, err
+	}
+	d.logSummary(source, downloaded, start, logger)
+	return source, nil
+}
+
+// build builds the block lists of the category, sending conditional
+// requests with the validators given if they are not nil. It returns
+// the source built, the number of bytes downloaded, and the numbers
+// of block lists not modified and modified.
+func (d categoryDownload) build(ctx context.Context, client *http.Client,
+	validators map[string]listValidator, logger Logger) (source blockListSource,
+	downloaded int64, notModified, modified int, err error) {
+	source.name = d.name
+	conditionalClient, conditional := newConditionalClient(client, validators)
+	const progressInterval = 10 * time.Second
+	progressClient, progress := newProgressClient(conditionalClient, logger,
+		d.name, progressInterval)
+	checkClient, checker := newLineCheckClient(progressClient)
+	if d.name == urlsSourceName {
+		source.result = buildURLBlockLists(ctx, checkClient, d.blacklist)
+	} else {
+		builder, err := blockbuilder.New(d.blacklist.ToBlockBuilderSettings(checkClient))
+		if err != nil {
+			return source, 0, 0, 0, fmt.Errorf("creating block builder for %s: %w", d.name, err)
+		}
+		source.result = builder.BuildAll(ctx)
+	}
+	checker.check(&source, *d.blacklist.MaxMalformedPercent,
+		*d.blacklist.LogMalformed, logger)
+	notModified, modified, urlValidators := conditional.counts()
+	source.validators = blockListValidators{
+		Allowed: d.allowed,
+		URLs:    urlValidators,
+	}
+	return source, progress.total(), notModified, modified, nil
+}
+
+func (d categoryDownload) logSummary(source blockListSource, downloaded int64,
+	start time.Time, logger Logger) {
+	result := source.result
+	logger.Info(fmt.Sprintf("%s block lists: %d hostnames, %d IP addresses "+
+		"and %d IP prefixes from %s downloaded in %s",
+		d.name, len(result.BlockedHostnames), len(result.BlockedIPs),
+		len(result.BlockedIPPrefixes), formatBytes(downloaded),
+		time.Since(start).Round(time.Millisecond)))
+}
+
+func ptrTo[T any](value T) *T { return &value }
+
+func isEmptyResult(result blockbuilder.Result) bool {
+	return len(result.BlockedHostnames) == 0 && len(result.BlockedIPs) == 0 &&
+		len(result.BlockedIPPrefixes) == 0
+}
+
+func filterAllowedHosts(hostnames, allowedHosts []string) (filtered []string) {
+	filtered = make([]string, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		allowed := false
+		for _, allowedHost := range allowedHosts {
+			if hostname == allowedHost || strings.HasSuffix(hostname, "."+allowedHost) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			filtered = append(filtered, hostname)
+		}
+	}
+	return filtered
+}
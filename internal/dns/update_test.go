@@ -0,0 +1,48 @@
+package dns
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qdm12/dns/v2/pkg/middlewares/filter/mapfilter"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Loop_updateFilter(t *testing.T) {
+	t.Parallel()
+
+	metrics, err := newMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	filter, err := mapfilter.New(mapfilter.Settings{Metrics: metrics})
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	loop := &Loop{
+		filter:  filter,
+		metrics: metrics,
+		logger:  noopLogger{},
+		timeNow: func() time.Time { return now },
+	}
+	var allSettings settings.Settings
+	allSettings.SetDefaults()
+	dnsSettings := allSettings.DNS
+	dnsSettings.DoT.Blacklist.AddBlockedHosts = []string{"ads.example.com"}
+	dnsSettings.DoT.Blacklist.AddBlockedIPs = []netip.Addr{
+		netip.AddrFrom4([4]byte{1, 2, 3, 4}),
+	}
+
+	err = loop.updateFilter(dnsSettings)
+
+	require.NoError(t, err)
+	expected := models.DNSBlockListCounts{
+		Hostnames: 1,
+		IPs:       1,
+		Time:      now,
+	}
+	assert.Equal(t, expected, loop.blockListCounts)
+}
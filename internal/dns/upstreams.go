@@ -0,0 +1,117 @@
+package dns
+
+import (
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/qdm12/dns/v2/pkg/provider"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// dialTracker implements the DNS over TLS resolver metrics interface
+// to record the upstream address last dialed for each provider, keyed
+// by the provider TLS server name. If the upstream proxy is used, the
+// addresses dialed are the addresses of the local relays.
+type dialTracker struct {
+	mutex  sync.Mutex
+	dials  map[string]upstreamDial
+	logger Logger
+}
+
+type upstreamDial struct {
+	address string
+	outcome string
+}
+
+func newDialTracker(logger Logger) *dialTracker {
+	return &dialTracker{
+		dials:  make(map[string]upstreamDial),
+		logger: logger,
+	}
+}
+
+func (t *dialTracker) DoTDialInc(serverName, address, outcome string) {
+	t.mutex.Lock()
+	previous := t.dials[serverName]
+	t.dials[serverName] = upstreamDial{address: address, outcome: outcome}
+	t.mutex.Unlock()
+
+	if previous.address != address {
+		t.logger.Debug("dialing DNS over TLS server " + serverName + " at address " + address)
+	}
+}
+
+func (t *dialTracker) DNSDialInc(string, string) {}
+
+func (t *dialTracker) lastDial(serverName string) (dial upstreamDial) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.dials[serverName]
+}
+
+func (t *dialTracker) reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	clear(t.dials)
+}
+
+// setUpstreams records the DNS over TLS providers given as the
+// providers in use, and logs their addresses for the IP version
+// given. Providers can be nil if the DNS over TLS server stopped.
+func (l *Loop) setUpstreams(providers []provider.Provider, ipv6 bool) {
+	l.dialTracker.reset()
+
+	l.detailsMu.Lock()
+	l.upstreamProviders = providers
+	l.upstreamsIPv6 = ipv6
+	l.detailsMu.Unlock()
+
+	if len(providers) == 0 {
+		return
+	}
+	descriptions := make([]string, len(providers))
+	for i, upstream := range providers {
+		addresses := upstreamAddresses(upstream, ipv6)
+		addressStrings := make([]string, len(addresses))
+		for j, address := range addresses {
+			addressStrings[j] = address.String()
+		}
+		descriptions[i] = upstream.Name + " at " + strings.Join(addressStrings, ", ")
+	}
+	l.logger.Info("DNS over TLS upstream resolvers: " + strings.Join(descriptions, "; "))
+}
+
+// getUpstreams returns the DNS over TLS upstream resolvers
+// in use, with the address last dialed for each of them.
+func (l *Loop) getUpstreams() (upstreams []models.DNSUpstream) {
+	l.detailsMu.RLock()
+	providers := l.upstreamProviders
+	ipv6 := l.upstreamsIPv6
+	l.detailsMu.RUnlock()
+
+	upstreams = make([]models.DNSUpstream, len(providers))
+	for i, upstream := range providers {
+		dial := l.dialTracker.lastDial(upstream.DoT.Name)
+		upstreams[i] = models.DNSUpstream{
+			Provider:        upstream.Name,
+			Addresses:       upstreamAddresses(upstream, ipv6),
+			LastDialed:      dial.address,
+			LastDialOutcome: dial.outcome,
+		}
+	}
+	return upstreams
+}
+
+// upstreamAddresses returns the DNS over TLS addresses of the
+// provider given the server can connect to, which are the IPv6
+// addresses as well if IPv6 is used.
+func upstreamAddresses(upstream provider.Provider, ipv6 bool) (
+	addresses []netip.AddrPort) {
+	addresses = make([]netip.AddrPort, 0, len(upstream.DoT.IPv4)+len(upstream.DoT.IPv6))
+	addresses = append(addresses, upstream.DoT.IPv4...)
+	if ipv6 {
+		addresses = append(addresses, upstream.DoT.IPv6...)
+	}
+	return addresses
+}
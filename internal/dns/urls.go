@@ -0,0 +1,124 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/qdm12/dns/v2/pkg/blockbuilder"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+)
+
+// urlsSourceName is the name of the block list source
+// of the block lists URLs set in the settings.
+const urlsSourceName = "urls"
+
+// urlsKey returns the allowed key given extended with the block
+// lists URLs of the settings given, so the previous entries of
+// the URLs source are not kept if its URLs changed.
+func urlsKey(allowed string, blacklist settings.DNSBlacklist) string {
+	return allowed + "|" + strings.Join(blacklist.HostnamesURLs, ",") +
+		"|" + strings.Join(blacklist.IPsURLs, ",")
+}
+
+// buildURLBlockLists downloads the hostnames and IP addresses block
+// lists at the URLs of the settings given, and removes the hostnames
+// allowed. Download errors are set in the result errors, the same way
+// the block builder does for the built-in block lists.
+func buildURLBlockLists(ctx context.Context, client *http.Client,
+	blacklist settings.DNSBlacklist) (result blockbuilder.Result) {
+	hostnames, hostnamesErrs := fetchBlockLists(ctx, client, blacklist.HostnamesURLs)
+	ipLines, ipsErrs := fetchBlockLists(ctx, client, blacklist.IPsURLs)
+	result.Errors = append(hostnamesErrs, ipsErrs...)
+
+	result.BlockedHostnames = filterAllowedHosts(hostnames, blacklist.AllowedHosts)
+	for _, line := range ipLines {
+		ip, err := netip.ParseAddr(line)
+		if err == nil {
+			result.BlockedIPs = append(result.BlockedIPs, ip)
+			continue
+		}
+
+		ipPrefix, err := netip.ParsePrefix(line)
+		if err == nil {
+			result.BlockedIPPrefixes = append(result.BlockedIPPrefixes, ipPrefix)
+		}
+	}
+	return result
+}
+
+// fetchBlockLists downloads the block lists at the URLs given
+// concurrently, and returns their unique lines together with
+// the download errors.
+func fetchBlockLists(ctx context.Context, client *http.Client, urls []string) (
+	lines []string, errs []error) {
+	results := make([][]string, len(urls))
+	urlErrs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			results[i], urlErrs[i] = fetchBlockList(ctx, client, url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	for i := range urls {
+		if urlErrs[i] != nil {
+			errs = append(errs, urlErrs[i])
+			continue
+		}
+		lines = mergeUnique(lines, results[i])
+	}
+	return lines, errs
+}
+
+var errBlockListBadStatus = errors.New("bad HTTP status code")
+
+// fetchBlockList downloads the block list at the URL given, and
+// returns its lines, without empty lines and lines starting with #.
+func fetchBlockList(ctx context.Context, client *http.Client, url string) (
+	lines []string, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s: %w", url, err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("%w: %s: %s", errBlockListBadStatus, url, response.Status)
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+
+	err = response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("closing response body of %s: %w", url, err)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,77 @@
+package dns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/qdm12/dns/v2/pkg/blockbuilder"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_buildURLBlockLists(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/hostnames1":
+				_, _ = w.Write([]byte("# comment\nads.com\n\n  tracker.net \nsub.allowed.org\n"))
+			case "/hostnames2":
+				_, _ = w.Write([]byte("ads.com\nmalware.io"))
+			case "/ips":
+				_, _ = w.Write([]byte("1.2.3.4\n10.0.0.0/8\n# comment\nnot-an-ip\n"))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+	t.Cleanup(server.Close)
+
+	testCases := map[string]struct {
+		blacklist  settings.DNSBlacklist
+		result     blockbuilder.Result
+		errorCount int
+	}{
+		"no_url": {},
+		"hostnames_and_ips": {
+			blacklist: settings.DNSBlacklist{
+				HostnamesURLs: []string{server.URL + "/hostnames1", server.URL + "/hostnames2"},
+				IPsURLs:       []string{server.URL + "/ips"},
+				AllowedHosts:  []string{"allowed.org"},
+			},
+			result: blockbuilder.Result{
+				BlockedHostnames:  []string{"ads.com", "tracker.net", "malware.io"},
+				BlockedIPs:        []netip.Addr{netip.MustParseAddr("1.2.3.4")},
+				BlockedIPPrefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			},
+		},
+		"download_error": {
+			blacklist: settings.DNSBlacklist{
+				HostnamesURLs: []string{server.URL + "/hostnames2", server.URL + "/missing"},
+			},
+			result: blockbuilder.Result{
+				BlockedHostnames: []string{"ads.com", "malware.io"},
+			},
+			errorCount: 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			result := buildURLBlockLists(context.Background(), server.Client(), testCase.blacklist)
+
+			require.Len(t, result.Errors, testCase.errorCount)
+			result.Errors = nil
+			assert.ElementsMatch(t, testCase.result.BlockedHostnames, result.BlockedHostnames)
+			assert.Equal(t, testCase.result.BlockedIPs, result.BlockedIPs)
+			assert.Equal(t, testCase.result.BlockedIPPrefixes, result.BlockedIPPrefixes)
+		})
+	}
+}
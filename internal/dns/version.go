@@ -0,0 +1,56 @@
+package dns
+
+import (
+	"runtime/debug"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+const dnsModulePath = "github.com/qdm12/dns/v2"
+
+// GetVersion returns the version of the DNS over TLS server
+// library built in the program, and the features enabled in
+// the current settings.
+func (l *Loop) GetVersion() (version models.DNSVersion) {
+	version.Server = "DNS over TLS server " + dnsModulePath
+	version.Version = "unknown"
+	buildInfo, ok := debug.ReadBuildInfo()
+	if ok {
+		for _, module := range buildInfo.Deps {
+			if module.Path != dnsModulePath {
+				continue
+			}
+			version.Version = module.Version
+			if module.Replace != nil {
+				version.Version = module.Replace.Version
+			}
+			break
+		}
+	}
+
+	version.QueryLog = l.queryLogger.enabled.Load()
+
+	settings := l.GetSettings()
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "dnssec", enabled: *settings.DNSSEC},
+		{name: "edns client subnet", enabled: *settings.EDNSClientSubnet},
+		{name: "dns64", enabled: *settings.DNS64.Enabled},
+		{name: "caching", enabled: *settings.DoT.Caching},
+		{name: "prefetch", enabled: *settings.DoT.Caching && *settings.DoT.CachePrefetch},
+		{name: "serve expired", enabled: *settings.DoT.Caching && *settings.DoT.ServeExpired},
+		{name: "negative caching", enabled: *settings.DoT.Caching && *settings.DoT.NegativeCacheSize > 0},
+		{name: "forward zones", enabled: len(settings.ForwardZones) > 0},
+		{name: "static records", enabled: len(settings.Records) > 0},
+		{name: "ipv6", enabled: l.useIPv6(settings)},
+	}
+	version.Features = make([]string, 0, len(features))
+	for _, feature := range features {
+		if feature.enabled {
+			version.Features = append(version.Features, feature.name)
+		}
+	}
+	return version
+}
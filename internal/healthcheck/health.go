@@ -23,16 +23,24 @@ func (s *Server) runHealthcheckLoop(ctx context.Context, done chan<- struct{}) {
 	}
 	s.vpn.healthyTimer = time.NewTimer(s.vpn.healthyWait)
 
+	previousErr := errHealthcheckNotRunYet
 	for {
-		previousErr := s.handler.getErr()
-
 		timeout := healthcheckTimeouts[timeoutIndex]
 		healthcheckCtx, healthcheckCancel := context.WithTimeout(
 			ctx, timeout)
 		err := s.healthCheck(healthcheckCtx)
+		handlerErr := err
+		if handlerErr == nil && *s.config.DNSOverTLS {
+			// DNS over TLS failures only make the program unhealthy,
+			// since restarting the VPN would not fix them.
+			handlerErr = s.dns.CheckDoT(healthcheckCtx)
+			if handlerErr != nil {
+				handlerErr = fmt.Errorf("checking DNS over TLS: %w", handlerErr)
+			}
+		}
 		healthcheckCancel()
 
-		s.handler.setErr(err)
+		s.handler.setErr(handlerErr)
 
 		switch {
 		case previousErr != nil && err == nil: // First success
@@ -62,6 +70,7 @@ func (s *Server) runHealthcheckLoop(ctx context.Context, done chan<- struct{}) {
 			case <-timer.C:
 			}
 		}
+		previousErr = err
 	}
 }
 
@@ -14,10 +14,11 @@ type Server struct {
 	dialer  *net.Dialer
 	config  settings.Health
 	vpn     vpnHealth
+	dns     DNSChecker
 }
 
 func NewServer(config settings.Health,
-	logger Logger, vpnLoop StatusApplier) *Server {
+	logger Logger, vpnLoop StatusApplier, dnsChecker DNSChecker) *Server {
 	return &Server{
 		logger:  logger,
 		handler: newHandler(),
@@ -31,6 +32,7 @@ func NewServer(config settings.Health,
 			loop:        vpnLoop,
 			healthyWait: *config.VPN.Initial,
 		},
+		dns: dnsChecker,
 	}
 }
 
@@ -38,3 +40,7 @@ type StatusApplier interface {
 	ApplyStatus(ctx context.Context, status models.LoopStatus) (
 		outcome string, err error)
 }
+
+type DNSChecker interface {
+	CheckDoT(ctx context.Context) (err error)
+}
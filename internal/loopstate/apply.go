@@ -51,7 +51,9 @@ func (s *State) ApplyStatus(ctx context.Context, status models.LoopStatus) (
 
 		return newStatus.String(), nil
 	case constants.Stopped:
-		if existingStatus != constants.Running {
+		switch existingStatus {
+		case constants.Running, constants.Paused:
+		default:
 			s.statusMu.Unlock()
 			return "already " + existingStatus.String(), nil
 		}
@@ -0,0 +1,262 @@
+package models
+
+import (
+	"net/netip"
+	"time"
+)
+
+// DNSStatus contains detailed status information of the DNS loop.
+type DNSStatus struct {
+	Status LoopStatus `json:"status"`
+	// PlaintextFallback is true if plaintext DNS is in use
+	// because the DNS over TLS server failed.
+	PlaintextFallback bool `json:"plaintext_fallback"`
+	// Encrypted is true if DNS queries are answered by the
+	// DNS over TLS server, and false if plaintext DNS or the
+	// existing nameserver is used.
+	Encrypted bool `json:"encrypted"`
+	// Upstreams are the DNS over TLS upstream resolvers of the
+	// providers in use, and is empty if the DNS over TLS server
+	// is not running.
+	Upstreams []DNSUpstream `json:"upstreams"`
+	// PlaintextAddress is the plaintext DNS address selected,
+	// and is empty if plaintext DNS is not in use.
+	PlaintextAddress string `json:"plaintext_address,omitempty"`
+	// BackoffTime is the duration to wait before the next
+	// restart attempt if the DNS over TLS server fails.
+	BackoffTime time.Duration `json:"backoff_time"`
+	// PermanentError is the last DNS over TLS server setup error
+	// which cannot be fixed by retrying, such as a settings error,
+	// and is empty if the server setup succeeded since.
+	PermanentError string `json:"permanent_error,omitempty"`
+	// ResolvConfError is the last error writing the nameserver
+	// to resolv.conf, and is empty if the last write succeeded.
+	// The system then does not use the expected DNS server.
+	ResolvConfError string `json:"resolv_conf_error,omitempty"`
+	// EDNSClientSubnet is true if EDNS client subnet options
+	// of queries are forwarded to the upstream resolvers.
+	EDNSClientSubnet bool `json:"edns_client_subnet"`
+	// LastUpdate is the time of the last successful block lists
+	// update, and is the zero time if no update succeeded yet.
+	LastUpdate time.Time `json:"last_update"`
+	// BlockListCounts are the numbers of entries
+	// loaded in the DNS server filter.
+	BlockListCounts DNSBlockListCounts `json:"block_list_counts"`
+	// LastEvent is the last event emitted, such as the DNS
+	// degrading to plaintext, and is nil if no event occurred.
+	LastEvent *DNSEvent `json:"last_event"`
+}
+
+// DNSUpstream contains information on the DNS over TLS
+// upstream resolver of a provider.
+type DNSUpstream struct {
+	Provider string `json:"provider"`
+	// Addresses are the addresses of the provider the
+	// DNS over TLS server can connect to.
+	Addresses []netip.AddrPort `json:"addresses"`
+	// LastDialed is the address of the provider last dialed
+	// by the DNS over TLS server, and is empty if none was
+	// dialed yet.
+	LastDialed string `json:"last_dialed,omitempty"`
+	// LastDialOutcome is `success` or `error` depending on
+	// the outcome of the last dial, and is empty if none
+	// was dialed yet.
+	LastDialOutcome string `json:"last_dial_outcome,omitempty"`
+}
+
+// DNSBlockListSources contains information on the block list
+// sources used at the last block lists update.
+type DNSBlockListSources struct {
+	Sources []DNSBlockListSource `json:"sources"`
+	// LastUpdate is the time of the last successful block lists
+	// update, and is the zero time if no update succeeded yet.
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// DNSNextUpdate contains information on the next
+// scheduled block lists update.
+type DNSNextUpdate struct {
+	// Enabled is false if periodic block lists
+	// updates are disabled.
+	Enabled bool `json:"enabled"`
+	// Paused is true if periodic block lists updates
+	// are paused until the DNS loop is resumed.
+	Paused bool `json:"paused"`
+	// Time is the time of the next block lists update,
+	// and is the zero time if no update is scheduled.
+	Time time.Time `json:"time"`
+	// Schedule is the cron schedule of the updates,
+	// and is empty if the update period is used.
+	Schedule string `json:"schedule,omitempty"`
+	// Period is the period between updates, and is zero
+	// if the cron schedule is used or updates are disabled.
+	Period time.Duration `json:"period"`
+}
+
+// DNSBlockListCounts contains the number of entries
+// loaded in the DNS server filter, including the custom
+// blocked hostnames, IPs and IP prefixes.
+type DNSBlockListCounts struct {
+	Hostnames  int `json:"hostnames"`
+	IPs        int `json:"ips"`
+	IPPrefixes int `json:"ip_prefixes"`
+	// Time is the time the entries were counted, and is
+	// the zero time if the filter was not updated yet.
+	Time time.Time `json:"time"`
+}
+
+// DNSBlockListCategories contains the built-in block list
+// categories to enable or disable. A nil field leaves
+// the category unchanged.
+type DNSBlockListCategories struct {
+	Malicious    *bool `json:"malicious"`
+	Ads          *bool `json:"ads"`
+	Surveillance *bool `json:"surveillance"`
+}
+
+// DNSBlockListSource contains the number of entries
+// a block list source contributed.
+type DNSBlockListSource struct {
+	// Name is the source name, which is a built-in category
+	// such as `malicious`, or `urls`, `local` or `custom`.
+	Name       string `json:"name"`
+	Hostnames  int    `json:"hostnames"`
+	IPs        int    `json:"ips"`
+	IPPrefixes int    `json:"ip_prefixes"`
+	// LastError is the download error of the source block
+	// lists at the last update, and is empty if the source
+	// block lists downloaded without error.
+	LastError string `json:"last_error,omitempty"`
+	// FailingSince is the time of the first update the source
+	// failed at since it last succeeded, and is nil if the
+	// source block lists downloaded without error.
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+}
+
+// DNSBlockCheck is the result of checking if a hostname
+// is blocked by the DNS server filter.
+type DNSBlockCheck struct {
+	Hostname string `json:"hostname"`
+	Blocked  bool   `json:"blocked"`
+	// Matches are the block list entries matching the
+	// hostname or one of its resolved IP addresses.
+	Matches []DNSBlockMatch `json:"matches"`
+	// ResolveError is set if the hostname IP addresses
+	// could not be resolved to be checked.
+	ResolveError string `json:"resolve_error,omitempty"`
+}
+
+// DNSBlockMatch is a block list entry matching a hostname.
+type DNSBlockMatch struct {
+	// Source is the block list source name, such as `ads`.
+	Source string `json:"source"`
+	// Entry is the blocked parent hostname, IP address or
+	// IP prefix matched.
+	Entry string `json:"entry"`
+}
+
+// DNSCacheFlush is the result of flushing the DNS server cache.
+type DNSCacheFlush struct {
+	// Domain is the domain flushed together with its
+	// subdomains, and is empty if the whole cache is flushed.
+	Domain string `json:"domain,omitempty"`
+	// Flushed is the number of cached responses flushed.
+	Flushed int `json:"flushed"`
+}
+
+// DNSEventType is the type of a DNS event.
+type DNSEventType string
+
+const (
+	// DNSEventDegraded is emitted when the DNS falls back
+	// to plaintext DNS because DNS over TLS failed.
+	DNSEventDegraded DNSEventType = "degraded"
+	// DNSEventRestored is emitted when DNS over TLS is
+	// working again after falling back to plaintext DNS.
+	DNSEventRestored DNSEventType = "restored"
+)
+
+// DNSEvent is an event emitted by the DNS loop.
+type DNSEvent struct {
+	Type DNSEventType `json:"type"`
+	Time time.Time    `json:"time"`
+}
+
+// DNSVersion contains version information of the DNS server.
+type DNSVersion struct {
+	// Server is the DNS server implementation name.
+	Server string `json:"server"`
+	// Version is the DNS server library module version,
+	// or `unknown` if the build information is not available.
+	Version string `json:"version"`
+	// QueryLog is true if each DNS query is logged.
+	QueryLog bool `json:"query_log"`
+	// Features are the DNS server features enabled
+	// in the current settings, such as `dnssec`.
+	Features []string `json:"features"`
+}
+
+// DNSResolver contains the nameservers in use by the
+// system and by the Go program.
+type DNSResolver struct {
+	// SystemNameservers are the nameservers currently
+	// set in the resolv.conf file.
+	SystemNameservers []netip.Addr `json:"system_nameservers"`
+	// SystemError is the error reading the resolv.conf
+	// file, if any.
+	SystemError string `json:"system_error,omitempty"`
+	// InternalResolver is the address used by the Go program
+	// resolver, and is empty if the Go default resolver is used.
+	InternalResolver string `json:"internal_resolver"`
+	// KeepNameserver is true if the original nameservers
+	// of the container are kept.
+	KeepNameserver bool `json:"keep_nameserver"`
+	// OriginalNameservers are the nameservers found in the
+	// resolv.conf file on start, before it is overwritten.
+	OriginalNameservers []netip.Addr `json:"original_nameservers"`
+}
+
+// DNSLogs contains the last warning and error
+// lines logged by the DNS loop.
+type DNSLogs struct {
+	// Generation is the current DNS server run generation,
+	// incremented each time the DNS server is set up.
+	Generation uint `json:"generation"`
+	// Lines are the last lines logged, from oldest to newest.
+	Lines []DNSLogLine `json:"lines"`
+}
+
+// DNSLogLine is a warning or error line logged by the DNS loop.
+type DNSLogLine struct {
+	Time time.Time `json:"time"`
+	// Level is `warn` or `error`.
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	// Generation is the DNS server run generation
+	// the line was logged in.
+	Generation uint `json:"generation"`
+}
+
+// DNSResolution is the result of resolving a name through
+// the resolver currently used by the Go program.
+type DNSResolution struct {
+	Name string `json:"name"`
+	// Type is the record type queried, such as `A`.
+	Type string `json:"type"`
+	// Resolver is the address of the resolver queried.
+	Resolver string `json:"resolver"`
+	// Mode is `dot` if the DNS over TLS server answered,
+	// `plaintext` if a plaintext DNS server answered or
+	// `plaintext_fallback` if a plaintext DNS server answered
+	// because the DNS over TLS server failed.
+	Mode string `json:"mode"`
+	// Rcode is the response code, such as `NOERROR`.
+	Rcode string `json:"rcode"`
+	// Answers are the answer records in presentation format.
+	Answers []string `json:"answers"`
+	// Duration is the time taken to get the response.
+	Duration time.Duration `json:"duration"`
+	// Cached is true if the DNS over TLS server answered
+	// from its cache without querying the upstream resolvers.
+	Cached bool `json:"cached"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// Health contains the status of each subsystem, and is
+// healthy only if all the required subsystems are running.
+type Health struct {
+	Healthy    bool                       `json:"healthy"`
+	Subsystems map[string]SubsystemHealth `json:"subsystems"`
+}
+
+type SubsystemHealth struct {
+	Status LoopStatus `json:"status"`
+	// Required is false if the subsystem is disabled,
+	// in which case its status is ignored.
+	Required bool `json:"required"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OpenVPNStats contains the statistics of the OpenVPN connection.
+type OpenVPNStats struct {
+	// State is the OpenVPN state, for example CONNECTED or RECONNECTING.
+	State string `json:"state"`
+	// ConnectedSince is the time OpenVPN entered its current state,
+	// which is the connection time if the state is CONNECTED.
+	ConnectedSince time.Time `json:"connected_since"`
+	RemoteEndpoint string    `json:"remote_endpoint,omitempty"`
+	TunnelAddress  string    `json:"tunnel_address,omitempty"`
+	// Cipher is the data channel cipher, and is empty if
+	// it is no longer found in the OpenVPN logs history.
+	Cipher        string `json:"cipher,omitempty"`
+	ReceivedBytes int64  `json:"received_bytes"`
+	SentBytes     int64  `json:"sent_bytes"`
+}
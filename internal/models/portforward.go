@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PortsForwarded contains the ports currently forwarded
+// and when they were last assigned.
+type PortsForwarded struct {
+	Ports []uint16 `json:"ports"`
+	// AssignedAt is the zero time if no port was forwarded yet.
+	AssignedAt time.Time `json:"assigned_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// WireguardStats contains the statistics of the Wireguard interface.
+type WireguardStats struct {
+	Interface string          `json:"interface"`
+	Peers     []WireguardPeer `json:"peers"`
+}
+
+type WireguardPeer struct {
+	PublicKey string `json:"public_key"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	// LastHandshake is the zero time if no handshake happened yet.
+	LastHandshake time.Time `json:"last_handshake"`
+	ReceivedBytes int64     `json:"received_bytes"`
+	SentBytes     int64     `json:"sent_bytes"`
+}
@@ -1,3 +1,7 @@
 package openvpn
 
 const configPath = "/etc/openvpn/target.ovpn"
+
+// managementPath is the Unix socket path of the OpenVPN management
+// interface, used to query the connection statistics.
+const managementPath = "/etc/openvpn/management.sock"
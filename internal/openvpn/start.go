@@ -29,7 +29,7 @@ func start(ctx context.Context, starter CmdStarter, version string, flags []stri
 		return nil, nil, nil, fmt.Errorf("%w: %s", ErrVersionUnknown, version)
 	}
 
-	args := []string{"--config", configPath}
+	args := []string{"--config", configPath, "--management", managementPath, "unix"}
 	args = append(args, flags...)
 	cmd := exec.CommandContext(ctx, bin, args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
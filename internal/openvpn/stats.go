@@ -0,0 +1,188 @@
+package openvpn
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+var (
+	ErrManagementUnavailable = errors.New("OpenVPN management interface is unavailable")
+	errManagementCommand     = errors.New("OpenVPN management command failed")
+	errStateNotValid         = errors.New("OpenVPN state is not valid")
+	errLoadStatsNotValid     = errors.New("OpenVPN load statistics are not valid")
+)
+
+// Stats returns the statistics of the OpenVPN connection, queried from
+// the OpenVPN management interface. It returns an error wrapping
+// ErrManagementUnavailable if the management interface cannot be reached,
+// for example while OpenVPN is restarting.
+func Stats(ctx context.Context) (stats models.OpenVPNStats, err error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "unix", managementPath)
+	if err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrManagementUnavailable, err)
+	}
+	defer conn.Close()
+
+	const timeout = 5 * time.Second
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return stats, fmt.Errorf("setting deadline: %w", err)
+	}
+
+	client := &managementClient{conn: conn, reader: bufio.NewReader(conn)}
+
+	stateLines, err := client.command("state", true)
+	if err != nil {
+		return stats, err
+	}
+	stats, err = parseState(stateLines)
+	if err != nil {
+		return stats, err
+	}
+
+	loadStatsLines, err := client.command("load-stats", false)
+	if err != nil {
+		return stats, err
+	}
+	stats.ReceivedBytes, stats.SentBytes, err = parseLoadStats(loadStatsLines[0])
+	if err != nil {
+		return stats, err
+	}
+
+	logLines, err := client.command("log all", true)
+	if err != nil {
+		return stats, err
+	}
+	stats.Cipher = parseCipher(logLines)
+
+	return stats, nil
+}
+
+type managementClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// command sends the command given and returns its response lines,
+// ignoring real time notification lines starting with '>'. Multi-line
+// responses end with an END line, and single line responses start
+// with SUCCESS, which is trimmed from the line returned.
+func (c *managementClient) command(command string, multiLine bool) (
+	lines []string, err error) {
+	_, err = c.conn.Write([]byte(command + "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: writing command %q: %w",
+			ErrManagementUnavailable, command, err)
+	}
+
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading response to command %q: %w",
+				ErrManagementUnavailable, command, err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, ">"):
+			continue
+		case strings.HasPrefix(line, "ERROR:"):
+			return nil, fmt.Errorf("%w: %s: %s", errManagementCommand, command,
+				strings.TrimSpace(strings.TrimPrefix(line, "ERROR:")))
+		case !multiLine:
+			line = strings.TrimSpace(strings.TrimPrefix(line, "SUCCESS:"))
+			return []string{line}, nil
+		case line == "END":
+			return lines, nil
+		default:
+			lines = append(lines, line)
+		}
+	}
+}
+
+// parseState parses the last line of the state command response, in the
+// format "time,state,description,tunnel ip,remote ip,remote port,...".
+func parseState(lines []string) (stats models.OpenVPNStats, err error) {
+	if len(lines) == 0 {
+		return stats, fmt.Errorf("%w: no state line", errStateNotValid)
+	}
+	line := lines[len(lines)-1]
+	fields := strings.Split(line, ",")
+	const minFields = 6
+	if len(fields) < minFields {
+		return stats, fmt.Errorf("%w: %q has %d fields instead of at least %d",
+			errStateNotValid, line, len(fields), minFields)
+	}
+
+	unixSeconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return stats, fmt.Errorf("%w: time: %w", errStateNotValid, err)
+	}
+
+	stats.State = fields[1]
+	stats.ConnectedSince = time.Unix(unixSeconds, 0)
+	stats.TunnelAddress = fields[3]
+	remoteIP, remotePort := fields[4], fields[5]
+	if remoteIP != "" {
+		stats.RemoteEndpoint = remoteIP
+		if remotePort != "" {
+			stats.RemoteEndpoint = net.JoinHostPort(remoteIP, remotePort)
+		}
+	}
+	return stats, nil
+}
+
+// parseLoadStats parses the load-stats command response,
+// in the format "nclients=0,bytesin=123,bytesout=456".
+func parseLoadStats(line string) (received, sent int64, err error) {
+	var receivedFound, sentFound bool
+	for _, field := range strings.Split(line, ",") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "bytesin":
+			received, err = strconv.ParseInt(value, 10, 64)
+			receivedFound = true
+		case "bytesout":
+			sent, err = strconv.ParseInt(value, 10, 64)
+			sentFound = true
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %s: %w", errLoadStatsNotValid, key, err)
+		}
+	}
+
+	if !receivedFound || !sentFound {
+		return 0, 0, fmt.Errorf("%w: %q", errLoadStatsNotValid, line)
+	}
+	return received, sent, nil
+}
+
+var regexCipher = regexp.MustCompile(`(?i)data channel: (?:using negotiated )?cipher '([^']+)'`)
+
+// parseCipher returns the data channel cipher of the most recent log line
+// mentioning it, or the empty string if no log line mentions it.
+func parseCipher(logLines []string) (cipher string) {
+	for i := len(logLines) - 1; i >= 0; i-- {
+		matches := regexCipher.FindStringSubmatch(logLines[i])
+		if matches != nil {
+			return matches[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package openvpn
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseState(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		lines      []string
+		stats      models.OpenVPNStats
+		errWrapped error
+	}{
+		"no_line": {
+			errWrapped: errStateNotValid,
+		},
+		"too_few_fields": {
+			lines:      []string{"1700000000,CONNECTED"},
+			errWrapped: errStateNotValid,
+		},
+		"connected": {
+			lines: []string{
+				"1699999990,ASSIGN_IP,,10.8.0.2,,,,",
+				"1700000000,CONNECTED,SUCCESS,10.8.0.2,1.2.3.4,1194,,",
+			},
+			stats: models.OpenVPNStats{
+				State:          "CONNECTED",
+				ConnectedSince: time.Unix(1700000000, 0),
+				RemoteEndpoint: "1.2.3.4:1194",
+				TunnelAddress:  "10.8.0.2",
+			},
+		},
+		"reconnecting": {
+			lines: []string{"1700000000,RECONNECTING,ping-restart,,,,,"},
+			stats: models.OpenVPNStats{
+				State:          "RECONNECTING",
+				ConnectedSince: time.Unix(1700000000, 0),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stats, err := parseState(testCase.lines)
+
+			assert.True(t, errors.Is(err, testCase.errWrapped))
+			if testCase.errWrapped == nil {
+				assert.Equal(t, testCase.stats, stats)
+			}
+		})
+	}
+}
+
+func Test_parseLoadStats(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		line       string
+		received   int64
+		sent       int64
+		errWrapped error
+	}{
+		"valid": {
+			line:     "nclients=0,bytesin=123,bytesout=456",
+			received: 123,
+			sent:     456,
+		},
+		"missing_bytes_out": {
+			line:       "nclients=0,bytesin=123",
+			errWrapped: errLoadStatsNotValid,
+		},
+		"malformed_bytes_in": {
+			line:       "nclients=0,bytesin=x,bytesout=456",
+			errWrapped: errLoadStatsNotValid,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			received, sent, err := parseLoadStats(testCase.line)
+
+			assert.True(t, errors.Is(err, testCase.errWrapped))
+			assert.Equal(t, testCase.received, received)
+			assert.Equal(t, testCase.sent, sent)
+		})
+	}
+}
+
+func Test_parseCipher(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		logLines []string
+		cipher   string
+	}{
+		"no_cipher_line": {
+			logLines: []string{"1700000000,I,Initialization Sequence Completed"},
+		},
+		"openvpn_2.5": {
+			logLines: []string{"1700000000,,Data Channel: using negotiated cipher 'AES-256-GCM'"},
+			cipher:   "AES-256-GCM",
+		},
+		"openvpn_2.6_last_line_wins": {
+			logLines: []string{
+				"1700000000,,Data Channel: cipher 'AES-128-GCM', peer-id: 3",
+				"1700003600,,Data Channel: cipher 'CHACHA20-POLY1305', peer-id: 3",
+			},
+			cipher: "CHACHA20-POLY1305",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cipher := parseCipher(testCase.logLines)
+
+			assert.Equal(t, testCase.cipher, cipher)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package portforward
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/command"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+// GetPortsForwardedData returns the ports currently forwarded
+// and when they were last assigned.
+func (l *Loop) GetPortsForwardedData() (data models.PortsForwarded) {
+	l.portsMutex.RLock()
+	defer l.portsMutex.RUnlock()
+	return models.PortsForwarded{
+		Ports:      l.GetPortsForwarded(),
+		AssignedAt: l.assignedAt,
+	}
+}
+
+// WaitForPortsChange blocks until the forwarded ports are assigned
+// after the assignment time given, or until the context is canceled.
+// It returns immediately if the last assignment time differs from the
+// one given, so callers can pass the assignment time of their last call.
+func (l *Loop) WaitForPortsChange(ctx context.Context,
+	assignedAt time.Time) (data models.PortsForwarded, err error) {
+	l.portsMutex.RLock()
+	changed := l.portsChanged
+	upToDate := l.assignedAt.Equal(assignedAt)
+	l.portsMutex.RUnlock()
+
+	if upToDate {
+		select {
+		case <-ctx.Done():
+			return data, ctx.Err()
+		case <-changed:
+		}
+	}
+	return l.GetPortsForwardedData(), nil
+}
+
+// onPortsForwarded records the ports forwarded by the service just
+// started, notifies the callers waiting for a ports change and runs
+// the change command if the ports differ from the previous ports.
+func (l *Loop) onPortsForwarded(ctx context.Context, ports []uint16) {
+	if len(ports) == 0 {
+		return
+	}
+
+	l.portsMutex.Lock()
+	if slices.Equal(ports, l.lastPorts) {
+		l.portsMutex.Unlock()
+		return
+	}
+	l.lastPorts = ports
+	l.assignedAt = l.timeNow()
+	close(l.portsChanged)
+	l.portsChanged = make(chan struct{})
+	l.portsMutex.Unlock()
+
+	if l.changeCommand == "" {
+		return
+	}
+	go l.runChangeCommand(ctx, ports)
+}
+
+func (l *Loop) runChangeCommand(ctx context.Context, ports []uint16) {
+	words, err := command.Split(l.changeCommand)
+	if err != nil {
+		l.logger.Error("port change command: " + err.Error())
+		return
+	}
+	args := words[1:]
+	for _, port := range ports {
+		args = append(args, fmt.Sprint(port))
+	}
+
+	cmd := exec.CommandContext(ctx, words[0], args...) //nolint:gosec
+	l.logger.Info("running port change command: " + cmd.String())
+	output, err := l.cmder.Run(cmd)
+	if output != "" {
+		l.logger.Info(output)
+	}
+	if err != nil {
+		l.logger.Error("port change command failed: " + err.Error())
+	}
+}
@@ -3,6 +3,7 @@ package portforward
 import (
 	"context"
 	"net/netip"
+	"os/exec"
 )
 
 type Service interface {
@@ -23,6 +24,10 @@ type PortAllower interface {
 		destinationPort uint16) (err error)
 }
 
+type CmdRunner interface {
+	Run(cmd *exec.Cmd) (output string, err error)
+}
+
 type Logger interface {
 	Debug(s string)
 	Info(s string)
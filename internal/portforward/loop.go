@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/gluetun/internal/portforward/service"
 )
 
@@ -19,9 +22,17 @@ type Loop struct {
 	routing     Routing
 	client      *http.Client
 	portAllower PortAllower
+	cmder       CmdRunner
 	logger      Logger
+	timeNow     func() time.Time
 	// Fixed parameters
-	uid, gid int
+	uid, gid      int
+	changeCommand string
+	// Ports change state
+	portsMutex   sync.RWMutex
+	lastPorts    []uint16
+	assignedAt   time.Time
+	portsChanged chan struct{}
 	// Internal channels and locks
 	// runCtx is used to detect when the loop has exited
 	// when performing an update
@@ -33,7 +44,7 @@ type Loop struct {
 }
 
 func NewLoop(settings settings.PortForwarding, routing Routing,
-	client *http.Client, portAllower PortAllower,
+	client *http.Client, portAllower PortAllower, cmder CmdRunner,
 	logger Logger, uid, gid int) *Loop {
 	return &Loop{
 		settings: Settings{
@@ -44,12 +55,16 @@ func NewLoop(settings settings.PortForwarding, routing Routing,
 				ListeningPort: *settings.ListeningPort,
 			},
 		},
-		routing:     routing,
-		client:      client,
-		portAllower: portAllower,
-		logger:      logger,
-		uid:         uid,
-		gid:         gid,
+		routing:       routing,
+		client:        client,
+		portAllower:   portAllower,
+		cmder:         cmder,
+		logger:        logger,
+		timeNow:       time.Now,
+		uid:           uid,
+		gid:           gid,
+		changeCommand: *settings.ChangeCommand,
+		portsChanged:  make(chan struct{}),
 	}
 }
 
@@ -117,6 +132,9 @@ func (l *Loop) run(runCtx context.Context, runDone chan<- struct{},
 
 		var err error
 		serviceRunError, err = l.service.Start(runCtx)
+		if err == nil {
+			l.onPortsForwarded(runCtx, l.service.GetPortsForwarded())
+		}
 		if updateReceived {
 			// Signal to the Update call that the service has started
 			// and if it failed to start.
@@ -163,3 +181,20 @@ func (l *Loop) GetPortsForwarded() (ports []uint16) {
 func ptrTo[T any](value T) *T {
 	return &value
 }
+
+// GetStatus returns the stopped status if port forwarding is disabled,
+// the running status if ports are forwarded, and the starting status
+// otherwise, for example while the VPN tunnel is not up.
+func (l *Loop) GetStatus() (status models.LoopStatus) {
+	l.settingsMutex.RLock()
+	enabled := *l.settings.Service.Enabled
+	l.settingsMutex.RUnlock()
+	switch {
+	case !enabled:
+		return constants.Stopped
+	case len(l.GetPortsForwarded()) > 0:
+		return constants.Running
+	default:
+		return constants.Starting
+	}
+}
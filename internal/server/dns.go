@@ -3,8 +3,14 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/dns"
+	"github.com/qdm12/gluetun/internal/models"
 )
 
 func newDNSHandler(ctx context.Context, loop DNSLoop,
@@ -24,7 +30,8 @@ type dnsHandler struct {
 
 func (h *dnsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/dns")
-	switch r.RequestURI {
+	route, _, _ := strings.Cut(r.RequestURI, "?")
+	switch route {
 	case "/status": //nolint:goconst
 		switch r.Method {
 		case http.MethodGet:
@@ -34,15 +41,120 @@ func (h *dnsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		default:
 			errMethodNotSupported(w, r.Method)
 		}
+	case "/settings":
+		switch r.Method {
+		case http.MethodGet:
+			h.getSettings(w)
+		case http.MethodPut:
+			h.patchSettings(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/querylog":
+		switch r.Method {
+		case http.MethodGet:
+			h.getQueryLog(w)
+		case http.MethodPost:
+			h.setQueryLog(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/dnssec":
+		switch r.Method {
+		case http.MethodGet:
+			h.getDNSSEC(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/version":
+		switch r.Method {
+		case http.MethodGet:
+			h.getVersion(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/logs":
+		switch r.Method {
+		case http.MethodGet:
+			h.getLogs(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/resolver":
+		switch r.Method {
+		case http.MethodGet:
+			h.getResolver(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/records":
+		switch r.Method {
+		case http.MethodGet:
+			h.getRecords(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/resolve":
+		switch r.Method {
+		case http.MethodGet:
+			h.resolve(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/nextupdate":
+		switch r.Method {
+		case http.MethodGet:
+			h.getNextUpdate(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/blacklist/check":
+		switch r.Method {
+		case http.MethodGet:
+			h.checkBlocked(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/blacklist/rebuild":
+		switch r.Method {
+		case http.MethodPost:
+			h.rebuildBlockLists(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/blacklist/sources":
+		switch r.Method {
+		case http.MethodGet:
+			h.getBlockListSources(w)
+		case http.MethodPatch:
+			h.setBlockListCategories(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/blacklist/hostnames":
+		switch r.Method {
+		case http.MethodGet:
+			h.getBlockedHostnames(w)
+		case http.MethodPost:
+			h.addBlockedHostnames(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/cache/flush":
+		switch r.Method {
+		case http.MethodPost:
+			h.flushCache(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
 	default:
-		errRouteNotSupported(w, r.RequestURI)
+		errRouteNotSupported(w, route)
 	}
 }
 
 func (h *dnsHandler) getStatus(w http.ResponseWriter) {
-	status := h.loop.GetStatus()
+	data := h.loop.GetStatusDetail()
 	encoder := json.NewEncoder(w)
-	data := statusWrapper{Status: string(status)}
 	if err := encoder.Encode(data); err != nil {
 		h.warner.Warn(err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
@@ -57,16 +169,57 @@ func (h *dnsHandler) setStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	status, err := data.getStatus()
+	status, err := data.getDNSStatus()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	outcome, err := h.loop.ApplyUserStatus(h.ctx, status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
+		h.warner.Warn(err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getSettings(w http.ResponseWriter) {
+	settings := h.loop.GetSettings()
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(settings); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var overrideSettings settings.DNS
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&overrideSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	outcome, err := h.loop.ApplyStatus(h.ctx, status)
+
+	err = r.Body.Close()
+	if err != nil {
+		h.warner.Warn("closing body: " + err.Error())
+	}
+
+	currentSettings := h.loop.GetSettings()
+	updatedSettings := currentSettings.Copy()
+	updatedSettings.OverrideWith(overrideSettings)
+	outcome, err := h.loop.SetSettings(h.ctx, updatedSettings)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+
 	encoder := json.NewEncoder(w)
 	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
 		h.warner.Warn(err.Error())
@@ -74,3 +227,288 @@ func (h *dnsHandler) setStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+type queryLogWrapper struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (h *dnsHandler) getQueryLog(w http.ResponseWriter) {
+	enabled := h.loop.GetSettings().DoT.QueryLog
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(queryLogWrapper{Enabled: enabled}); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) setQueryLog(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data queryLogWrapper
+	if err := decoder.Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if data.Enabled == nil {
+		http.Error(w, `field "enabled" is missing`, http.StatusBadRequest)
+		return
+	}
+	outcome := h.loop.SetQueryLog(*data.Enabled)
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(outcomeWrapper{Outcome: outcome}); err != nil {
+		h.warner.Warn(err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+type dnssecWrapper struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (h *dnsHandler) getDNSSEC(w http.ResponseWriter) {
+	enabled := h.loop.GetSettings().DNSSEC
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(dnssecWrapper{Enabled: enabled}); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getRecords(w http.ResponseWriter) {
+	records := h.loop.GetSettings().Records
+	if records == nil {
+		records = []settings.DNSRecord{}
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(records); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+type blockedHostnamesWrapper struct {
+	Custom     []string `json:"custom"`
+	Downloaded []string `json:"downloaded"`
+}
+
+func (h *dnsHandler) getBlockedHostnames(w http.ResponseWriter) {
+	custom, downloaded := h.loop.GetBlockedHostnames()
+	encoder := json.NewEncoder(w)
+	data := blockedHostnamesWrapper{Custom: custom, Downloaded: downloaded}
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getVersion(w http.ResponseWriter) {
+	data := h.loop.GetVersion()
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getLogs(w http.ResponseWriter) {
+	data := h.loop.GetLogs()
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getResolver(w http.ResponseWriter) {
+	data := h.loop.GetResolver()
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getBlockListSources(w http.ResponseWriter) {
+	data := h.loop.GetBlockListSources()
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) getNextUpdate(w http.ResponseWriter) {
+	data := h.loop.GetNextUpdate()
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) rebuildBlockLists(w http.ResponseWriter, r *http.Request) {
+	const timeout = 2 * time.Minute
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	data, err := h.loop.RebuildBlockLists(ctx)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		http.Error(w, "rebuilding block lists: "+ctx.Err().Error(), http.StatusGatewayTimeout)
+		return
+	case errors.Is(err, dns.ErrDNSServerNotRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, dns.ErrTunnelNotUp):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) setBlockListCategories(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	var categories models.DNSBlockListCategories
+	if err := decoder.Decode(&categories); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	const timeout = 2 * time.Minute
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	data, err := h.loop.SetBlockListCategories(ctx, categories)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		http.Error(w, "rebuilding block lists: "+ctx.Err().Error(), http.StatusGatewayTimeout)
+		return
+	case errors.Is(err, dns.ErrTunnelNotUp):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) checkBlocked(w http.ResponseWriter, r *http.Request) {
+	hostname := r.URL.Query().Get("hostname")
+	if hostname == "" {
+		http.Error(w, `query parameter "hostname" is missing`, http.StatusBadRequest)
+		return
+	}
+	data := h.loop.CheckBlocked(r.Context(), hostname)
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) resolve(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("name")
+	if name == "" {
+		http.Error(w, `query parameter "name" is missing`, http.StatusBadRequest)
+		return
+	}
+	recordType := query.Get("type")
+	if recordType == "" {
+		recordType = "A"
+	}
+
+	data, err := h.loop.Resolve(r.Context(), name, recordType)
+	switch {
+	case errors.Is(err, dns.ErrResolveTypeNotValid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) addBlockedHostnames(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var hostnames []string
+	if err := decoder.Decode(&hostnames); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := h.loop.AddBlockedHostnames(hostnames)
+	switch {
+	case errors.Is(err, settings.ErrBlockedHostNotValid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		h.warner.Warn(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(outcomeWrapper{Outcome: "blocked hostnames updated"}); err != nil {
+		h.warner.Warn(err.Error())
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *dnsHandler) flushCache(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	data, err := h.loop.FlushCache(domain)
+	switch {
+	case errors.Is(err, dns.ErrFlushDomainNotValid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, dns.ErrCacheDisabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
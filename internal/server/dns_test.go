@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/dns"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string)          {}
+func (noopLogger) Debugf(string, ...any) {}
+func (noopLogger) Info(string)           {}
+func (noopLogger) Warn(string)           {}
+func (noopLogger) Warnf(string, ...any)  {}
+func (noopLogger) Error(string)          {}
+
+// newTestDNSHandler returns a DNS handler using a DNS loop
+// with the default settings, which is not started.
+func newTestDNSHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	var allSettings settings.Settings
+	allSettings.SetDefaults()
+	loop, err := dns.NewLoop(allSettings.DNS, http.DefaultClient, nil,
+		false, false, noopLogger{}, false, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	return newDNSHandler(context.Background(), loop, noopLogger{})
+}
+
+func Test_dnsHandler_settings(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		body       string
+		statusCode int
+		response   string
+	}{
+		"empty_providers": {
+			body:       `{"DoT":{"providers":[]}}`,
+			statusCode: http.StatusBadRequest,
+			response: "validating settings: validating DoT settings: " +
+				"no DNS over TLS provider is set\n",
+		},
+		"unknown_provider": {
+			body:       `{"DoT":{"providers":["unknown"]}}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := newTestDNSHandler(t)
+			request := httptest.NewRequest(http.MethodPut, "/dns/settings",
+				strings.NewReader(testCase.body))
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.statusCode, recorder.Code)
+			if testCase.response != "" {
+				assert.Equal(t, testCase.response, recorder.Body.String())
+			}
+		})
+	}
+}
+
+// dnsLoopStub is a DNS loop returning the block list sources or
+// cache flush set, or the error set, and recording the domain
+// given to flush.
+type dnsLoopStub struct {
+	DNSLoop
+	sources models.DNSBlockListSources
+	flush   models.DNSCacheFlush
+	err     error
+	domain  string
+}
+
+func (s *dnsLoopStub) RebuildBlockLists(context.Context) (
+	sources models.DNSBlockListSources, err error) {
+	return s.sources, s.err
+}
+
+func (s *dnsLoopStub) FlushCache(domain string) (
+	flush models.DNSCacheFlush, err error) {
+	s.domain = domain
+	return s.flush, s.err
+}
+
+func Test_dnsHandler_rebuildBlockLists(t *testing.T) {
+	t.Parallel()
+
+	errDummy := errors.New("dummy")
+
+	testCases := map[string]struct {
+		loop       *dnsLoopStub
+		statusCode int
+		response   string
+	}{
+		"success": {
+			loop:       &dnsLoopStub{},
+			statusCode: http.StatusOK,
+			response:   `{"sources":null,"last_update":"0001-01-01T00:00:00Z"}` + "\n",
+		},
+		"not_running": {
+			loop: &dnsLoopStub{
+				err: fmt.Errorf("%w: status is stopped", dns.ErrDNSServerNotRunning),
+			},
+			statusCode: http.StatusConflict,
+			response:   "DNS server is not running: status is stopped\n",
+		},
+		"tunnel_not_up": {
+			loop: &dnsLoopStub{
+				err: fmt.Errorf("rebuilding block lists: skipping block lists build: %w",
+					dns.ErrTunnelNotUp),
+			},
+			statusCode: http.StatusServiceUnavailable,
+			response: "rebuilding block lists: skipping block lists build: " +
+				"VPN tunnel is not up\n",
+		},
+		"failure": {
+			loop:       &dnsLoopStub{err: errDummy},
+			statusCode: http.StatusInternalServerError,
+			response:   "dummy\n",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := newDNSHandler(context.Background(), testCase.loop, noopLogger{})
+			request := httptest.NewRequest(http.MethodPost, "/dns/blacklist/rebuild", nil)
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.statusCode, recorder.Code)
+			assert.Equal(t, testCase.response, recorder.Body.String())
+		})
+	}
+}
+
+func Test_dnsHandler_flushCache(t *testing.T) {
+	t.Parallel()
+
+	errDummy := errors.New("dummy")
+
+	testCases := map[string]struct {
+		target     string
+		loop       *dnsLoopStub
+		domain     string
+		statusCode int
+		response   string
+	}{
+		"domain": {
+			target: "/dns/cache/flush?domain=example.com",
+			loop: &dnsLoopStub{
+				flush: models.DNSCacheFlush{Domain: "example.com", Flushed: 2},
+			},
+			domain:     "example.com",
+			statusCode: http.StatusOK,
+			response:   `{"domain":"example.com","flushed":2}` + "\n",
+		},
+		"all": {
+			target:     "/dns/cache/flush",
+			loop:       &dnsLoopStub{flush: models.DNSCacheFlush{Flushed: 3}},
+			statusCode: http.StatusOK,
+			response:   `{"flushed":3}` + "\n",
+		},
+		"domain_not_valid": {
+			target: "/dns/cache/flush?domain=example..com",
+			loop: &dnsLoopStub{
+				err: fmt.Errorf("%w: example..com", dns.ErrFlushDomainNotValid),
+			},
+			domain:     "example..com",
+			statusCode: http.StatusBadRequest,
+			response:   "domain to flush is not valid: example..com\n",
+		},
+		"cache_disabled": {
+			target:     "/dns/cache/flush",
+			loop:       &dnsLoopStub{err: dns.ErrCacheDisabled},
+			statusCode: http.StatusConflict,
+			response:   "DNS cache is disabled\n",
+		},
+		"failure": {
+			target:     "/dns/cache/flush",
+			loop:       &dnsLoopStub{err: errDummy},
+			statusCode: http.StatusInternalServerError,
+			response:   "dummy\n",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := newDNSHandler(context.Background(), testCase.loop, noopLogger{})
+			request := httptest.NewRequest(http.MethodPost, testCase.target, nil)
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.domain, testCase.loop.domain)
+			assert.Equal(t, testCase.statusCode, recorder.Code)
+			assert.Equal(t, testCase.response, recorder.Body.String())
+		})
+	}
+}
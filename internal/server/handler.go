@@ -6,13 +6,17 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/qdm12/gluetun/internal/models"
 	"github.com/qdm12/gluetun/internal/server/middlewares/auth"
 	"github.com/qdm12/gluetun/internal/server/middlewares/log"
+	"github.com/qdm12/gluetun/internal/server/middlewares/ratelimit"
 )
 
 func newHandler(ctx context.Context, logger Logger, logging bool,
 	authSettings auth.Settings,
+	apiKey string,
+	rateLimit uint,
 	buildInfo models.BuildInformation,
 	vpnLooper VPNLooper,
 	pfGetter PortForwardedGetter,
@@ -24,15 +28,24 @@ func newHandler(ctx context.Context, logger Logger, logging bool,
 ) (httpHandler http.Handler, err error) {
 	handler := &handler{}
 
-	vpn := newVPNHandler(ctx, vpnLooper, storage, ipv6Supported, logger)
+	vpn := newVPNHandler(ctx, vpnLooper, pfGetter, storage, ipv6Supported, logger)
 	openvpn := newOpenvpnHandler(ctx, vpnLooper, pfGetter, logger)
 	dns := newDNSHandler(ctx, dnsLooper, logger)
 	updater := newUpdaterHandler(ctx, updaterLooper, logger)
 	publicip := newPublicIPHandler(publicIPLooper, logger)
+	health := newHealthHandler(vpnLooper, dnsLooper, pfGetter, logger)
+	metrics := promhttp.Handler()
 
 	handler.v0 = newHandlerV0(ctx, logger, vpnLooper, dnsLooper, updaterLooper)
-	handler.v1 = newHandlerV1(logger, buildInfo, vpn, openvpn, dns, updater, publicip)
+	handler.v1 = newHandlerV1(logger, buildInfo, vpn, openvpn, dns, updater, publicip, health, metrics)
 
+	if apiKey != "" {
+		// The API key role gives access to all the routes, and
+		// is checked first so requests with the API key are not
+		// authorized through a role without authentication.
+		authSettings.Roles = append([]auth.Role{auth.NewAPIKeyRole(apiKey)},
+			authSettings.Roles...)
+	}
 	authMiddleware, err := auth.New(authSettings, logger)
 	if err != nil {
 		return nil, fmt.Errorf("creating auth middleware: %w", err)
@@ -40,8 +53,17 @@ func newHandler(ctx context.Context, logger Logger, logging bool,
 
 	middlewares := []func(http.Handler) http.Handler{
 		authMiddleware,
-		log.New(logger, logging),
 	}
+	if apiKey != "" {
+		// The API key middleware wraps the auth middleware, so
+		// the routes of roles without authentication require
+		// the API key as well.
+		middlewares = append(middlewares, auth.NewAPIKeyMiddleware(apiKey, logger))
+	}
+	if rateLimit > 0 {
+		middlewares = append(middlewares, newRateLimitMiddleware(rateLimit))
+	}
+	middlewares = append(middlewares, log.New(logger, logging))
 	httpHandler = handler
 	for _, middleware := range middlewares {
 		httpHandler = middleware(httpHandler)
@@ -63,3 +85,20 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/v1")
 	h.v1.ServeHTTP(w, r)
 }
+
+// newRateLimitMiddleware returns a rate limiting middleware allowing the
+// number of requests per minute given for each client IP address and route.
+// Block lists rebuilds are limited further since each rebuild downloads
+// all the block lists again.
+func newRateLimitMiddleware(requestsPerMinute uint) (
+	middleware func(http.Handler) http.Handler) {
+	const secondsPerMinute = 60
+	limit := ratelimit.Limit{
+		Rate:  float64(requestsPerMinute) / secondsPerMinute,
+		Burst: float64(requestsPerMinute),
+	}
+	routeLimits := map[string]ratelimit.Limit{
+		http.MethodPost + " /v1/dns/blacklist/rebuild": {Rate: 1.0 / secondsPerMinute, Burst: 1},
+	}
+	return ratelimit.New(limit, routeLimits)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/server/middlewares/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newHandler_apiKey(t *testing.T) {
+	t.Parallel()
+
+	authSettings := auth.Settings{
+		Roles: []auth.Role{{
+			Name:   "open",
+			Auth:   auth.AuthNone,
+			Routes: []string{http.MethodGet + " /v1/version"},
+		}},
+	}
+
+	testCases := map[string]struct {
+		apiKey     string
+		target     string
+		header     http.Header
+		statusCode int
+	}{
+		"none_role_without_api_key_set": {
+			target:     "/v1/version",
+			statusCode: http.StatusOK,
+		},
+		"none_role_without_api_key": {
+			apiKey:     "key",
+			target:     "/v1/version",
+			statusCode: http.StatusUnauthorized,
+		},
+		"none_role_with_wrong_api_key": {
+			apiKey:     "key",
+			target:     "/v1/version",
+			header:     http.Header{"X-Api-Key": []string{"wrong"}},
+			statusCode: http.StatusUnauthorized,
+		},
+		"none_role_with_api_key": {
+			apiKey:     "key",
+			target:     "/v1/version",
+			header:     http.Header{"X-Api-Key": []string{"key"}},
+			statusCode: http.StatusOK,
+		},
+		"route_of_no_role_with_api_key": {
+			apiKey:     "key",
+			target:     "/v1/publicip/ip",
+			header:     http.Header{"Authorization": []string{"Bearer key"}},
+			statusCode: http.StatusOK,
+		},
+		"route_of_no_role_without_api_key": {
+			apiKey:     "key",
+			target:     "/v1/publicip/ip",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := newHandler(context.Background(), noopLogger{}, false,
+				authSettings, testCase.apiKey, 0, models.BuildInformation{},
+				nil, nil, nil, nil, &publicIPLoopStub{}, nil, false)
+			require.NoError(t, err)
+			request := httptest.NewRequest(http.MethodGet, testCase.target, nil)
+			for key, values := range testCase.header {
+				request.Header[key] = values
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, testCase.statusCode, recorder.Code)
+		})
+	}
+}
+
+type publicIPLoopStub struct{}
+
+func (publicIPLoopStub) GetData() (data models.PublicIP) { return data }
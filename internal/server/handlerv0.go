@@ -43,9 +43,9 @@ func (h *handlerV0) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.logger.Warn(err.Error())
 		}
 	case "/unbound/actions/restart": // TODO v4 change to /dns/
-		outcome, _ := h.dns.ApplyStatus(h.ctx, constants.Stopped)
+		outcome, _ := h.dns.ApplyUserStatus(h.ctx, constants.Stopped)
 		h.logger.Info("dns: " + outcome)
-		outcome, _ = h.dns.ApplyStatus(h.ctx, constants.Running)
+		outcome, _ = h.dns.ApplyUserStatus(h.ctx, constants.Running)
 		h.logger.Info("dns: " + outcome)
 		if _, err := w.Write([]byte("dns restarted, please consider using the /v1/ API in the future.")); err != nil {
 			h.logger.Warn(err.Error())
@@ -10,7 +10,7 @@ import (
 )
 
 func newHandlerV1(w warner, buildInfo models.BuildInformation,
-	vpn, openvpn, dns, updater, publicip http.Handler) http.Handler {
+	vpn, openvpn, dns, updater, publicip, health, metrics http.Handler) http.Handler {
 	return &handlerV1{
 		warner:    w,
 		buildInfo: buildInfo,
@@ -19,6 +19,8 @@ func newHandlerV1(w warner, buildInfo models.BuildInformation,
 		dns:       dns,
 		updater:   updater,
 		publicip:  publicip,
+		health:    health,
+		metrics:   metrics,
 	}
 }
 
@@ -30,12 +32,18 @@ type handlerV1 struct {
 	dns       http.Handler
 	updater   http.Handler
 	publicip  http.Handler
+	health    http.Handler
+	metrics   http.Handler
 }
 
 func (h *handlerV1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.RequestURI == "/version" && r.Method == http.MethodGet:
 		h.getVersion(w)
+	case r.RequestURI == "/metrics" && r.Method == http.MethodGet:
+		h.metrics.ServeHTTP(w, r)
+	case r.RequestURI == "/health":
+		h.health.ServeHTTP(w, r)
 	case strings.HasPrefix(r.RequestURI, "/vpn"):
 		h.vpn.ServeHTTP(w, r)
 	case strings.HasPrefix(r.RequestURI, "/openvpn"):
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/models"
+)
+
+func newHealthHandler(vpnLooper VPNLooper, dnsLooper DNSLoop,
+	pfGetter PortForwardedGetter, w warner) http.Handler {
+	return &healthHandler{
+		vpn:    vpnLooper,
+		dns:    dnsLooper,
+		pf:     pfGetter,
+		warner: w,
+	}
+}
+
+type healthHandler struct {
+	vpn    VPNLooper
+	dns    DNSLoop
+	pf     PortForwardedGetter
+	warner warner
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getHealth(w)
+	default:
+		errMethodNotSupported(w, r.Method)
+	}
+}
+
+// getHealth responds with the status of the VPN, DNS and port
+// forwarding subsystems, with the 200 status code only if all
+// the required subsystems are running, and 503 otherwise.
+// A paused DNS server is considered running since it still
+// resolves hostnames.
+func (h *healthHandler) getHealth(w http.ResponseWriter) {
+	dnsSettings := h.dns.GetSettings()
+	pfStatus := h.pf.GetStatus()
+	health := models.Health{
+		Healthy: true,
+		Subsystems: map[string]models.SubsystemHealth{
+			"vpn": {
+				Status:   h.vpn.GetStatus(),
+				Required: true,
+			},
+			"dns": {
+				Status:   h.dns.GetStatus(),
+				Required: *dnsSettings.DoT.Enabled && !*dnsSettings.KeepNameserver,
+			},
+			"port_forwarding": {
+				Status:   pfStatus,
+				Required: pfStatus != constants.Stopped,
+			},
+		},
+	}
+	for _, subsystem := range health.Subsystems {
+		running := subsystem.Status == constants.Running ||
+			subsystem.Status == constants.Paused
+		if subsystem.Required && !running {
+			health.Healthy = false
+			break
+		}
+	}
+
+	if !health.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(health); err != nil {
+		h.warner.Warn(err.Error())
+		return
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
 	"github.com/qdm12/gluetun/internal/models"
@@ -13,16 +14,43 @@ type VPNLooper interface {
 		outcome string, err error)
 	GetSettings() (settings settings.VPN)
 	SetSettings(ctx context.Context, settings settings.VPN) (outcome string)
+	GetWireguardStats() (stats models.WireguardStats, err error)
+	GetOpenVPNStats(ctx context.Context) (stats models.OpenVPNStats, err error)
 }
 
 type DNSLoop interface {
 	ApplyStatus(ctx context.Context, status models.LoopStatus) (
 		outcome string, err error)
+	ApplyUserStatus(ctx context.Context, status models.LoopStatus) (
+		outcome string, err error)
 	GetStatus() (status models.LoopStatus)
+	GetStatusDetail() (detail models.DNSStatus)
+	GetSettings() (settings settings.DNS)
+	SetSettings(ctx context.Context, settings settings.DNS) (
+		outcome string, err error)
+	GetBlockedHostnames() (custom, downloaded []string)
+	GetBlockListSources() (sources models.DNSBlockListSources)
+	GetNextUpdate() (next models.DNSNextUpdate)
+	RebuildBlockLists(ctx context.Context) (sources models.DNSBlockListSources, err error)
+	SetBlockListCategories(ctx context.Context, categories models.DNSBlockListCategories) (
+		sources models.DNSBlockListSources, err error)
+	CheckBlocked(ctx context.Context, hostname string) (check models.DNSBlockCheck)
+	Resolve(ctx context.Context, name, recordType string) (
+		resolution models.DNSResolution, err error)
+	AddBlockedHostnames(hostnames []string) (err error)
+	FlushCache(domain string) (flush models.DNSCacheFlush, err error)
+	SetQueryLog(enabled bool) (outcome string)
+	GetVersion() (version models.DNSVersion)
+	GetResolver() (resolver models.DNSResolver)
+	GetLogs() (logs models.DNSLogs)
 }
 
 type PortForwardedGetter interface {
+	GetStatus() (status models.LoopStatus)
 	GetPortsForwarded() (ports []uint16)
+	GetPortsForwardedData() (data models.PortsForwarded)
+	WaitForPortsChange(ctx context.Context, assignedAt time.Time) (
+		data models.PortsForwarded, err error)
 }
 
 type PublicIPLoop interface {
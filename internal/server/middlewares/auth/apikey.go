@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"net/http"
+	"strings"
 )
 
 type apiKeyMethod struct {
@@ -28,9 +29,31 @@ func (a *apiKeyMethod) equal(other authorizationChecker) bool {
 
 func (a *apiKeyMethod) isAuthorized(_ http.Header, request *http.Request) bool {
 	xAPIKey := request.Header.Get("X-API-Key")
+	if xAPIKey == "" {
+		xAPIKey, _ = strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	}
 	if xAPIKey == "" {
 		xAPIKey = request.URL.Query().Get("api_key")
 	}
 	xAPIKeyDigest := sha256.Sum256([]byte(xAPIKey))
 	return subtle.ConstantTimeCompare(xAPIKeyDigest[:], a.apiKeyDigest[:]) == 1
 }
+
+// NewAPIKeyMiddleware returns a middleware refusing the requests
+// without the API key given, before the roles are checked, so the
+// routes of roles without authentication require the API key too.
+func NewAPIKeyMiddleware(apiKey string, debugLogger DebugLogger) (
+	middleware func(http.Handler) http.Handler) {
+	method := newAPIKeyMethod(apiKey)
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if !method.isAuthorized(nil, request) {
+				debugLogger.Debugf("access to route %s %s unauthorized without the API key",
+					request.Method, request.URL.Path)
+				http.Error(writer, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			handler.ServeHTTP(writer, request)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_apiKeyMethod_isAuthorized(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header     http.Header
+		target     string
+		authorized bool
+	}{
+		"no_key": {
+			target: "/",
+		},
+		"x_api_key_header": {
+			header:     http.Header{"X-Api-Key": []string{"key"}},
+			target:     "/",
+			authorized: true,
+		},
+		"bearer_token": {
+			header:     http.Header{"Authorization": []string{"Bearer key"}},
+			target:     "/",
+			authorized: true,
+		},
+		"basic_authorization": {
+			header: http.Header{"Authorization": []string{"Basic key"}},
+			target: "/",
+		},
+		"query_parameter": {
+			target:     "/?api_key=key",
+			authorized: true,
+		},
+		"wrong_key": {
+			header: http.Header{"X-Api-Key": []string{"wrong"}},
+			target: "/",
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			method := newAPIKeyMethod("key")
+			request := httptest.NewRequest(http.MethodGet, testCase.target, nil)
+			for key, values := range testCase.header {
+				request.Header[key] = values
+			}
+
+			authorized := method.isAuthorized(nil, request)
+
+			assert.Equal(t, testCase.authorized, authorized)
+		})
+	}
+}
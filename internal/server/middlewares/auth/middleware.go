@@ -23,6 +23,7 @@ func New(settings Settings, debugLogger DebugLogger) (
 				http.MethodGet + " /unbound/actions/restart": {},
 				http.MethodGet + " /updater/restart":         {},
 				http.MethodGet + " /v1/version":              {},
+				http.MethodGet + " /v1/health":               {},
 				http.MethodGet + " /v1/vpn/status":           {},
 				http.MethodPut + " /v1/vpn/status":           {},
 				// GET /v1/vpn/settings is protected by default
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/qdm12/gosettings"
 	"github.com/qdm12/gosettings/validate"
@@ -24,6 +25,7 @@ func (s *Settings) SetDefaults() {
 			http.MethodGet + " /unbound/actions/restart",
 			http.MethodGet + " /updater/restart",
 			http.MethodGet + " /v1/version",
+			http.MethodGet + " /v1/health",
 			http.MethodGet + " /v1/vpn/status",
 			http.MethodPut + " /v1/vpn/status",
 			http.MethodGet + " /v1/openvpn/status",
@@ -111,21 +113,60 @@ func (r Role) validate() (err error) {
 
 // WARNING: do not mutate programmatically.
 var validRoutes = map[string]struct{}{ //nolint:gochecknoglobals
-	http.MethodGet + " /openvpn/actions/restart":  {},
-	http.MethodGet + " /unbound/actions/restart":  {},
-	http.MethodGet + " /updater/restart":          {},
-	http.MethodGet + " /v1/version":               {},
-	http.MethodGet + " /v1/vpn/status":            {},
-	http.MethodPut + " /v1/vpn/status":            {},
-	http.MethodGet + " /v1/vpn/settings":          {},
-	http.MethodPut + " /v1/vpn/settings":          {},
-	http.MethodGet + " /v1/openvpn/status":        {},
-	http.MethodPut + " /v1/openvpn/status":        {},
-	http.MethodGet + " /v1/openvpn/portforwarded": {},
-	http.MethodGet + " /v1/openvpn/settings":      {},
-	http.MethodGet + " /v1/dns/status":            {},
-	http.MethodPut + " /v1/dns/status":            {},
-	http.MethodGet + " /v1/updater/status":        {},
-	http.MethodPut + " /v1/updater/status":        {},
-	http.MethodGet + " /v1/publicip/ip":           {},
+	http.MethodGet + " /openvpn/actions/restart":     {},
+	http.MethodGet + " /unbound/actions/restart":     {},
+	http.MethodGet + " /updater/restart":             {},
+	http.MethodGet + " /v1/version":                  {},
+	http.MethodGet + " /v1/health":                   {},
+	http.MethodGet + " /v1/vpn/status":               {},
+	http.MethodPut + " /v1/vpn/status":               {},
+	http.MethodGet + " /v1/vpn/settings":             {},
+	http.MethodPut + " /v1/vpn/settings":             {},
+	http.MethodGet + " /v1/vpn/portforwarded":        {},
+	http.MethodGet + " /v1/vpn/wireguard/stats":      {},
+	http.MethodGet + " /v1/vpn/openvpn/stats":        {},
+	http.MethodGet + " /v1/openvpn/status":           {},
+	http.MethodPut + " /v1/openvpn/status":           {},
+	http.MethodGet + " /v1/openvpn/portforwarded":    {},
+	http.MethodGet + " /v1/openvpn/settings":         {},
+	http.MethodGet + " /v1/dns/status":               {},
+	http.MethodPut + " /v1/dns/status":               {},
+	http.MethodGet + " /v1/dns/settings":             {},
+	http.MethodPut + " /v1/dns/settings":             {},
+	http.MethodGet + " /v1/dns/querylog":             {},
+	http.MethodPost + " /v1/dns/querylog":            {},
+	http.MethodGet + " /v1/dns/dnssec":               {},
+	http.MethodGet + " /v1/dns/version":              {},
+	http.MethodGet + " /v1/dns/logs":                 {},
+	http.MethodGet + " /v1/dns/resolver":             {},
+	http.MethodGet + " /v1/dns/records":              {},
+	http.MethodGet + " /v1/dns/blacklist/check":      {},
+	http.MethodGet + " /v1/dns/resolve":              {},
+	http.MethodGet + " /v1/dns/nextupdate":           {},
+	http.MethodPost + " /v1/dns/blacklist/rebuild":   {},
+	http.MethodGet + " /v1/dns/blacklist/sources":    {},
+	http.MethodPatch + " /v1/dns/blacklist/sources":  {},
+	http.MethodGet + " /v1/dns/blacklist/hostnames":  {},
+	http.MethodPost + " /v1/dns/blacklist/hostnames": {},
+	http.MethodPost + " /v1/dns/cache/flush":         {},
+	http.MethodGet + " /v1/updater/status":           {},
+	http.MethodPut + " /v1/updater/status":           {},
+	http.MethodGet + " /v1/publicip/ip":              {},
+	http.MethodGet + " /v1/metrics":                  {},
+}
+
+// NewAPIKeyRole returns a role using the API key given
+// to authenticate requests to all the routes.
+func NewAPIKeyRole(apiKey string) (role Role) {
+	role = Role{
+		Name:   "api key",
+		Auth:   AuthAPIKey,
+		APIKey: apiKey,
+		Routes: make([]string, 0, len(validRoutes)),
+	}
+	for route := range validRoutes {
+		role.Routes = append(role.Routes, route)
+	}
+	sort.Strings(role.Routes)
+	return role
 }
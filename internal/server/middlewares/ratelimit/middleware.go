@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket limit, allowing Burst requests
+// at once and refilling at Rate requests per second.
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// New returns a middleware limiting requests per client IP address
+// and per route, using the limit given for each route except for
+// the routes in routeLimits, in the format "METHOD /path".
+func New(limit Limit, routeLimits map[string]Limit) (
+	middleware func(http.Handler) http.Handler) {
+	return func(handler http.Handler) http.Handler {
+		return &rateLimiter{
+			childHandler: handler,
+			limit:        limit,
+			routeLimits:  routeLimits,
+			timeNow:      time.Now,
+			buckets:      make(map[bucketKey]*bucket),
+		}
+	}
+}
+
+type rateLimiter struct {
+	childHandler http.Handler
+	limit        Limit
+	routeLimits  map[string]Limit
+	timeNow      func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	clientIP string
+	route    string
+}
+
+type bucket struct {
+	limit    Limit
+	tokens   float64
+	lastTime time.Time
+}
+
+func (r *rateLimiter) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	route := request.Method + " " + request.URL.Path
+	clientIP, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		clientIP = request.RemoteAddr
+	}
+
+	retryAfter := r.take(bucketKey{clientIP: clientIP, route: route})
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	r.childHandler.ServeHTTP(w, request)
+}
+
+// maxBuckets is the number of buckets above which
+// full buckets are removed from memory.
+const maxBuckets = 1000
+
+// take takes a token from the bucket for the key given, and returns
+// zero if a token was available, or the duration to wait for a token
+// to be available otherwise.
+func (r *rateLimiter) take(key bucketKey) (retryAfter time.Duration) {
+	now := r.timeNow()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	b, ok := r.buckets[key]
+	if !ok {
+		if len(r.buckets) >= maxBuckets {
+			r.removeFullBuckets(now)
+		}
+		limit, ok := r.routeLimits[key.route]
+		if !ok {
+			limit = r.limit
+		}
+		b = &bucket{limit: limit, tokens: limit.Burst, lastTime: now}
+		r.buckets[key] = b
+	}
+
+	b.refill(now)
+	if b.tokens < 1 {
+		missing := 1 - b.tokens
+		return time.Duration(missing / b.limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+func (r *rateLimiter) removeFullBuckets(now time.Time) {
+	for key, b := range r.buckets {
+		b.refill(now)
+		if b.tokens >= b.limit.Burst {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.lastTime)
+	b.lastTime = now
+	b.tokens = math.Min(b.limit.Burst, b.tokens+elapsed.Seconds()*b.limit.Rate)
+}
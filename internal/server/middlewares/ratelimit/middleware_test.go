@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rateLimiter_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	childHandler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	limit := Limit{Rate: 1, Burst: 2}
+	routeLimits := map[string]Limit{
+		http.MethodPost + " /rebuild": {Rate: 1.0 / 60, Burst: 1},
+	}
+	handler := New(limit, routeLimits)(childHandler)
+	limiter, ok := handler.(*rateLimiter)
+	require.True(t, ok)
+	now := time.Unix(0, 0)
+	limiter.timeNow = func() time.Time { return now }
+
+	serve := func(method, path, remoteAddr string) (response *http.Response) {
+		request := httptest.NewRequest(method, path, nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Result()
+	}
+
+	const client1, client2 = "1.2.3.4:1000", "5.6.7.8:1000"
+
+	// Burst for the first client
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/a", client1).StatusCode)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/a", client1).StatusCode)
+	response := serve(http.MethodGet, "/a", client1)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, "1", response.Header.Get("Retry-After"))
+
+	// Other route and other client are not limited
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/b", client1).StatusCode)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/a", client2).StatusCode)
+
+	// Route with its own limit
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/rebuild", client1).StatusCode)
+	response = serve(http.MethodPost, "/rebuild", client1)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, "60", response.Header.Get("Retry-After"))
+
+	// Refill
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/a", client1).StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/a", client1).StatusCode)
+}
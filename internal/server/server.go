@@ -10,7 +10,7 @@ import (
 )
 
 func New(ctx context.Context, address string, logEnabled bool, logger Logger,
-	authConfigPath string, buildInfo models.BuildInformation, openvpnLooper VPNLooper,
+	authConfigPath, apiKey string, rateLimit uint, buildInfo models.BuildInformation, openvpnLooper VPNLooper,
 	pfGetter PortForwardedGetter, dnsLooper DNSLoop,
 	updaterLooper UpdaterLooper, publicIPLooper PublicIPLoop, storage Storage,
 	ipv6Supported bool) (
@@ -25,7 +25,7 @@ func New(ctx context.Context, address string, logEnabled bool, logger Logger,
 		return nil, fmt.Errorf("validating auth settings: %w", err)
 	}
 
-	handler, err := newHandler(ctx, logger, logEnabled, authSettings, buildInfo,
+	handler, err := newHandler(ctx, logger, logEnabled, authSettings, apiKey, rateLimit, buildInfo,
 		openvpnLooper, pfGetter, dnsLooper, updaterLooper, publicIPLooper,
 		storage, ipv6Supported)
 	if err != nil {
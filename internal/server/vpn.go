@@ -3,17 +3,23 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/qdm12/gluetun/internal/configuration/settings"
+	"github.com/qdm12/gluetun/internal/openvpn"
+	"github.com/qdm12/gluetun/internal/vpn"
 )
 
 func newVPNHandler(ctx context.Context, looper VPNLooper,
-	storage Storage, ipv6Supported bool, w warner) http.Handler {
+	pfGetter PortForwardedGetter, storage Storage,
+	ipv6Supported bool, w warner) http.Handler {
 	return &vpnHandler{
 		ctx:           ctx,
 		looper:        looper,
+		pf:            pfGetter,
 		storage:       storage,
 		ipv6Supported: ipv6Supported,
 		warner:        w,
@@ -23,6 +29,7 @@ func newVPNHandler(ctx context.Context, looper VPNLooper,
 type vpnHandler struct {
 	ctx           context.Context //nolint:containedctx
 	looper        VPNLooper
+	pf            PortForwardedGetter
 	storage       Storage
 	ipv6Supported bool
 	warner        warner
@@ -30,7 +37,8 @@ type vpnHandler struct {
 
 func (h *vpnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.RequestURI = strings.TrimPrefix(r.RequestURI, "/vpn")
-	switch r.RequestURI {
+	route, _, _ := strings.Cut(r.RequestURI, "?")
+	switch route {
 	case "/status":
 		switch r.Method {
 		case http.MethodGet:
@@ -49,6 +57,27 @@ func (h *vpnHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		default:
 			errMethodNotSupported(w, r.Method)
 		}
+	case "/portforwarded":
+		switch r.Method {
+		case http.MethodGet:
+			h.getPortsForwarded(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/openvpn/stats":
+		switch r.Method {
+		case http.MethodGet:
+			h.getOpenVPNStats(w, r)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
+	case "/wireguard/stats":
+		switch r.Method {
+		case http.MethodGet:
+			h.getWireguardStats(w)
+		default:
+			errMethodNotSupported(w, r.Method)
+		}
 	default:
 		errRouteNotSupported(w, r.RequestURI)
 	}
@@ -128,3 +157,75 @@ func (h *vpnHandler) patchSettings(w http.ResponseWriter, r *http.Request) {
 		h.warner.Warn("writing response: " + err.Error())
 	}
 }
+
+func (h *vpnHandler) getWireguardStats(w http.ResponseWriter) {
+	stats, err := h.looper.GetWireguardStats()
+	switch {
+	case errors.Is(err, vpn.ErrWireguardNotUsed), errors.Is(err, vpn.ErrVPNNotRunning):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		h.warner.Warn(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(stats); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// getPortsForwarded responds with the ports forwarded and when they were
+// last assigned. If the query parameter "changed_since" is set to the
+// assignment time of a previous response, it waits for the ports to be
+// assigned again before responding, so clients can subscribe to changes.
+func (h *vpnHandler) getPortsForwarded(w http.ResponseWriter, r *http.Request) {
+	data := h.pf.GetPortsForwardedData()
+	changedSince := r.URL.Query().Get("changed_since")
+	if changedSince != "" {
+		assignedAt, err := time.Parse(time.RFC3339Nano, changedSince)
+		if err != nil {
+			http.Error(w, "query parameter \"changed_since\" is not valid: "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		data, err = h.pf.WaitForPortsChange(r.Context(), assignedAt)
+		if err != nil {
+			return // client went away
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(data); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *vpnHandler) getOpenVPNStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.looper.GetOpenVPNStats(r.Context())
+	switch {
+	case errors.Is(err, vpn.ErrOpenVPNNotUsed), errors.Is(err, vpn.ErrVPNNotRunning):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, openvpn.ErrManagementUnavailable):
+		// OpenVPN may be restarting, so the client can retry later.
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		h.warner.Warn(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(stats); err != nil {
+		h.warner.Warn(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
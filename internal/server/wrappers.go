@@ -25,6 +25,19 @@ func (sw *statusWrapper) getStatus() (status models.LoopStatus, err error) {
 	}
 }
 
+// getDNSStatus returns the status like getStatus, but also
+// allows the paused status only supported by the DNS loop.
+func (sw *statusWrapper) getDNSStatus() (status models.LoopStatus, err error) {
+	status = models.LoopStatus(sw.Status)
+	switch status {
+	case constants.Stopped, constants.Running, constants.Paused:
+		return status, nil
+	default:
+		return "", fmt.Errorf("%w: %s: possible values are: %s, %s, %s",
+			errInvalidStatus, sw.Status, constants.Stopped, constants.Running, constants.Paused)
+	}
+}
+
 type portWrapper struct { // TODO v4 remove
 	Port uint16 `json:"port"`
 }
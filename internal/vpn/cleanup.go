@@ -6,6 +6,8 @@ import (
 )
 
 func (l *Loop) cleanup() {
+	l.dnsLooper.SetTunnelDown()
+
 	for _, vpnPort := range l.vpnInputPorts {
 		err := l.fw.RemoveAllowedPort(context.Background(), vpnPort)
 		if err != nil {
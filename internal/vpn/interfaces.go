@@ -84,9 +84,10 @@ type Linker interface {
 }
 
 type DNSLoop interface {
-	ApplyStatus(ctx context.Context, status models.LoopStatus) (
-		outcome string, err error)
+	OnTunnelUp(ctx context.Context)
 	GetSettings() (settings settings.DNS)
+	SetTunnelUp(vpnInterface string)
+	SetTunnelDown()
 }
 
 type PublicIPLoop interface {
@@ -0,0 +1,52 @@
+package vpn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/constants"
+	"github.com/qdm12/gluetun/internal/constants/vpn"
+	"github.com/qdm12/gluetun/internal/models"
+	"github.com/qdm12/gluetun/internal/openvpn"
+	"github.com/qdm12/gluetun/internal/wireguard"
+)
+
+var (
+	ErrWireguardNotUsed = errors.New("Wireguard is not used")
+	ErrOpenVPNNotUsed   = errors.New("OpenVPN is not used")
+	ErrVPNNotRunning    = errors.New("VPN is not running")
+)
+
+// GetWireguardStats returns the statistics of the Wireguard interface,
+// and an error if Wireguard is not used or the VPN is not running.
+func (l *Loop) GetWireguardStats() (stats models.WireguardStats, err error) {
+	settings := l.GetSettings()
+	if settings.Type != vpn.Wireguard {
+		return stats, fmt.Errorf("%w: VPN type is %s", ErrWireguardNotUsed, settings.Type)
+	}
+
+	status := l.GetStatus()
+	if status != constants.Running {
+		return stats, fmt.Errorf("%w: status is %s", ErrVPNNotRunning, status)
+	}
+
+	return wireguard.Stats(settings.Wireguard.Interface)
+}
+
+// GetOpenVPNStats returns the statistics of the OpenVPN connection,
+// and an error if OpenVPN is not used or the VPN is not running.
+func (l *Loop) GetOpenVPNStats(ctx context.Context) (
+	stats models.OpenVPNStats, err error) {
+	settings := l.GetSettings()
+	if settings.Type != vpn.OpenVPN {
+		return stats, fmt.Errorf("%w: VPN type is %s", ErrOpenVPNNotUsed, settings.Type)
+	}
+
+	status := l.GetStatus()
+	if status != constants.Running {
+		return stats, fmt.Errorf("%w: status is %s", ErrVPNNotRunning, status)
+	}
+
+	return openvpn.Stats(ctx)
+}
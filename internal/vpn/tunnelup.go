@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/qdm12/dns/v2/pkg/check"
-	"github.com/qdm12/gluetun/internal/constants"
 	"github.com/qdm12/gluetun/internal/version"
 )
 
@@ -28,8 +27,9 @@ func (l *Loop) onTunnelUp(ctx context.Context, data tunnelUpData) {
 		}
 	}
 
+	l.dnsLooper.SetTunnelUp(data.vpnIntf)
 	if *l.dnsLooper.GetSettings().DoT.Enabled {
-		_, _ = l.dnsLooper.ApplyStatus(ctx, constants.Running)
+		l.dnsLooper.OnTunnelUp(ctx)
 	} else {
 		err := check.WaitForDNS(ctx, check.Settings{})
 		if err != nil {
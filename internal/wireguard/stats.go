@@ -0,0 +1,46 @@
+package wireguard
+
+import (
+	"fmt"
+
+	"github.com/qdm12/gluetun/internal/models"
+	"golang.zx2c4.com/wireguard/wgctrl"
+)
+
+// Stats returns the statistics of the Wireguard interface with the name given.
+// It works for both the kernelspace and the userspace implementations, since
+// wgctrl queries the kernel through netlink and userspace through its UAPI socket.
+func Stats(interfaceName string) (stats models.WireguardStats, err error) {
+	client, err := wgctrl.New()
+	if err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrWgctrlOpen, err)
+	}
+
+	device, err := client.Device(interfaceName)
+	if err != nil {
+		_ = client.Close()
+		return stats, fmt.Errorf("%w: %w", ErrDeviceInfo, err)
+	}
+
+	err = client.Close()
+	if err != nil {
+		return stats, fmt.Errorf("closing controller client: %w", err)
+	}
+
+	stats = models.WireguardStats{
+		Interface: device.Name,
+		Peers:     make([]models.WireguardPeer, len(device.Peers)),
+	}
+	for i, peer := range device.Peers {
+		stats.Peers[i] = models.WireguardPeer{
+			PublicKey:     peer.PublicKey.String(),
+			LastHandshake: peer.LastHandshakeTime,
+			ReceivedBytes: peer.ReceiveBytes,
+			SentBytes:     peer.TransmitBytes,
+		}
+		if peer.Endpoint != nil {
+			stats.Peers[i].Endpoint = peer.Endpoint.String()
+		}
+	}
+	return stats, nil
+}